@@ -0,0 +1,106 @@
+// Package adrestiatest provides an in-process Adrestia stand-in for tests.
+package adrestiatest
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+
+	"github.com/grupokindynos/adrestia-go/models"
+	"github.com/grupokindynos/plutus/adrestia"
+)
+
+const signature = "adrestiatest"
+
+// Server answers /address/:coin with the configured exchange addresses. Responses are
+// "signed" with a fixed header that only the client returned by Client accepts.
+type Server struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	addresses map[string]string
+	failures  int
+	unsigned  bool
+	requests  int
+}
+
+func NewServer(addresses map[string]string) *Server {
+	s := &Server{addresses: addresses}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// FailNext makes the next n requests answer with a 503.
+func (s *Server) FailNext(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures = n
+}
+
+// Unsigned makes the server omit the signature header.
+func (s *Server) Unsigned(unsigned bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.unsigned = unsigned
+}
+
+// Requests returns the amount of requests received.
+func (s *Server) Requests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests
+}
+
+// Client returns an adrestia client pointed to the server without retry delays.
+func (s *Server) Client() *adrestia.Client {
+	client := adrestia.NewClient(s.URL)
+	client.Backoff = 0
+	client.Sign = func(method string, url string) (*http.Request, error) {
+		return http.NewRequest(method, url, nil)
+	}
+	client.Verify = func(header string, token string) (bool, []byte) {
+		if header != signature {
+			return false, nil
+		}
+		payload, err := base64.StdEncoding.DecodeString(token)
+		if err != nil {
+			return false, nil
+		}
+		return true, payload
+	}
+	return client
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.requests++
+	failing := s.failures > 0
+	if failing {
+		s.failures--
+	}
+	unsigned := s.unsigned
+	s.mu.Unlock()
+	if failing {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	if !strings.HasPrefix(r.URL.Path, "/address/") {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	coin := strings.TrimPrefix(r.URL.Path, "/address/")
+	var response models.AddressResponse
+	response.ExchangeAddress.Address = s.addresses[coin]
+	payload, err := json.Marshal(response)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if !unsigned {
+		w.Header().Set("service", signature)
+	}
+	_ = json.NewEncoder(w).Encode(base64.StdEncoding.EncodeToString(payload))
+}
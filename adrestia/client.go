@@ -0,0 +1,151 @@
+package adrestia
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/grupokindynos/adrestia-go/models"
+	"github.com/grupokindynos/common/tokens/mrt"
+	"github.com/grupokindynos/common/tokens/mvt"
+)
+
+// DefaultURL is used when ADRESTIA_URL is not set.
+const DefaultURL = "https://adrestia.polispay.com"
+
+var (
+	ErrNoSignature      = errors.New("adrestia: response without signature header")
+	ErrInvalidSignature = errors.New("adrestia: invalid response signature")
+	ErrNoAddress        = errors.New("adrestia: empty exchange address")
+)
+
+// StatusError is returned when Adrestia answers with a non 200 status code.
+type StatusError struct {
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return "adrestia: unexpected status code " + strconv.Itoa(e.StatusCode)
+}
+
+// Client talks to the Adrestia service. Requests are signed with a MVT token and
+// responses must carry a valid MRT signature.
+type Client struct {
+	BaseURL    string
+	HTTPClient *http.Client
+	// Retries is the number of extra attempts after a network error or a 5xx response.
+	Retries int
+	// Backoff is the wait before the first retry, it doubles on every attempt.
+	Backoff time.Duration
+	// Sign builds the authenticated request for the given method and url.
+	Sign func(method string, url string) (*http.Request, error)
+	// Verify checks the response signature and returns the signed payload.
+	Verify func(signature string, token string) (bool, []byte)
+}
+
+func NewClient(baseURL string) *Client {
+	return &Client{
+		BaseURL:    strings.TrimRight(baseURL, "/"),
+		HTTPClient: &http.Client{Timeout: 5 * time.Second},
+		Retries:    3,
+		Backoff:    500 * time.Millisecond,
+		Sign:       signMVT,
+		Verify:     verifyMRT,
+	}
+}
+
+// NewClientFromEnv creates a client for the url in ADRESTIA_URL.
+func NewClientFromEnv() *Client {
+	url := os.Getenv("ADRESTIA_URL")
+	if url == "" {
+		url = DefaultURL
+	}
+	return NewClient(url)
+}
+
+// GetAddress returns the addresses Adrestia uses for the given coin.
+func (c *Client) GetAddress(coin string) (address models.AddressResponse, err error) {
+	payload, err := c.get("/address/" + coin)
+	if err != nil {
+		return
+	}
+	err = json.Unmarshal(payload, &address)
+	return
+}
+
+// GetDepositAddress returns the exchange address where the coin must be sent.
+func (c *Client) GetDepositAddress(coin string) (string, error) {
+	address, err := c.GetAddress(coin)
+	if err != nil {
+		return "", err
+	}
+	if address.ExchangeAddress.Address == "" {
+		return "", ErrNoAddress
+	}
+	return address.ExchangeAddress.Address, nil
+}
+
+func (c *Client) get(path string) ([]byte, error) {
+	var err error
+	backoff := c.Backoff
+	for attempt := 0; attempt <= c.Retries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+		var payload []byte
+		var retry bool
+		payload, retry, err = c.do(http.MethodGet, c.BaseURL+path)
+		if err == nil || !retry {
+			return payload, err
+		}
+	}
+	return nil, err
+}
+
+// do performs a single request, the returned bool reports whether the error is transient.
+func (c *Client) do(method string, url string) ([]byte, bool, error) {
+	req, err := c.Sign(method, url)
+	if err != nil {
+		return nil, false, err
+	}
+	res, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, true, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, res.StatusCode >= http.StatusInternalServerError, &StatusError{StatusCode: res.StatusCode}
+	}
+	tokenResponse, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, true, err
+	}
+	var tokenString string
+	err = json.Unmarshal(tokenResponse, &tokenString)
+	if err != nil {
+		return nil, false, err
+	}
+	headerSignature := res.Header.Get("service")
+	if headerSignature == "" {
+		return nil, false, ErrNoSignature
+	}
+	valid, payload := c.Verify(headerSignature, tokenString)
+	if !valid {
+		return nil, false, ErrInvalidSignature
+	}
+	return payload, false, nil
+}
+
+func signMVT(method string, url string) (*http.Request, error) {
+	return mvt.CreateMVTToken(method, url, "plutus", os.Getenv("MASTER_PASSWORD"), nil, os.Getenv("HESTIA_AUTH_USERNAME"), os.Getenv("HESTIA_AUTH_PASSWORD"), os.Getenv("PLUTUS_PRIVATE_KEY"))
+}
+
+func verifyMRT(signature string, token string) (bool, []byte) {
+	return mrt.VerifyMRTToken(signature, token, os.Getenv("ADRESTIA_PUBLIC_KEY"), os.Getenv("MASTER_PASSWORD"))
+}
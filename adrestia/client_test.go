@@ -0,0 +1,66 @@
+package adrestia_test
+
+import (
+	"testing"
+
+	"github.com/grupokindynos/plutus/adrestia"
+	"github.com/grupokindynos/plutus/adrestia/adrestiatest"
+)
+
+func TestGetDepositAddress(t *testing.T) {
+	server := adrestiatest.NewServer(map[string]string{"POLIS": "PJqsgYRu8xbs6GRFFh6D5ZcFHu4hhfsZ5V"})
+	defer server.Close()
+	client := server.Client()
+	address, err := client.GetDepositAddress("POLIS")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if address != "PJqsgYRu8xbs6GRFFh6D5ZcFHu4hhfsZ5V" {
+		t.Error("unexpected address " + address)
+	}
+	_, err = client.GetDepositAddress("BTC")
+	if err != adrestia.ErrNoAddress {
+		t.Error("expected empty address error")
+	}
+}
+
+func TestRetries(t *testing.T) {
+	server := adrestiatest.NewServer(map[string]string{"POLIS": "PJqsgYRu8xbs6GRFFh6D5ZcFHu4hhfsZ5V"})
+	defer server.Close()
+	client := server.Client()
+	server.FailNext(2)
+	_, err := client.GetDepositAddress("POLIS")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if server.Requests() != 3 {
+		t.Errorf("expected 3 requests got %d", server.Requests())
+	}
+	server.FailNext(10)
+	_, err = client.GetDepositAddress("POLIS")
+	if _, ok := err.(*adrestia.StatusError); !ok {
+		t.Error("expected a status error after exhausting the retries")
+	}
+}
+
+func TestVerification(t *testing.T) {
+	server := adrestiatest.NewServer(map[string]string{"POLIS": "PJqsgYRu8xbs6GRFFh6D5ZcFHu4hhfsZ5V"})
+	defer server.Close()
+	client := server.Client()
+	server.Unsigned(true)
+	_, err := client.GetDepositAddress("POLIS")
+	if err != adrestia.ErrNoSignature {
+		t.Error("expected missing signature error")
+	}
+	server.Unsigned(false)
+	client.Verify = func(signature string, token string) (bool, []byte) {
+		return false, nil
+	}
+	_, err = client.GetDepositAddress("POLIS")
+	if err != adrestia.ErrInvalidSignature {
+		t.Error("expected invalid signature error")
+	}
+	if server.Requests() != 2 {
+		t.Error("verification errors must not be retried")
+	}
+}
@@ -0,0 +1,184 @@
+// Command plutus-keystore creates and inspects the encrypted keystores unlocked by Plutus
+// at startup.
+//
+//	plutus-keystore create -keystore keystore.json -coins BTC,LTC,ETH
+//	plutus-keystore import -keystore keystore.json -env .env
+//	plutus-keystore list -keystore keystore.json
+//
+// The passphrase is read from KEYSTORE_PASSPHRASE_FILE or KEYSTORE_PASSPHRASE, or prompted.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/grupokindynos/plutus/keystore"
+	"github.com/joho/godotenv"
+	"github.com/tyler-smith/go-bip39"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+const usage = `usage: plutus-keystore <command> [flags]
+
+commands:
+  create  generate new mnemonics for the given coins and store them in a new keystore
+  import  store the MNEMONIC_<TAG> and MNEMONIC_PASSWORD variables in a new keystore
+  list    show the coins stored in a keystore
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	var err error
+	switch os.Args[1] {
+	case "create":
+		err = create(os.Args[2:])
+	case "import":
+		err = importEnv(os.Args[2:])
+	case "list":
+		err = list(os.Args[2:])
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+}
+
+func create(args []string) error {
+	flags := flag.NewFlagSet("create", flag.ExitOnError)
+	path := flags.String("keystore", "keystore.json", "path of the keystore to create")
+	coinList := flags.String("coins", "", "comma separated coin tags, e.g. BTC,LTC,ETH")
+	bits := flags.Int("entropy", 256, "entropy bits of the mnemonics, 128 for 12 words or 256 for 24 words")
+	force := flags.Bool("force", false, "overwrite an existing keystore")
+	_ = flags.Parse(args)
+	if *coinList == "" {
+		return errors.New("-coins is required")
+	}
+	if err := checkOverwrite(*path, *force); err != nil {
+		return err
+	}
+	secrets := &keystore.Secrets{Mnemonics: make(map[string]string)}
+	for _, tag := range strings.Split(*coinList, ",") {
+		tag = strings.ToUpper(strings.TrimSpace(tag))
+		entropy, err := bip39.NewEntropy(*bits)
+		if err != nil {
+			return err
+		}
+		mnemonic, err := bip39.NewMnemonic(entropy)
+		if err != nil {
+			return err
+		}
+		secrets.Mnemonics[tag] = mnemonic
+	}
+	passphrase, err := readPassphrase(true)
+	if err != nil {
+		return err
+	}
+	if err := keystore.Save(*path, secrets, passphrase, keystore.StandardScrypt); err != nil {
+		return err
+	}
+	fmt.Println("Keystore written to " + *path + ". Write down the mnemonics and keep them offline, they are not shown again:")
+	for _, tag := range sortedTags(secrets) {
+		fmt.Println(tag + ": " + secrets.Mnemonics[tag])
+	}
+	return nil
+}
+
+func importEnv(args []string) error {
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	path := flags.String("keystore", "keystore.json", "path of the keystore to create")
+	envFile := flags.String("env", "", "optional .env file holding the mnemonics")
+	force := flags.Bool("force", false, "overwrite an existing keystore")
+	_ = flags.Parse(args)
+	if err := checkOverwrite(*path, *force); err != nil {
+		return err
+	}
+	if *envFile != "" {
+		if err := godotenv.Load(*envFile); err != nil {
+			return err
+		}
+	}
+	secrets := keystore.SecretsFromEnv()
+	if len(secrets.Mnemonics) == 0 {
+		return errors.New("no MNEMONIC_<TAG> variable found")
+	}
+	passphrase, err := readPassphrase(true)
+	if err != nil {
+		return err
+	}
+	if err := keystore.Save(*path, secrets, passphrase, keystore.StandardScrypt); err != nil {
+		return err
+	}
+	fmt.Println("Imported " + strings.Join(sortedTags(secrets), ", ") + " into " + *path + ". Remove the mnemonics from the environment and the .env files.")
+	return nil
+}
+
+func list(args []string) error {
+	flags := flag.NewFlagSet("list", flag.ExitOnError)
+	path := flags.String("keystore", "keystore.json", "path of the keystore")
+	_ = flags.Parse(args)
+	passphrase, err := readPassphrase(false)
+	if err != nil {
+		return err
+	}
+	secrets, err := keystore.Load(*path, passphrase)
+	if err != nil {
+		return err
+	}
+	fmt.Println("coins: " + strings.Join(sortedTags(secrets), ", "))
+	fmt.Println("mnemonic password:", secrets.MnemonicPassword != "")
+	return nil
+}
+
+func checkOverwrite(path string, force bool) error {
+	if _, err := os.Stat(path); err == nil && !force {
+		return errors.New(path + " already exists, use -force to overwrite it")
+	}
+	return nil
+}
+
+func readPassphrase(confirm bool) ([]byte, error) {
+	if os.Getenv("KEYSTORE_PASSPHRASE_FILE") != "" || os.Getenv("KEYSTORE_PASSPHRASE") != "" {
+		return keystore.PassphraseFromEnv()
+	}
+	fmt.Fprint(os.Stderr, "Passphrase: ")
+	passphrase, err := terminal.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return nil, err
+	}
+	if len(passphrase) == 0 {
+		return nil, keystore.ErrEmptyPass
+	}
+	if !confirm {
+		return passphrase, nil
+	}
+	fmt.Fprint(os.Stderr, "Repeat the passphrase: ")
+	repeated, err := terminal.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return nil, err
+	}
+	if string(repeated) != string(passphrase) {
+		return nil, errors.New("the passphrases don't match")
+	}
+	return passphrase, nil
+}
+
+func sortedTags(secrets *keystore.Secrets) []string {
+	tags := make([]string, 0, len(secrets.Mnemonics))
+	for tag := range secrets.Mnemonics {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	return tags
+}
@@ -0,0 +1,26 @@
+//go:build !windows
+// +build !windows
+
+package main
+
+import (
+	"net"
+	"os"
+	"syscall"
+)
+
+// listen creates the socket readable by the owner only. The umask applies when the socket
+// is created, so no other user can connect before it is restricted.
+func listen(socket string) (net.Listener, error) {
+	umask := syscall.Umask(0077)
+	listener, err := net.Listen("unix", socket)
+	syscall.Umask(umask)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(socket, 0600); err != nil {
+		_ = listener.Close()
+		return nil, err
+	}
+	return listener, nil
+}
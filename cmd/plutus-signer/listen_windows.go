@@ -0,0 +1,8 @@
+package main
+
+import "net"
+
+// listen creates the socket, its access is restricted by the ACL of its directory on windows.
+func listen(socket string) (net.Listener, error) {
+	return net.Listen("unix", socket)
+}
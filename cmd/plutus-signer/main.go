@@ -0,0 +1,72 @@
+// Command plutus-signer holds the private keys of Plutus and signs the transactions built by
+// the HTTP service, which is started with SIGNER=remote.
+//
+//	SIGNER_SOCKET=/run/plutus/signer.sock KEYSTORE_PATH=keystore.json plutus-signer
+//
+// The mnemonics are read from the keystore at KEYSTORE_PATH or from the MNEMONIC_<TAG>
+// variables, like the HTTP service does.
+package main
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/grupokindynos/plutus/controllers"
+	"github.com/grupokindynos/plutus/keystore"
+	"github.com/grupokindynos/plutus/logger"
+	"github.com/grupokindynos/plutus/signer"
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	_ = godotenv.Load()
+	logger.AddSecretsFromEnv()
+	secrets, err := keystore.LoadFromEnv()
+	if err != nil {
+		fatal("unable to unlock the keystore", err)
+	}
+	if secrets != nil {
+		for _, mnemonic := range secrets.Mnemonics {
+			logger.AddSecret(mnemonic)
+		}
+		logger.AddSecret(secrets.MnemonicPassword)
+		controllers.SetSecrets(secrets.Mnemonics, secrets.MnemonicPassword)
+		keystore.ClearEnv()
+	}
+
+	socket := os.Getenv("SIGNER_SOCKET")
+	if socket == "" {
+		fatal("SIGNER_SOCKET is required", nil)
+	}
+	if err := os.Remove(socket); err != nil && !os.IsNotExist(err) {
+		fatal("unable to remove the stale socket", err)
+	}
+	listener, err := listen(socket)
+	if err != nil {
+		fatal("unable to listen", err)
+	}
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	stopping := make(chan struct{})
+	go func() {
+		sig := <-quit
+		logger.Info("signer: shutting down", "signal", sig.String())
+		close(stopping)
+		_ = listener.Close()
+	}()
+	logger.Info("signer: listening", "socket", socket)
+	err = signer.Serve(listener, signer.NewLocal(controllers.KeySource()))
+	controllers.WipeKeys()
+	select {
+	case <-stopping:
+	default:
+		fatal("the listener stopped", err)
+	}
+}
+
+func fatal(msg string, err error) {
+	logger.Error("signer: "+msg, "err", err)
+	os.Exit(1)
+}
@@ -0,0 +1,99 @@
+package controllers
+
+import (
+	"errors"
+	"time"
+
+	"github.com/grupokindynos/common/coin-factory/coins"
+	"github.com/grupokindynos/plutus/models"
+	"github.com/grupokindynos/plutus/store"
+)
+
+// addressBucket holds the metadata of the issued addresses, keyed by coin and address, so
+// a deposit can be traced back to the service and the order that requested the address.
+const addressBucket = "addresses"
+
+// addressIndexBucket keeps, for every coin, the index following the last receive address
+// issued. The discovery only knows the used addresses, an address issued but not funded yet
+// must not be issued again after a restart.
+const addressIndexBucket = "address_index"
+
+type addressIndex struct {
+	Next int `json:"next"`
+}
+
+func addressKey(tag string, address string) string {
+	return tag + ":" + address
+}
+
+// recordAddress stores the metadata of an issued address, an address is only recorded once.
+func recordAddress(meta models.AddressMeta) error {
+	meta.CreatedAt = time.Now().UTC()
+	var recorded models.AddressMeta
+	return stateStore.Update(addressBucket, addressKey(meta.Coin, meta.Address), &recorded, func(found bool) error {
+		if found {
+			return errors.New("the address " + meta.Address + " was already issued to " + recorded.Service)
+		}
+		recorded = meta
+		return nil
+	})
+}
+
+// recordAccountAddress records an ethereum account address, issued again on every request.
+// The first record is kept, only the time of the last issuance is updated.
+func recordAccountAddress(meta models.AddressMeta) error {
+	now := time.Now().UTC()
+	var recorded models.AddressMeta
+	return stateStore.Update(addressBucket, addressKey(meta.Coin, meta.Address), &recorded, func(found bool) error {
+		if !found {
+			recorded = meta
+			recorded.CreatedAt = now
+			return nil
+		}
+		recorded.LastIssuedAt = &now
+		return nil
+	})
+}
+
+// reserveAddressIndex returns the index of the next receive address of the coin, following
+// the last used address and every address already issued, and reserves it.
+func reserveAddressIndex(tag string, lastUsed int) (int, error) {
+	var index addressIndex
+	var path int
+	err := stateStore.Update(addressIndexBucket, tag, &index, func(found bool) error {
+		path = lastUsed + 1
+		if found && index.Next > path {
+			path = index.Next
+		}
+		index.Next = path + 1
+		return nil
+	})
+	return path, err
+}
+
+// addressMeta returns the metadata of an address issued for the coin.
+func addressMeta(coinConfig *coins.Coin, address string) (*models.AddressMeta, error) {
+	var meta models.AddressMeta
+	err := stateStore.Get(addressBucket, addressKey(coinConfig.Info.Tag, address), &meta)
+	if err == store.ErrNotFound {
+		return nil, errors.New("the address " + address + " was not issued by plutus")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &meta, nil
+}
+
+// IssuedAddresses returns the metadata of every address issued by Plutus.
+func IssuedAddresses() ([]models.AddressMeta, error) {
+	var issued []models.AddressMeta
+	for _, key := range stateStore.Keys(addressBucket) {
+		var meta models.AddressMeta
+		err := stateStore.Get(addressBucket, key, &meta)
+		if err != nil {
+			return nil, err
+		}
+		issued = append(issued, meta)
+	}
+	return issued, nil
+}
@@ -0,0 +1,91 @@
+package controllers
+
+import (
+	"testing"
+
+	coinfactory "github.com/grupokindynos/common/coin-factory"
+	"github.com/grupokindynos/plutus/models"
+	"github.com/grupokindynos/plutus/store"
+)
+
+func TestAddressMeta(t *testing.T) {
+	defaultStore := stateStore
+	defer SetStore(defaultStore)
+	db, _ := store.Open("")
+	SetStore(db)
+	btc := coinfactory.Coins["BTC"]
+	err := recordAddress(models.AddressMeta{Coin: "BTC", Address: "1JsMio1jkCBsneBsQxs1cbpj5BwTnZKS8i", Path: 10, Service: "ladon", Reference: "order-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	meta, err := addressMeta(btc, "1JsMio1jkCBsneBsQxs1cbpj5BwTnZKS8i")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta.Service != "ladon" || meta.Reference != "order-1" || meta.Path != 10 || meta.CreatedAt.IsZero() {
+		t.Error("the metadata of the address was not kept")
+	}
+	if _, err := addressMeta(coinfactory.Coins["LTC"], "1JsMio1jkCBsneBsQxs1cbpj5BwTnZKS8i"); err == nil {
+		t.Error("the addresses of a coin must not be found under another coin")
+	}
+}
+
+func TestAddressIndex(t *testing.T) {
+	defaultStore := stateStore
+	defer SetStore(defaultStore)
+	db, _ := store.Open("")
+	SetStore(db)
+	err := recordAddress(models.AddressMeta{Coin: "BTC", Address: "1JsMio1jkCBsneBsQxs1cbpj5BwTnZKS8i", Path: 10, Service: "ladon"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := recordAddress(models.AddressMeta{Coin: "BTC", Address: "1JsMio1jkCBsneBsQxs1cbpj5BwTnZKS8i", Path: 10, Service: "tyche"}); err == nil {
+		t.Error("an issued address must not be recorded again")
+	}
+	meta, _ := addressMeta(coinfactory.Coins["BTC"], "1JsMio1jkCBsneBsQxs1cbpj5BwTnZKS8i")
+	if meta == nil || meta.Service != "ladon" {
+		t.Error("the first record of an address must be kept")
+	}
+	if index, err := reserveAddressIndex("BTC", 3); err != nil || index != 4 {
+		t.Error("the index must follow the last used address")
+	}
+	// after a restart the discovery only knows the used addresses
+	if index, err := reserveAddressIndex("BTC", 3); err != nil || index != 5 {
+		t.Error("a reserved index must not be issued twice")
+	}
+	if index, err := reserveAddressIndex("BTC", 20); err != nil || index != 21 {
+		t.Error("the index must follow a used address beyond the reserved ones")
+	}
+	if index, err := reserveAddressIndex("LTC", -1); err != nil || index != 0 {
+		t.Error("the first address of a coin must be issued first")
+	}
+}
+
+func TestAccountAddress(t *testing.T) {
+	defaultStore := stateStore
+	defer SetStore(defaultStore)
+	db, _ := store.Open("")
+	SetStore(db)
+	address := "0x931D387731bBbC988B312206c74F77D004D6B84b"
+	if err := recordAccountAddress(models.AddressMeta{Coin: "ETH", Address: address, Service: "ladon", Reference: "order-1"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := recordAccountAddress(models.AddressMeta{Coin: "ETH", Address: address, Service: "tyche"}); err != nil {
+		t.Error("an account address must be issued again")
+	}
+	meta, err := addressMeta(coinfactory.Coins["ETH"], address)
+	if err != nil || meta.Service != "ladon" || meta.Reference != "order-1" || meta.LastIssuedAt == nil {
+		t.Error("the first record of an account address must be kept with the last issuance")
+	}
+}
+
+func TestTrackAddress(t *testing.T) {
+	ctrl := &Controller{Address: make(map[string]AddrInfo), availability: newCoinAvailability()}
+	ctrl.trackAddress("BTC", models.AddrInfo{Addr: "1JsMio1jkCBsneBsQxs1cbpj5BwTnZKS8i", Path: 10, Chain: externalChain})
+	ctrl.trackAddress("BTC", models.AddrInfo{Addr: "1JsMio1jkCBsneBsQxs1cbpj5BwTnZKS8i", Path: 10, Chain: externalChain})
+	ctrl.trackAddress("BTC", models.AddrInfo{Addr: "3P14159f73E4gFr7JterCCQh9QjiTjiZrG", Path: 12, Multisig: true})
+	info := ctrl.addrInfo("BTC")
+	if len(info.AddrInfo) != 2 || info.LastUsed != 10 {
+		t.Error("an address must be tracked once and only the receive addresses move the last used index")
+	}
+}
@@ -0,0 +1,90 @@
+package controllers
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/grupokindynos/common/coin-factory/coins"
+	"github.com/grupokindynos/plutus/logger"
+)
+
+const (
+	syncRetryInitial = 30 * time.Second
+	syncRetryMax     = 10 * time.Minute
+)
+
+// coinAvailability tracks the coins whose address sync failed. They are retried in the
+// background and requests for them are rejected until they recover, while the healthy
+// coins keep serving.
+type coinAvailability struct {
+	mu          sync.RWMutex
+	unavailable map[string]error
+}
+
+func newCoinAvailability() *coinAvailability {
+	return &coinAvailability{
+		unavailable: make(map[string]error),
+	}
+}
+
+func (a *coinAvailability) markUnavailable(tag string, err error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.unavailable[tag] = err
+}
+
+func (a *coinAvailability) markAvailable(tag string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.unavailable, tag)
+}
+
+// check returns an error describing why the coin can't be used right now.
+func (a *coinAvailability) check(coinConfig *coins.Coin) error {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	err, ok := a.unavailable[coinConfig.Info.Tag]
+	if !ok {
+		return nil
+	}
+	return errors.New("the coin " + coinConfig.Info.Tag + " is temporarily unavailable: " + err.Error())
+}
+
+// syncCoin runs the initial address sync of a coin. On failure the coin is marked as
+// unavailable and the sync is retried in the background with an exponential backoff.
+func (a *coinAvailability) syncCoin(coin *coins.Coin, getAddrs func(coinConfig *coins.Coin) error) {
+	err := getAddrs(coin)
+	if err == nil {
+		return
+	}
+	logger.Error("syncCoin: the coin is unavailable", "coin", coin.Info.Tag, "err", err)
+	a.markUnavailable(coin.Info.Tag, err)
+	go func() {
+		backoff := syncRetryInitial
+		for {
+			time.Sleep(backoff)
+			err := getAddrs(coin)
+			if err == nil {
+				logger.Info("syncCoin: the coin is available again", "coin", coin.Info.Tag)
+				a.markAvailable(coin.Info.Tag)
+				return
+			}
+			a.markUnavailable(coin.Info.Tag, err)
+			backoff *= 2
+			if backoff > syncRetryMax {
+				backoff = syncRetryMax
+			}
+		}
+	}()
+}
+
+func (a *coinAvailability) list() map[string]string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	unavailable := make(map[string]string, len(a.unavailable))
+	for tag, err := range a.unavailable {
+		unavailable[tag] = err.Error()
+	}
+	return unavailable
+}
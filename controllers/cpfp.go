@@ -0,0 +1,202 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/eabz/btcutil"
+	"github.com/eabz/btcutil/chaincfg"
+	"github.com/eabz/btcutil/txscript"
+	"github.com/grupokindynos/common/coin-factory/coins"
+	"github.com/grupokindynos/plutus/logger"
+	"github.com/grupokindynos/plutus/metrics"
+	"github.com/grupokindynos/plutus/models"
+	"github.com/martinboehm/btcd/wire"
+)
+
+// dustThreshold is the smallest output, in satoshis, relayed by the nodes.
+const dustThreshold = 546
+
+var (
+	errParentConfirmed = errors.New("the parent transaction is already confirmed")
+	errNoParentOutput  = errors.New("the parent transaction has no unspent output of our receive addresses")
+	errParentPaysRate  = errors.New("the parent transaction already pays the fee rate")
+	errChildDust       = errors.New("our outputs of the parent transaction can't pay the fee of the child")
+)
+
+type blockbookParentTx struct {
+	Hex           string `json:"hex"`
+	Fees          string `json:"fees"`
+	Confirmations int    `json:"confirmations"`
+	Vout          []struct {
+		N     uint32 `json:"n"`
+		Spent bool   `json:"spent"`
+	} `json:"vout"`
+	// Error is set when the backend doesn't know the transaction.
+	Error string `json:"error"`
+}
+
+// AccelerateV2 creates a child transaction spending our outputs of an unconfirmed transaction,
+// a send or an incoming payment, back to our first address at a fee bringing both to the fee
+// rate (child pays for parent), and returns its journal entry.
+func (c *ControllerV2) AccelerateV2(params ParamsV2) (interface{}, error) {
+	var req models.AccelerateReq
+	log := logger.Default().WithRequestID(params.RequestID).With("service", params.Service)
+	err := json.Unmarshal(params.Body, &req)
+	if err != nil {
+		log.Error("AccelerateV2: unable to decode the request body", "err", err)
+		return nil, err
+	}
+	coinConfig, err := getCoin(req.Coin)
+	if err != nil {
+		return nil, err
+	}
+	if coinConfig.Info.Token || coinConfig.Info.Tag == "ETH" {
+		return nil, errNoUtxoCoin
+	}
+	if err := c.availability.check(coinConfig); err != nil {
+		return nil, err
+	}
+	if watchOnly() {
+		return nil, errWatchOnly
+	}
+	if req.FeeRate < 0 {
+		return nil, errors.New("the fee rate must be positive")
+	}
+	feeRate := req.FeeRate
+	if feeRate == 0 {
+		feeRate, err = getFeeRate(coinConfig)
+		if err != nil {
+			return nil, err
+		}
+	}
+	bumpMu.Lock()
+	defer bumpMu.Unlock()
+	var res blockbookParentTx
+	start := time.Now()
+	err = getJSON(strings.TrimRight(coinConfig.Info.Blockbook, "/")+"/api/v2/tx/"+req.Txid, &res)
+	metrics.ObserveBlockbook(coinConfig.Info.Tag, "tx", start, err)
+	if err != nil {
+		return nil, err
+	}
+	if res.Error != "" {
+		return nil, errors.New("unable to get the parent transaction: " + res.Error)
+	}
+	if res.Confirmations > 0 {
+		return nil, errParentConfirmed
+	}
+	rawTx, err := hex.DecodeString(res.Hex)
+	if err != nil {
+		return nil, err
+	}
+	parent := wire.NewMsgTx(wire.TxVersion)
+	if err := parent.Deserialize(bytes.NewReader(rawTx)); err != nil {
+		return nil, err
+	}
+	parentFee, err := strconv.ParseInt(res.Fees, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	spent := make(map[uint32]bool)
+	for _, out := range res.Vout {
+		spent[out.N] = out.Spent
+	}
+	inputs, err := parentOutputs(coinConfig, parent, c.addrInfo(coinConfig.Info.Tag), spent)
+	if err != nil {
+		return nil, err
+	}
+	p, err := childPayment(coinConfig, parent, parentFee, inputs, feeRate, log)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.sign(coinConfig, log); err != nil {
+		return nil, err
+	}
+	txid, err := broadcastTx(coinConfig, p.tx, log)
+	if err != nil {
+		log.Error("AccelerateV2: unable to broadcast the child", "coin", coinConfig.Info.Tag, "parent", req.Txid, "err", err)
+		return nil, err
+	}
+	metrics.FeePaid.WithLabelValues(coinConfig.Info.Tag).Add(p.fee.ToBTC())
+	amount := btcutil.Amount(p.tx.TxOut[0].Value).ToBTC()
+	entry := p.journalEntry(coinConfig, txid, inputs[0].address, amount)
+	entry.Accelerates = parent.TxHash().String()
+	return journalSend(entry, sendOrigin{params.Service, params.RequestID}, log), nil
+}
+
+// parentOutputs returns the unspent outputs of parent paying our receive addresses.
+func parentOutputs(coinConfig *coins.Coin, parent *wire.MsgTx, info AddrInfo, spent map[uint32]bool) ([]spentOutput, error) {
+	// To prevent address collision we need to de-register all networks and register just the network using
+	chaincfg.ResetParams()
+	_ = chaincfg.Register(coinConfig.NetParams)
+	type receiveAddr struct {
+		address string
+		index   uint32
+	}
+	addrs := make(map[string]receiveAddr)
+	for _, addr := range info.AddrInfo {
+		if addr.Chain != externalChain || addr.Multisig {
+			continue
+		}
+		address, err := btcutil.DecodeAddress(addr.Addr, coinConfig.NetParams)
+		if err != nil {
+			return nil, err
+		}
+		script, err := txscript.PayToAddrScript(address)
+		if err != nil {
+			return nil, err
+		}
+		addrs[string(script)] = receiveAddr{addr.Addr, uint32(addr.Path)}
+	}
+	txid := parent.TxHash().String()
+	var inputs []spentOutput
+	for i, out := range parent.TxOut {
+		addr, ok := addrs[string(out.PkScript)]
+		if !ok || spent[uint32(i)] {
+			continue
+		}
+		inputs = append(inputs, spentOutput{
+			txid:    txid,
+			vout:    uint32(i),
+			value:   btcutil.Amount(out.Value),
+			address: addr.address,
+			index:   addr.index,
+			script:  out.PkScript,
+		})
+	}
+	if len(inputs) == 0 {
+		return nil, errNoParentOutput
+	}
+	return inputs, nil
+}
+
+// childPayment spends inputs, outputs of parent, to the address of the first one. Its fee
+// brings the parent and the child to feeRate, the ancestors of the parent are not accounted.
+func childPayment(coinConfig *coins.Coin, parent *wire.MsgTx, parentFee int64, inputs []spentOutput, feeRate int64, log *logger.Logger) (*payment, error) {
+	size := parent.SerializeSize()
+	parentVSize := (parent.SerializeSizeStripped()*3 + size + 3) / 4
+	if parentFee*1024 >= feeRate*int64(parentVSize) {
+		return nil, errParentPaysRate
+	}
+	var total btcutil.Amount
+	for _, input := range inputs {
+		total += input.value
+	}
+	packageFee := func(feeRate int64, inputs int, outputs int) btcutil.Amount {
+		parentDeficit := btcutil.Amount(float64(feeRate)/1024.0*float64(parentVSize)) - btcutil.Amount(parentFee)
+		return estimateFee(feeRate, inputs, outputs) + parentDeficit
+	}
+	p, err := newPaymentAtRate(coinConfig, inputs, inputs[0].address, total.ToBTC(), feeRate, packageFee, log)
+	if err != nil {
+		return nil, err
+	}
+	if p.tx.TxOut[0].Value < dustThreshold {
+		return nil, errChildDust
+	}
+	return p, nil
+}
@@ -0,0 +1,57 @@
+package controllers
+
+import (
+	"testing"
+
+	"github.com/eabz/btcutil"
+	"github.com/eabz/btcutil/txscript"
+	"github.com/grupokindynos/plutus/logger"
+	"github.com/grupokindynos/plutus/models"
+	"github.com/martinboehm/btcd/wire"
+)
+
+func TestChildPayment(t *testing.T) {
+	log := logger.Default()
+	for _, test := range testXpup {
+		ourScript := testAddrScript(t, test)
+		otherScript, _ := txscript.NewScriptBuilder().AddOp(txscript.OP_RETURN).Script()
+		parent := testSpendingTx(testPrevHash(), 0)
+		parent.TxIn[0].SignatureScript = make([]byte, 107)
+		parent.AddTxOut(wire.NewTxOut(100000, otherScript))
+		parent.AddTxOut(wire.NewTxOut(50000, ourScript))
+		parent.AddTxOut(wire.NewTxOut(20000, ourScript))
+		info := AddrInfo{AddrInfo: []models.AddrInfo{
+			{Addr: test.addr, Path: int(test.path), Chain: externalChain},
+		}}
+		inputs, err := parentOutputs(test.coin, parent, info, map[uint32]bool{2: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(inputs) != 1 || inputs[0].vout != 1 || inputs[0].index != test.path || inputs[0].txid != parent.TxHash().String() {
+			t.Error("unexpected parent outputs for " + test.coin.Info.Tag)
+		}
+		info.AddrInfo[0].Chain = internalChain
+		if _, err := parentOutputs(test.coin, parent, info, nil); err != errNoParentOutput {
+			t.Error("the outputs of the change chain must be ignored for " + test.coin.Info.Tag)
+		}
+		parentVSize := int64(parent.SerializeSize())
+		if _, err := childPayment(test.coin, parent, 10*parentVSize, inputs, 4000, log); err != errParentPaysRate {
+			t.Error("a parent paying the fee rate must not be accelerated for " + test.coin.Info.Tag)
+		}
+		p, err := childPayment(test.coin, parent, parentVSize, inputs, 20000, log)
+		if err != nil {
+			t.Fatal(err)
+		}
+		childFee := estimateFee(20000, 1, 0)
+		packageFee := btcutil.Amount(parentVSize) + p.fee
+		if len(p.tx.TxOut) != 1 || p.tx.TxOut[0].Value != int64(50000-p.fee) || p.fee <= childFee {
+			t.Error("unexpected child for " + test.coin.Info.Tag)
+		}
+		if rate := float64(packageFee) / float64(parentVSize+int64(childFee)*1024/20000); rate < 19.5 {
+			t.Error("the package must pay the fee rate for " + test.coin.Info.Tag)
+		}
+		if _, err := childPayment(test.coin, parent, parentVSize, inputs, 1000000, log); err != errChildDust {
+			t.Error("a child unable to pay its fee must be rejected for " + test.coin.Info.Tag)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/grupokindynos/plutus/deposits"
+	"github.com/grupokindynos/plutus/models"
+)
+
+var errDepositsDisabled = errors.New("the deposit watcher is disabled, no webhook is configured")
+
+// DepositsController exposes the webhook delivery log of the deposit watcher and the
+// payments expected on the issued addresses.
+type DepositsController struct {
+	Watcher  *deposits.Watcher
+	Registry *deposits.Registry
+}
+
+// GetDeliveries returns the delivery log of the service, filtered by the status query parameter.
+func (c *DepositsController) GetDeliveries(params ParamsV2) (interface{}, error) {
+	if c.Watcher == nil {
+		return nil, errDepositsDisabled
+	}
+	return c.Watcher.Deliveries(params.Service, params.Status), nil
+}
+
+// RetryDelivery schedules a failed delivery again, it is sent on the next run.
+func (c *DepositsController) RetryDelivery(params ParamsV2) (interface{}, error) {
+	if c.Watcher == nil {
+		return nil, errDepositsDisabled
+	}
+	return c.Watcher.Retry(params.Service, params.ID)
+}
+
+// ExpectPayment registers a payment expected on an address issued by Plutus.
+func (c *DepositsController) ExpectPayment(params ParamsV2) (interface{}, error) {
+	var req models.ExpectedPaymentReq
+	err := json.Unmarshal(params.Body, &req)
+	if err != nil {
+		return nil, err
+	}
+	coinConfig, err := getCoin(params.Coin)
+	if err != nil {
+		return nil, err
+	}
+	if coinConfig.Info.Token || coinConfig.Info.Tag == "ETH" {
+		return nil, errors.New("expected payments are only supported on utxo coins")
+	}
+	if _, err := addressMeta(coinConfig, req.Address); err != nil {
+		return nil, err
+	}
+	return c.Registry.Expect(coinConfig.Info.Tag, req.Address, req.Amount, time.Duration(req.Expiry)*time.Second, req.Reference, params.Service)
+}
+
+// GetExpectedPayment matches the payment expected on an address with the transactions it
+// received and returns its status.
+func (c *DepositsController) GetExpectedPayment(params ParamsV2) (interface{}, error) {
+	coinConfig, err := getCoin(params.Coin)
+	if err != nil {
+		return nil, err
+	}
+	return c.Registry.Status(coinConfig.Info.Tag, params.Address)
+}
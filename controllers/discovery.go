@@ -0,0 +1,204 @@
+package controllers
+
+import (
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/eabz/btcutil/hdkeychain"
+	"github.com/grupokindynos/common/coin-factory/coins"
+	"github.com/grupokindynos/plutus/metrics"
+	"github.com/grupokindynos/plutus/models"
+)
+
+// The known addresses of a coin are found with the BIP44 account discovery: the external
+// and internal chains are scanned until gapLimit consecutive addresses without transactions.
+// The backend discovers the xpub addresses with its own gap of backendGap, the addresses
+// beyond it are only probed one by one when a larger gap is configured.
+
+const (
+	externalChain = 0
+	internalChain = 1
+	backendGap    = 20
+)
+
+// gapLimit returns the gap of the discovery, ADDRESS_GAP_LIMIT or addrGap by default.
+func gapLimit() int {
+	gap, err := strconv.Atoi(os.Getenv("ADDRESS_GAP_LIMIT"))
+	if err != nil || gap <= 0 {
+		return addrGap
+	}
+	return gap
+}
+
+type xpubTokens struct {
+	Tokens []struct {
+		Path string `json:"path"`
+	} `json:"tokens"`
+}
+
+type addressTxs struct {
+	Txs            int `json:"txs"`
+	UnconfirmedTxs int `json:"unconfirmedTxs"`
+}
+
+// discoverAddrs returns the addresses of both chains of the account up to the gap limit
+// after the last used one, and the addresses issued by the multisig wallet. LastUsed is the
+// index of the last used external address.
+func discoverAddrs(coinConfig *coins.Coin) (AddrInfo, error) {
+	acc, err := getAccPub(coinConfig)
+	if err != nil {
+		return AddrInfo{}, err
+	}
+	lastUsed, err := backendLastUsed(coinConfig, acc.String())
+	if err != nil {
+		return AddrInfo{}, err
+	}
+	gap := gapLimit()
+	info := AddrInfo{}
+	for _, chain := range []uint32{externalChain, internalChain} {
+		chainExt, err := acc.Child(chain)
+		if err != nil {
+			return AddrInfo{}, err
+		}
+		used := func(index int) (bool, error) {
+			addr, err := chainAddress(coinConfig, chainExt, index)
+			if err != nil {
+				return false, err
+			}
+			return addressUsed(coinConfig, addr)
+		}
+		last, err := discoverChain(lastUsed[chain], gap, used)
+		if err != nil {
+			return AddrInfo{}, err
+		}
+		for i := 0; i <= last+gap; i++ {
+			addr, err := chainAddress(coinConfig, chainExt, i)
+			if err != nil {
+				return AddrInfo{}, err
+			}
+			info.AddrInfo = append(info.AddrInfo, models.AddrInfo{Addr: addr, Path: i, Chain: int(chain)})
+		}
+		if chain == externalChain {
+			info.LastUsed = last
+		} else {
+			info.LastUsedInternal = last
+		}
+	}
+	multisigAddrs, err := multisigAddrInfo(coinConfig)
+	if err != nil {
+		return AddrInfo{}, err
+	}
+	info.AddrInfo = append(info.AddrInfo, multisigAddrs...)
+	return info, nil
+}
+
+// discoverChain returns the index of the last used address of a chain, starting from the
+// last one the backend knows. The backend already checked the backendGap addresses after it.
+func discoverChain(lastUsed int, gap int, used func(index int) (bool, error)) (int, error) {
+	unused := backendGap
+	if unused > gap {
+		unused = gap
+	}
+	for index := lastUsed + 1 + unused; unused < gap; index++ {
+		isUsed, err := used(index)
+		if err != nil {
+			return 0, err
+		}
+		if isUsed {
+			lastUsed = index
+			unused = 0
+		} else {
+			unused++
+		}
+	}
+	return lastUsed, nil
+}
+
+// backendLastUsed returns the index of the last used address of the external and internal
+// chains seen by the backend, -1 for an unused chain.
+func backendLastUsed(coinConfig *coins.Coin, xpub string) ([2]int, error) {
+	lastUsed := [2]int{-1, -1}
+	var res xpubTokens
+	start := time.Now()
+	err := getJSON(strings.TrimRight(coinConfig.Info.Blockbook, "/")+"/api/v2/xpub/"+xpub+"?details=tokens&tokens=used", &res)
+	metrics.ObserveBlockbook(coinConfig.Info.Tag, "xpub", start, err)
+	if err != nil {
+		return lastUsed, err
+	}
+	for _, token := range res.Tokens {
+		chain, index, err := parseAddressPath(token.Path)
+		if err != nil {
+			return lastUsed, err
+		}
+		if chain > internalChain {
+			continue
+		}
+		if index > lastUsed[chain] {
+			lastUsed[chain] = index
+		}
+	}
+	return lastUsed, nil
+}
+
+// parseAddressPath returns the chain and the index of a BIP44 path like m/44'/0'/0'/0/5.
+func parseAddressPath(path string) (int, int, error) {
+	parts := strings.Split(path, "/")
+	if len(parts) != 6 {
+		return 0, 0, errors.New("invalid address derivation path " + path)
+	}
+	chain, err := strconv.Atoi(parts[4])
+	if err != nil {
+		return 0, 0, errors.New("invalid address derivation path " + path)
+	}
+	index, err := strconv.Atoi(parts[5])
+	if err != nil {
+		return 0, 0, errors.New("invalid address derivation path " + path)
+	}
+	return chain, index, nil
+}
+
+// addressUsed reports whether the address has any transaction.
+func addressUsed(coinConfig *coins.Coin, address string) (bool, error) {
+	var res addressTxs
+	start := time.Now()
+	err := getJSON(strings.TrimRight(coinConfig.Info.Blockbook, "/")+"/api/v2/address/"+address+"?details=basic", &res)
+	metrics.ObserveBlockbook(coinConfig.Info.Tag, "address", start, err)
+	if err != nil {
+		return false, err
+	}
+	return res.Txs+res.UnconfirmedTxs > 0, nil
+}
+
+func chainAddress(coinConfig *coins.Coin, chainExt *hdkeychain.ExtendedKey, index int) (string, error) {
+	addrExtPub, err := chainExt.Child(uint32(index))
+	if err != nil {
+		return "", err
+	}
+	addr, err := addrExtPub.Address(coinConfig.NetParams)
+	if err != nil {
+		return "", err
+	}
+	return addr.String(), nil
+}
+
+func rescanResponse(coinConfig *coins.Coin, info AddrInfo) *models.RescanResponse {
+	response := &models.RescanResponse{
+		Coin:             coinConfig.Info.Tag,
+		Gap:              gapLimit(),
+		LastUsed:         info.LastUsed,
+		LastUsedInternal: info.LastUsedInternal,
+	}
+	for _, addr := range info.AddrInfo {
+		if addr.Multisig {
+			response.Multisig++
+		} else if addr.Chain == internalChain {
+			response.Internal++
+		} else {
+			response.External++
+		}
+	}
+	return response
+}
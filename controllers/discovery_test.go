@@ -0,0 +1,60 @@
+package controllers
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+)
+
+var testDiscovery = []struct {
+	name     string
+	lastUsed int
+	gap      int
+	used     []int
+	expected int
+	probes   int
+}{
+	{"backend gap", 7, 20, nil, 7, 0},
+	{"smaller gap", 7, 5, []int{15}, 7, 0},
+	{"unused chain", -1, 25, nil, -1, 5},
+	{"used beyond the backend gap", 3, 30, []int{30, 45}, 45, 52},
+	{"used after the gap", 3, 25, []int{30}, 3, 5},
+}
+
+func TestDiscoverChain(t *testing.T) {
+	for _, test := range testDiscovery {
+		var probes int
+		used := func(index int) (bool, error) {
+			probes++
+			if index <= test.lastUsed+backendGap {
+				return false, errors.New("the backend already checked " + strconv.Itoa(index))
+			}
+			for _, usedIndex := range test.used {
+				if usedIndex == index {
+					return true, nil
+				}
+			}
+			return false, nil
+		}
+		lastUsed, err := discoverChain(test.lastUsed, test.gap, used)
+		if err != nil {
+			t.Error(test.name + ": " + err.Error())
+			continue
+		}
+		if lastUsed != test.expected || probes != test.probes {
+			t.Error(test.name + ": got last used " + strconv.Itoa(lastUsed) + " after " + strconv.Itoa(probes) + " probes")
+		}
+	}
+}
+
+func TestParseAddressPath(t *testing.T) {
+	chain, index, err := parseAddressPath("m/44'/0'/0'/1/17")
+	if err != nil || chain != 1 || index != 17 {
+		t.Error("unexpected chain or index")
+	}
+	for _, path := range []string{"m/44'/0'/0'/1", "m/44'/0'/0'/x/1", "m/44'/0'/0'/0/1'"} {
+		if _, _, err := parseAddressPath(path); err == nil {
+			t.Error("expected an error for " + path)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package controllers
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/grupokindynos/common/coin-factory/coins"
+	"golang.org/x/crypto/sha3"
+)
+
+// ERC20 methods understood by DecodeERC20Data.
+const (
+	ERC20Transfer     = "transfer"
+	ERC20TransferFrom = "transferFrom"
+	ERC20Approve      = "approve"
+)
+
+const abiWordSize = 32
+
+// erc20Method describes the arguments of an ERC20 method, every argument is an ABI word.
+type erc20Method struct {
+	name string
+	args int
+}
+
+var erc20Methods = map[string]erc20Method{
+	string(methodID("transfer(address,uint256)")):             {ERC20Transfer, 2},
+	string(methodID("transferFrom(address,address,uint256)")): {ERC20TransferFrom, 3},
+	string(methodID("approve(address,uint256)")):              {ERC20Approve, 2},
+}
+
+var (
+	ErrERC20Method  = errors.New("the data is not a transfer, transferFrom or approve call")
+	ErrERC20Length  = errors.New("the length of the data doesn't match the arguments of the call")
+	ErrERC20Address = errors.New("the data holds an invalid address")
+)
+
+// ERC20Call is a decoded call to an ERC20 contract. To is the recipient of the tokens, or
+// the spender for approve, and From is only set for transferFrom.
+type ERC20Call struct {
+	Method string
+	From   common.Address
+	To     common.Address
+	Amount *big.Int
+}
+
+// Pays reports whether the call moves Amount tokens to To.
+func (c *ERC20Call) Pays() bool {
+	return c.Method == ERC20Transfer || c.Method == ERC20TransferFrom
+}
+
+func methodID(signature string) []byte {
+	hash := sha3.NewLegacyKeccak256()
+	hash.Write([]byte(signature))
+	return hash.Sum(nil)[:4]
+}
+
+// DecodeERC20Data decodes the calldata of a transaction sent to an ERC20 contract.
+func DecodeERC20Data(data []byte) (*ERC20Call, error) {
+	if len(data) < 4 {
+		return nil, ErrERC20Method
+	}
+	method, ok := erc20Methods[string(data[:4])]
+	if !ok {
+		return nil, ErrERC20Method
+	}
+	args := data[4:]
+	if len(args) != method.args*abiWordSize {
+		return nil, ErrERC20Length
+	}
+	words := make([][]byte, method.args)
+	for i := range words {
+		words[i] = args[i*abiWordSize : (i+1)*abiWordSize]
+	}
+	call := &ERC20Call{Method: method.name, Amount: new(big.Int).SetBytes(words[len(words)-1])}
+	addresses := words[:len(words)-1]
+	for _, word := range addresses {
+		// addresses are left padded with zeros to the size of a word
+		if !bytes.Equal(word[:abiWordSize-common.AddressLength], make([]byte, abiWordSize-common.AddressLength)) {
+			return nil, ErrERC20Address
+		}
+	}
+	call.To = common.BytesToAddress(addresses[len(addresses)-1])
+	if method.name == ERC20TransferFrom {
+		call.From = common.BytesToAddress(addresses[0])
+	}
+	return call, nil
+}
+
+// ethPayment returns the recipient and the amount paid by an ETH or ERC20 transaction, ok
+// is false when it doesn't transfer any coin or token. A token is only paid by a call to its
+// configured contract.
+func ethPayment(coinConfig *coins.Coin, tx *types.Transaction) (common.Address, *big.Int, bool, error) {
+	if tx.To() == nil {
+		return common.Address{}, nil, false, nil
+	}
+	if !coinConfig.Info.Token || coinConfig.Info.Tag == "ETH" {
+		return *tx.To(), tx.Value(), true, nil
+	}
+	if *tx.To() != common.HexToAddress(coinConfig.Info.Contract) {
+		return common.Address{}, nil, false, nil
+	}
+	call, err := DecodeERC20Data(tx.Data())
+	if err != nil {
+		return common.Address{}, nil, false, err
+	}
+	if !call.Pays() {
+		return common.Address{}, nil, false, nil
+	}
+	return call.To, call.Amount, true, nil
+}
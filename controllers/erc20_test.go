@@ -0,0 +1,82 @@
+package controllers
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	coinfactory "github.com/grupokindynos/common/coin-factory"
+)
+
+const (
+	testTokenRecipient = "000000000000000000000000673153460d01a22f9dac129f2ea59be3681921a4"
+	testTokenAmount    = "00000000000000000000000000000000000000000000000000000000002f4d60"
+	// testERC20RawTx transfers 3.1 USDT to testTokenRecipient
+	testERC20RawTx = "f8a91b84b2d05e0083030d4094dac17f958d2ee523a2206206994597c13d831ec780b844a9059cbb000000000000000000000000673153460d01a22f9dac129f2ea59be3681921a400000000000000000000000000000000000000000000000000000000002f4d601ca0accf91f7628d757135bb2ec51afdd990a029e98c0ef19cf4673799501d241e76a051208e147977fb79c09e06885687637787efa8b34d46b398d98b5ee5da10c081"
+)
+
+var erc20Tests = []struct {
+	data   string
+	method string
+	err    error
+}{
+	{"a9059cbb" + testTokenRecipient + testTokenAmount, ERC20Transfer, nil},
+	{"23b872dd" + testTokenRecipient + testTokenRecipient + testTokenAmount, ERC20TransferFrom, nil},
+	{"095ea7b3" + testTokenRecipient + testTokenAmount, ERC20Approve, nil},
+	{"", "", ErrERC20Method},
+	{"a905", "", ErrERC20Method},
+	{"a9059cbb" + testTokenRecipient, "", ErrERC20Length},
+	{"a9059cbb" + testTokenRecipient + testTokenAmount + "00", "", ErrERC20Length},
+	{"23b872dd" + testTokenRecipient + testTokenAmount, "", ErrERC20Length},
+	{"70a08231" + testTokenRecipient, "", ErrERC20Method},
+	{"a9059cbb" + "ff" + testTokenRecipient[2:] + testTokenAmount, "", ErrERC20Address},
+}
+
+func TestDecodeERC20Data(t *testing.T) {
+	for _, test := range erc20Tests {
+		data, _ := hex.DecodeString(test.data)
+		call, err := DecodeERC20Data(data)
+		if err != test.err {
+			t.Error("unexpected error for " + test.data)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if call.Method != test.method || call.To.Hex() != "0x673153460D01A22F9dAc129F2Ea59be3681921A4" || call.Amount.Int64() != 3100000 {
+			t.Error("unexpected call for " + test.data)
+		}
+		if call.Method == ERC20TransferFrom && call.From != call.To {
+			t.Error("the sender of transferFrom was not decoded")
+		}
+		if call.Pays() == (call.Method == ERC20Approve) {
+			t.Error("only the transfers pay the recipient")
+		}
+	}
+}
+
+func TestEthPaymentContract(t *testing.T) {
+	tx, err := decodeEthTx(testERC20RawTx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := *coinfactory.Coins["ETH"]
+	token.Info.Tag = "USDT"
+	token.Info.Token = true
+	token.Info.Contract = "0xdAC17F958D2ee523a2206206994597C13D831ec7"
+	recipient, amount, ok, err := ethPayment(&token, tx)
+	if err != nil || !ok || recipient != common.HexToAddress(testTokenRecipient[24:]) || amount.Int64() != 3100000 {
+		t.Error("the transfer of the configured contract must pay the token")
+	}
+	token.Info.Contract = "0x0000000000000000000000000000000000000001"
+	if _, _, ok, err := ethPayment(&token, tx); err != nil || ok {
+		t.Error("a transfer of another contract must not pay the token")
+	}
+	report, err := evmTxReport(&token, tx, recipient.Hex(), 3100000, func(common.Address) (uint64, error) { return 0, nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Valid || report.PaysAmount || len(report.Outputs) != 0 || report.EVM.Contract == report.EVM.TokenContract {
+		t.Error("the report must not accept a transfer of another contract")
+	}
+}
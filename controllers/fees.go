@@ -0,0 +1,113 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+	"strconv"
+	"time"
+
+	"github.com/eabz/btcutil"
+	"github.com/grupokindynos/common/blockbook"
+	"github.com/grupokindynos/common/coin-factory/coins"
+	"github.com/grupokindynos/common/plutus"
+	"github.com/grupokindynos/plutus/metrics"
+)
+
+const ethGasStationURL = "https://ethgasstation.info/json/ethgasAPI.json"
+
+// fallbackFeeRate is used (in satoshis per kB) when the backend is unable to estimate a fee.
+const fallbackFeeRate = 4000
+
+// getFeeRate returns the fee rate in satoshis per kB reported by the coin backend.
+func getFeeRate(coinConfig *coins.Coin) (int64, error) {
+	blockBookWrap := blockbook.NewBlockBookWrapper(coinConfig.Info.Blockbook)
+	var fee blockbook.Fee
+	var err error
+	start := time.Now()
+	if coinConfig.Info.Tag == "BTC" {
+		fee, err = blockBookWrap.GetFee("4")
+	} else {
+		fee, err = blockBookWrap.GetFee("2")
+	}
+	metrics.ObserveBlockbook(coinConfig.Info.Tag, "fee", start, err)
+	if err != nil {
+		return 0, err
+	}
+	if fee.Result == "-1" || fee.Result == "0" || fee.Result == "" {
+		return fallbackFeeRate, nil
+	}
+	feeParse, err := strconv.ParseFloat(fee.Result, 64)
+	if err != nil {
+		return 0, err
+	}
+	return int64(feeParse * 1e8), nil
+}
+
+// estimateFee calculates the fee of a transaction with the given amount of inputs and outputs.
+func estimateFee(feeRate int64, inputs int, outputs int) btcutil.Amount {
+	txSize := (inputs * 180) + (outputs * 34) + 124
+	feeSats := float64(feeRate) / 1024.0 * float64(txSize)
+	return btcutil.Amount(int64(feeSats))
+}
+
+// getGasPrice returns the average gas price in wei.
+func getGasPrice() (*big.Int, error) {
+	var gasStation GasStation
+	err := getJSON(ethGasStationURL, &gasStation)
+	if err != nil {
+		return nil, errors.New("could not retrieve the gas price")
+	}
+	return big.NewInt(int64(1000000000 * (gasStation.Average / 10))), nil //(10^9*(gweiValue/10))
+}
+
+// gasFee returns the maximum fee in ETH a transaction with the given gas price and limit can pay.
+func gasFee(gasPrice *big.Int, gasLimit uint64) float64 {
+	fee := new(big.Int).Mul(gasPrice, new(big.Int).SetUint64(gasLimit))
+	feeEth, _ := new(big.Float).Quo(new(big.Float).SetInt(fee), big.NewFloat(1e18)).Float64()
+	return feeEth
+}
+
+func ethGasLimit(coinConfig *coins.Coin) uint64 {
+	if coinConfig.Info.Tag != "ETH" {
+		return uint64(200000)
+	}
+	return uint64(21000)
+}
+
+// EstimateFee returns the fee, in units of the coin paying it, that a send of the requested amount would pay.
+func (c *Controller) EstimateFee(params Params) (interface{}, error) {
+	var SendToAddressData plutus.SendAddressBodyReq
+	err := json.Unmarshal(params.Body, &SendToAddressData)
+	if err != nil {
+		return nil, err
+	}
+	coinConfig, err := getCoin(SendToAddressData.Coin)
+	if err != nil {
+		return nil, err
+	}
+	if coinConfig.Info.Token || coinConfig.Info.Tag == "ETH" {
+		gasPrice, err := getGasPrice()
+		if err != nil {
+			return nil, err
+		}
+		return gasFee(gasPrice, ethGasLimit(coinConfig)), nil
+	}
+	acc, err := getAccPub(coinConfig)
+	if err != nil {
+		return nil, err
+	}
+	blockBookWrap := blockbook.NewBlockBookWrapper(coinConfig.Info.Blockbook)
+	utxos, err := blockBookWrap.GetUtxo(acc.String(), false)
+	if err != nil {
+		return nil, err
+	}
+	if len(utxos) == 0 {
+		return nil, errors.New("no balance available")
+	}
+	feeRate, err := getFeeRate(coinConfig)
+	if err != nil {
+		return nil, err
+	}
+	return estimateFee(feeRate, len(utxos), 0).ToBTC(), nil
+}
@@ -0,0 +1,82 @@
+package controllers
+
+import (
+	"testing"
+
+	"github.com/eabz/btcutil"
+	"github.com/eabz/btcutil/hdkeychain"
+	"github.com/eabz/btcutil/txscript"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/grupokindynos/plutus/signer"
+	"github.com/martinboehm/btcd/btcec"
+	"github.com/martinboehm/btcd/chaincfg/chainhash"
+	"github.com/martinboehm/btcd/wire"
+	hdwallet "github.com/miguelmota/go-ethereum-hdwallet"
+)
+
+// testPrevTxid is the txid of the outputs spent by the test transactions.
+const testPrevTxid = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+type testKeySource struct {
+	privKey *btcec.PrivateKey
+}
+
+func (k testKeySource) PrivateKey(coin string, index uint32) (*btcec.PrivateKey, error) {
+	return k.privKey, nil
+}
+
+func (k testKeySource) EthWallet(tag string) (*hdwallet.Wallet, accounts.Account, error) {
+	return nil, accounts.Account{}, nil
+}
+
+// useTestKey configures the test mnemonic on the coin and makes the signer use the key of
+// the test address. It returns the private account and that key, the caller restores txSigner.
+func useTestKey(t *testing.T, test testData) (*hdkeychain.ExtendedKey, *btcec.PrivateKey) {
+	test.coin.Mnemonic = testMnemonic
+	acc, err := getAccFromMnemonic(test.coin, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	privKey, err := getPrivKeyFromPath(acc, test.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	SetSigner(signer.NewLocal(testKeySource{privKey: privKey}))
+	return acc, privKey
+}
+
+// testAddrScript returns the output script paying the test address.
+func testAddrScript(t *testing.T, test testData) []byte {
+	addr, err := btcutil.DecodeAddress(test.addr, test.coin.NetParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	script, err := txscript.PayToAddrScript(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return script
+}
+
+// testSpendingTx returns a transaction spending the outputs vouts of prevHash.
+func testSpendingTx(prevHash *chainhash.Hash, vouts ...uint32) *wire.MsgTx {
+	tx := wire.NewMsgTx(1)
+	for _, vout := range vouts {
+		tx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(prevHash, vout), nil, nil))
+	}
+	return tx
+}
+
+func testPrevHash() *chainhash.Hash {
+	prevHash, _ := chainhash.NewHashFromStr(testPrevTxid)
+	return prevHash
+}
+
+// testPrevTx returns a transaction paying value to script, and a transaction spending it.
+func testPrevTx(value int64, script []byte) (*wire.MsgTx, *wire.MsgTx) {
+	prevTx := testSpendingTx(testPrevHash(), 0)
+	prevTx.TxIn[0].SignatureScript = []byte{0x51}
+	prevTx.AddTxOut(wire.NewTxOut(value, script))
+	prevTxHash := prevTx.TxHash()
+	return prevTx, testSpendingTx(&prevTxHash, 0)
+}
@@ -0,0 +1,56 @@
+//go:build go1.18
+// +build go1.18
+
+package controllers
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+	"testing"
+
+	coinfactory "github.com/grupokindynos/common/coin-factory"
+	"github.com/grupokindynos/common/plutus"
+)
+
+func FuzzDecodeERC20Data(f *testing.F) {
+	for _, test := range erc20Tests {
+		data, _ := hex.DecodeString(test.data)
+		f.Add(data)
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		call, err := DecodeERC20Data(data)
+		if err == nil && (call.Amount == nil || len(data) < 68) {
+			t.Error("a call was decoded from invalid data")
+		}
+	})
+}
+
+func FuzzValidateRawTx(f *testing.F) {
+	var tags []string
+	for tag := range coinfactory.Coins {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	ctrl := &Controller{Address: make(map[string]AddrInfo), availability: newCoinAvailability()}
+	f.Add(uint8(0), testERC20RawTx, int64(3100000), "0x673153460D01A22F9dAc129F2Ea59be3681921A4")
+	f.Add(uint8(1), "0x"+testERC20RawTx[:20], int64(0), "")
+	f.Add(uint8(2), "0x", int64(1), "")
+	f.Add(uint8(3), "", int64(1), "")
+	f.Add(uint8(4), "0100000001", int64(1), "")
+	f.Fuzz(func(t *testing.T, coin uint8, rawTx string, amount int64, address string) {
+		body, err := json.Marshal(plutus.ValidateRawTxReq{
+			Coin:    tags[int(coin)%len(tags)],
+			RawTx:   rawTx,
+			Amount:  amount,
+			Address: address,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		valid, err := ctrl.ValidateRawTx(Params{Body: body})
+		if err == nil && valid != true && valid != false {
+			t.Error("the validation must answer a boolean")
+		}
+	})
+}
@@ -0,0 +1,220 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	coinfactory "github.com/grupokindynos/common/coin-factory"
+	"github.com/grupokindynos/common/coin-factory/coins"
+	"github.com/grupokindynos/plutus/logger"
+	"github.com/grupokindynos/plutus/models"
+)
+
+// readinessTTL is how long a readiness check is reused, so the probe can be polled freely
+// without hammering the backends.
+const readinessTTL = 15 * time.Second
+
+const (
+	ReadinessReady       = "ready"
+	ReadinessDegraded    = "degraded"
+	ReadinessUnavailable = "unavailable"
+)
+
+// syncStatus keeps the time of the last successful address sync of every coin.
+var syncStatus = struct {
+	sync.RWMutex
+	lastSync map[string]time.Time
+}{lastSync: make(map[string]time.Time)}
+
+var readinessCache struct {
+	sync.Mutex
+	readiness models.Readiness
+}
+
+func markSynced(tag string) {
+	syncStatus.Lock()
+	defer syncStatus.Unlock()
+	syncStatus.lastSync[tag] = time.Now()
+}
+
+func lastSynced(tag string) (time.Time, bool) {
+	syncStatus.RLock()
+	defer syncStatus.RUnlock()
+	lastSync, ok := syncStatus.lastSync[tag]
+	return lastSync, ok
+}
+
+// syncsAddresses reports whether the controllers keep the address set of a coin.
+func syncsAddresses(coin *coins.Coin) bool {
+	return !coin.Info.Token && coin.Info.Tag != "ETH" && coin.Info.Tag != "XSG" && coin.Info.Tag != "DAPS"
+}
+
+// AvailabilityReporter is implemented by the controllers, which track the coins whose sync
+// failed separately.
+type AvailabilityReporter interface {
+	Unavailable() map[string]string
+}
+
+// Readiness reports the state of the backend of every coin and of the gas oracle. Only
+// booleans are reported for the mnemonics, no key material is ever exposed. The service
+// is ready as long as one configured coin is, unavailable coins are rejected per request.
+// A coin is unavailable when any of the controllers failed to sync it.
+func Readiness(controllers ...AvailabilityReporter) models.Readiness {
+	readinessCache.Lock()
+	defer readinessCache.Unlock()
+	if time.Since(readinessCache.readiness.CheckedAt) < readinessTTL {
+		return readinessCache.readiness
+	}
+	tags := make([]string, 0, len(coinfactory.Coins))
+	for tag := range coinfactory.Coins {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	ethConfig, err := getCoin("ETH")
+	hasEthMnemonic := err == nil && ethConfig.Mnemonic != ""
+
+	// Tokens share the ETH backend, every url is probed once.
+	urls := []string{ethGasStationURL}
+	seen := make(map[string]bool)
+	for _, tag := range tags {
+		url := coinfactory.Coins[tag].Info.Blockbook
+		if !seen[url] {
+			seen[url] = true
+			urls = append(urls, url)
+		}
+	}
+	probes := make(map[string]models.ServiceHealth)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, url := range urls {
+		wg.Add(1)
+		go func(url string) {
+			defer wg.Done()
+			target := url
+			if url != ethGasStationURL {
+				target = strings.TrimRight(url, "/") + "/api"
+			}
+			health := probe(target)
+			mu.Lock()
+			probes[url] = health
+			mu.Unlock()
+		}(url)
+	}
+	wg.Wait()
+	for _, url := range urls {
+		if health := probes[url]; !health.Reachable {
+			logger.Warn("Readiness: the backend is unreachable", "url", url, "err", health.Error)
+		}
+	}
+
+	unavailable := mergeUnavailable(controllers)
+	readiness := models.Readiness{
+		CheckedAt: time.Now(),
+		WatchOnly: watchOnly(),
+		GasOracle: probes[ethGasStationURL],
+	}
+	for _, tag := range tags {
+		coin, err := getCoin(tag)
+		if err != nil {
+			continue
+		}
+		backend := probes[coin.Info.Blockbook]
+		coinHealth := models.CoinHealth{
+			Coin:      coin.Info.Tag,
+			Reachable: backend.Reachable,
+			LatencyMs: backend.LatencyMs,
+			Error:     backend.Error,
+		}
+		if coin.Info.Token || coin.Info.Tag == "ETH" {
+			coinHealth.MnemonicAvailable = hasEthMnemonic
+		} else {
+			coinHealth.MnemonicAvailable = coin.Mnemonic != ""
+		}
+		if lastSync, ok := lastSynced(coin.Info.Tag); ok {
+			coinHealth.LastSync = &lastSync
+		}
+		coinHealth.Ready = coinHealth.MnemonicAvailable && coinHealth.Reachable
+		if publicKeysOnly() {
+			coinHealth.PublicKeyAvailable = publicKeysAvailable(coin)
+			coinHealth.Ready = coinHealth.PublicKeyAvailable && coinHealth.Reachable
+		}
+		if reason, ok := unavailable[coin.Info.Tag]; ok {
+			coinHealth.Ready = false
+			coinHealth.Error = reason
+		}
+		if (coin.Info.Token || coin.Info.Tag == "ETH") && !readiness.GasOracle.Reachable {
+			coinHealth.Ready = false
+		}
+		if coinHealth.Ready {
+			readiness.Ready = true
+		}
+		readiness.Coins = append(readiness.Coins, coinHealth)
+	}
+	readiness.Status = ReadinessUnavailable
+	if readiness.Ready {
+		readiness.Status = ReadinessReady
+		for _, coinHealth := range readiness.Coins {
+			if !coinHealth.Ready {
+				readiness.Status = ReadinessDegraded
+				break
+			}
+		}
+	}
+	readinessCache.readiness = readiness
+	return readiness
+}
+
+// mergeUnavailable returns the coins unavailable in any of the controllers with their reasons.
+func mergeUnavailable(controllers []AvailabilityReporter) map[string]string {
+	unavailable := make(map[string]string)
+	for _, ctrl := range controllers {
+		for tag, reason := range ctrl.Unavailable() {
+			if previous, ok := unavailable[tag]; ok && previous != reason {
+				reason = previous + "; " + reason
+			}
+			unavailable[tag] = reason
+		}
+	}
+	return unavailable
+}
+
+// ServeReadiness answers the readiness probe, 503 when no coin is ready. The probe is not
+// authenticated: only the status of every check is written, the backend errors are logged.
+func ServeReadiness(w http.ResponseWriter, readiness models.Readiness) {
+	readiness.GasOracle.Error = ""
+	coinsHealth := make([]models.CoinHealth, len(readiness.Coins))
+	for i, coinHealth := range readiness.Coins {
+		coinHealth.Error = ""
+		coinsHealth[i] = coinHealth
+	}
+	readiness.Coins = coinsHealth
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	if !readiness.Ready {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	_ = json.NewEncoder(w).Encode(readiness)
+}
+
+func probe(url string) models.ServiceHealth {
+	start := time.Now()
+	res, err := myClient.Get(url)
+	health := models.ServiceHealth{
+		LatencyMs: int64(time.Since(start) / time.Millisecond),
+	}
+	if err != nil {
+		health.Error = err.Error()
+		return health
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		health.Error = "unexpected status code " + strconv.Itoa(res.StatusCode)
+		return health
+	}
+	health.Reachable = true
+	return health
+}
@@ -0,0 +1,60 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/grupokindynos/plutus/models"
+)
+
+var serveReadinessTests = []struct {
+	readiness models.Readiness
+	code      int
+}{
+	{models.Readiness{Ready: true, Status: ReadinessReady, Coins: []models.CoinHealth{{Coin: "BTC", Ready: true, Reachable: true}}}, http.StatusOK},
+	{models.Readiness{Ready: true, Status: ReadinessDegraded, Coins: []models.CoinHealth{{Coin: "BTC", Ready: true, Reachable: true}, {Coin: "LTC", Error: "dial tcp: lookup ltc.blockbook.example"}}}, http.StatusOK},
+	{models.Readiness{Status: ReadinessUnavailable, Coins: []models.CoinHealth{{Coin: "BTC", Error: "Get https://btc.blockbook.example/api: timeout"}}, GasOracle: models.ServiceHealth{Error: "Get https://gas.example: timeout"}}, http.StatusServiceUnavailable},
+}
+
+func TestServeReadiness(t *testing.T) {
+	for _, test := range serveReadinessTests {
+		rec := httptest.NewRecorder()
+		ServeReadiness(rec, test.readiness)
+		if rec.Code != test.code {
+			t.Error("unexpected status code for a " + test.readiness.Status + " readiness")
+		}
+		if strings.Contains(rec.Body.String(), "example") || strings.Contains(rec.Body.String(), "error") {
+			t.Error("the backend errors must not be exposed " + rec.Body.String())
+		}
+		var readiness models.Readiness
+		if err := json.Unmarshal(rec.Body.Bytes(), &readiness); err != nil {
+			t.Fatal(err)
+		}
+		if readiness.Status != test.readiness.Status || len(readiness.Coins) != len(test.readiness.Coins) {
+			t.Error("the status of every check must be reported " + rec.Body.String())
+		}
+	}
+	if serveReadinessTests[2].readiness.Coins[0].Error == "" {
+		t.Error("the readiness of the caller must not be modified")
+	}
+}
+
+func TestMergeUnavailable(t *testing.T) {
+	v1 := &Controller{availability: newCoinAvailability()}
+	v2 := &ControllerV2{availability: newCoinAvailability()}
+	v1.availability.markUnavailable("BTC", errors.New("timeout"))
+	v2.availability.markUnavailable("BTC", errors.New("timeout"))
+	v2.availability.markUnavailable("LTC", errors.New("connection refused"))
+	unavailable := mergeUnavailable([]AvailabilityReporter{v1, v2})
+	if len(unavailable) != 2 || unavailable["BTC"] != "timeout" || unavailable["LTC"] != "connection refused" {
+		t.Error("a coin unavailable in any controller must be unavailable")
+	}
+	v1.availability.markUnavailable("LTC", errors.New("bad gateway"))
+	if unavailable := mergeUnavailable([]AvailabilityReporter{v1, v2}); unavailable["LTC"] != "bad gateway; connection refused" {
+		t.Error("the reasons of both controllers must be kept")
+	}
+}
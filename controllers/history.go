@@ -0,0 +1,534 @@
+package controllers
+
+import (
+	"errors"
+	"math/big"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/grupokindynos/common/coin-factory/coins"
+	"github.com/grupokindynos/plutus/metrics"
+	"github.com/grupokindynos/plutus/models"
+)
+
+const (
+	historyDefaultLimit = 50
+	// historyMaxLimit is the largest page served by blockbook.
+	historyMaxLimit = 1000
+	// historyMaxPages bounds the backend pages read to cover a period.
+	historyMaxPages = 50
+)
+
+// Directions of the history transactions.
+const (
+	directionIn   = "in"
+	directionOut  = "out"
+	directionSelf = "self"
+)
+
+type historyQuery struct {
+	page  int
+	limit int
+	// from and to are zero when the history is not filtered.
+	from time.Time
+	to   time.Time
+}
+
+func parseHistoryQuery(params ParamsV2) (historyQuery, error) {
+	q := historyQuery{page: 1, limit: historyDefaultLimit}
+	var err error
+	if params.Page != "" {
+		q.page, err = strconv.Atoi(params.Page)
+		if err != nil || q.page < 1 {
+			return q, errors.New("the page must be a positive number")
+		}
+	}
+	if params.Limit != "" {
+		q.limit, err = strconv.Atoi(params.Limit)
+		if err != nil || q.limit < 1 || q.limit > historyMaxLimit {
+			return q, errors.New("the limit must be between 1 and " + strconv.Itoa(historyMaxLimit))
+		}
+	}
+	if params.From != "" {
+		q.from, err = parseHistoryTime(params.From)
+		if err != nil {
+			return q, err
+		}
+	}
+	if params.To != "" {
+		q.to, err = parseHistoryTime(params.To)
+		if err != nil {
+			return q, err
+		}
+	}
+	if !q.from.IsZero() && !q.to.IsZero() && q.to.Before(q.from) {
+		return q, errors.New("the end of the period is before its start")
+	}
+	return q, nil
+}
+
+// parseHistoryTime accepts unix timestamps and RFC3339 times.
+func parseHistoryTime(value string) (time.Time, error) {
+	if unix, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(unix, 0).UTC(), nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, errors.New("invalid time " + value + ", use a unix timestamp or RFC3339")
+	}
+	return t.UTC(), nil
+}
+
+type blockbookHistory struct {
+	TotalPages   int                  `json:"totalPages"`
+	Txs          int                  `json:"txs"`
+	Transactions []blockbookHistoryTx `json:"transactions"`
+}
+
+type blockbookHistoryTx struct {
+	Txid           string               `json:"txid"`
+	Vin            []blockbookHistoryIO `json:"vin"`
+	Vout           []blockbookHistoryIO `json:"vout"`
+	BlockHeight    int                  `json:"blockHeight"`
+	Confirmations  int                  `json:"confirmations"`
+	BlockTime      int64                `json:"blockTime"`
+	Value          string               `json:"value"`
+	Fees           string               `json:"fees"`
+	TokenTransfers []struct {
+		From     string `json:"from"`
+		To       string `json:"to"`
+		Token    string `json:"token"`
+		Decimals int    `json:"decimals"`
+		Value    string `json:"value"`
+	} `json:"tokenTransfers"`
+}
+
+// historyPage is a page of the transactions of the backend.
+type historyPage struct {
+	txs        []models.HistoryTx
+	total      int
+	totalPages int
+}
+
+// blockbookHistoryIO is an input or an output, IsOwn marks the addresses of the xpub.
+type blockbookHistoryIO struct {
+	Value     string   `json:"value"`
+	Addresses []string `json:"addresses"`
+	IsOwn     bool     `json:"isOwn"`
+}
+
+// GetHistoryV2 returns the transactions of the wallet of a coin, newest first, annotated with
+// the journal of the sends. The page, limit, from and to query parameters select them.
+func (c *ControllerV2) GetHistoryV2(params ParamsV2) (interface{}, error) {
+	coinConfig, err := getCoin(params.Coin)
+	if err != nil {
+		return nil, err
+	}
+	q, err := parseHistoryQuery(params)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.availability.check(coinConfig); err != nil {
+		return nil, err
+	}
+	var read historyReader
+	if coinConfig.Info.Token || coinConfig.Info.Tag == "ETH" {
+		address, err := ethAddress(ethAccountTag(params.Service))
+		if err != nil {
+			return nil, err
+		}
+		read = func(page int, pageSize int) (historyPage, error) {
+			return ethHistory(coinConfig, address.Hex(), page, pageSize)
+		}
+	} else {
+		accPub, err := getAccPub(coinConfig)
+		if err != nil {
+			return nil, err
+		}
+		read = func(page int, pageSize int) (historyPage, error) {
+			return utxoHistory(coinConfig, accPub.String(), page, pageSize)
+		}
+	}
+	entries, err := journalEntries(coinConfig.Info.Tag)
+	if err != nil {
+		return nil, err
+	}
+	if q.from.IsZero() && q.to.IsZero() {
+		page, err := read(q.page, q.limit)
+		if err != nil {
+			return nil, err
+		}
+		return newHistory(coinConfig.Info.Tag, page, entries, q), nil
+	}
+	txs, err := periodHistory(read, q)
+	if err != nil {
+		return nil, err
+	}
+	return newPeriodHistory(coinConfig.Info.Tag, txs, entries, q), nil
+}
+
+// historyReader reads a page of the transactions of the wallet, newest first.
+type historyReader func(page int, pageSize int) (historyPage, error)
+
+// periodHistory reads the pages of the backend until the period is covered and returns the
+// transactions read. The backend can't filter by time, only by block height.
+func periodHistory(read historyReader, q historyQuery) ([]models.HistoryTx, error) {
+	var txs []models.HistoryTx
+	for page := 1; ; page++ {
+		if page > historyMaxPages {
+			return nil, errors.New("the period holds too many transactions, narrow it")
+		}
+		res, err := read(page, historyMaxLimit)
+		if err != nil {
+			return nil, err
+		}
+		txs = append(txs, res.txs...)
+		if page >= res.totalPages || len(res.txs) == 0 {
+			return txs, nil
+		}
+		last := res.txs[len(res.txs)-1]
+		if !q.from.IsZero() && !last.Time.IsZero() && last.Time.Before(q.from) {
+			return txs, nil
+		}
+	}
+}
+
+// fetchHistory reads a page of the transactions of path, newest first.
+func fetchHistory(coinConfig *coins.Coin, path string, page int, pageSize int) (blockbookHistory, error) {
+	var res blockbookHistory
+	start := time.Now()
+	err := getJSON(strings.TrimRight(coinConfig.Info.Blockbook, "/")+path+"&page="+strconv.Itoa(page)+"&pageSize="+strconv.Itoa(pageSize), &res)
+	metrics.ObserveBlockbook(coinConfig.Info.Tag, "history", start, err)
+	return res, err
+}
+
+func utxoHistory(coinConfig *coins.Coin, xpub string, page int, pageSize int) (historyPage, error) {
+	res, err := fetchHistory(coinConfig, "/api/v2/xpub/"+xpub+"?details=txs", page, pageSize)
+	if err != nil {
+		return historyPage{}, err
+	}
+	history := historyPage{total: res.Txs, totalPages: res.TotalPages}
+	for _, tx := range res.Transactions {
+		historyTx, err := utxoHistoryTx(tx)
+		if err != nil {
+			return historyPage{}, err
+		}
+		history.txs = append(history.txs, historyTx)
+	}
+	return history, nil
+}
+
+// utxoHistoryTx computes the amount moved by a transaction of the xpub. Addresses are our
+// receiving addresses for the incoming transactions and the destinations for the others.
+func utxoHistoryTx(tx blockbookHistoryTx) (models.HistoryTx, error) {
+	historyTx := newHistoryTx(tx)
+	var received, sent int64
+	var own, others []string
+	for _, in := range tx.Vin {
+		if !in.IsOwn {
+			continue
+		}
+		value, err := strconv.ParseInt(in.Value, 10, 64)
+		if err != nil {
+			return historyTx, err
+		}
+		sent += value
+	}
+	for _, out := range tx.Vout {
+		value, err := strconv.ParseInt(out.Value, 10, 64)
+		if err != nil {
+			return historyTx, err
+		}
+		if out.IsOwn {
+			received += value
+			own = append(own, out.Addresses...)
+		} else {
+			others = append(others, out.Addresses...)
+		}
+	}
+	fee, err := unitsToFloat(tx.Fees, 8)
+	if err != nil {
+		return historyTx, err
+	}
+	historyTx.Amount = float64(received-sent) / 1e8
+	switch {
+	case sent == 0:
+		historyTx.Direction = directionIn
+		historyTx.Addresses = own
+	case len(others) == 0:
+		historyTx.Direction = directionSelf
+		historyTx.Addresses = own
+		historyTx.Fee = fee
+	default:
+		historyTx.Direction = directionOut
+		historyTx.Addresses = others
+		historyTx.Fee = fee
+	}
+	return historyTx, nil
+}
+
+func ethHistory(coinConfig *coins.Coin, address string, page int, pageSize int) (historyPage, error) {
+	path := "/api/v2/address/" + address + "?details=txs"
+	token := coinConfig.Info.Token && coinConfig.Info.Tag != "ETH"
+	if token {
+		path += "&contract=" + url.QueryEscape(coinConfig.Info.Contract)
+	}
+	ethConfig, err := getCoin("ETH")
+	if err != nil {
+		return historyPage{}, err
+	}
+	res, err := fetchHistory(ethConfig, path, page, pageSize)
+	if err != nil {
+		return historyPage{}, err
+	}
+	history := historyPage{total: res.Txs, totalPages: res.TotalPages}
+	for _, tx := range res.Transactions {
+		var historyTx models.HistoryTx
+		var ok bool
+		if token {
+			historyTx, ok, err = tokenHistoryTx(tx, address, coinConfig.Info.Contract)
+		} else {
+			historyTx, ok, err = ethHistoryTx(tx, address)
+		}
+		if err != nil {
+			return historyPage{}, err
+		}
+		if ok {
+			history.txs = append(history.txs, historyTx)
+		}
+	}
+	return history, nil
+}
+
+// ethHistoryTx computes the ether moved by a transaction of the address.
+func ethHistoryTx(tx blockbookHistoryTx, address string) (models.HistoryTx, bool, error) {
+	historyTx := newHistoryTx(tx)
+	if len(tx.Vin) == 0 || len(tx.Vin[0].Addresses) == 0 || len(tx.Vout) == 0 || len(tx.Vout[0].Addresses) == 0 {
+		return historyTx, false, nil
+	}
+	from, to := tx.Vin[0].Addresses[0], tx.Vout[0].Addresses[0]
+	value, err := unitsToFloat(tx.Value, 18)
+	if err != nil {
+		return historyTx, false, err
+	}
+	fee, err := unitsToFloat(tx.Fees, 18)
+	if err != nil {
+		return historyTx, false, err
+	}
+	historyTx.Direction, historyTx.Amount = transferDirection(from, to, address, value)
+	if historyTx.Direction == "" {
+		return historyTx, false, nil
+	}
+	if historyTx.Direction != directionIn {
+		historyTx.Fee = fee
+	}
+	historyTx.Addresses = []string{to}
+	if historyTx.Direction == directionIn {
+		historyTx.Addresses = []string{from}
+	}
+	return historyTx, true, nil
+}
+
+// tokenHistoryTx computes the tokens of contract moved by a transaction of the address. The
+// fee is paid in ether.
+func tokenHistoryTx(tx blockbookHistoryTx, address string, contract string) (models.HistoryTx, bool, error) {
+	historyTx := newHistoryTx(tx)
+	var found, sent bool
+	for _, transfer := range tx.TokenTransfers {
+		if !strings.EqualFold(transfer.Token, contract) {
+			continue
+		}
+		value, err := unitsToFloat(transfer.Value, transfer.Decimals)
+		if err != nil {
+			return historyTx, false, err
+		}
+		direction, amount := transferDirection(transfer.From, transfer.To, address, value)
+		if direction == "" {
+			continue
+		}
+		found = true
+		sent = sent || direction != directionIn
+		historyTx.Amount += amount
+		if direction == directionIn {
+			historyTx.Addresses = append(historyTx.Addresses, transfer.From)
+		} else {
+			historyTx.Addresses = append(historyTx.Addresses, transfer.To)
+		}
+	}
+	if !found {
+		return historyTx, false, nil
+	}
+	switch {
+	case !sent:
+		historyTx.Direction = directionIn
+	case historyTx.Amount == 0:
+		historyTx.Direction = directionSelf
+	default:
+		historyTx.Direction = directionOut
+	}
+	if sent {
+		fee, err := unitsToFloat(tx.Fees, 18)
+		if err != nil {
+			return historyTx, false, err
+		}
+		historyTx.Fee = fee
+	}
+	return historyTx, true, nil
+}
+
+// transferDirection returns the direction of a transfer for address and the amount it
+// received, the direction is empty when the address is not involved.
+func transferDirection(from string, to string, address string, value float64) (string, float64) {
+	fromUs, toUs := strings.EqualFold(from, address), strings.EqualFold(to, address)
+	switch {
+	case fromUs && toUs:
+		return directionSelf, 0
+	case fromUs:
+		return directionOut, -value
+	case toUs:
+		return directionIn, value
+	}
+	return "", 0
+}
+
+func newHistoryTx(tx blockbookHistoryTx) models.HistoryTx {
+	historyTx := models.HistoryTx{
+		Txid:          tx.Txid,
+		BlockHeight:   tx.BlockHeight,
+		Confirmations: tx.Confirmations,
+		Addresses:     []string{},
+	}
+	if tx.BlockTime != 0 {
+		historyTx.Time = time.Unix(tx.BlockTime, 0).UTC()
+	}
+	return historyTx
+}
+
+// unitsToFloat converts an integer amount of the smallest unit to coins.
+func unitsToFloat(value string, decimals int) (float64, error) {
+	if value == "" {
+		return 0, nil
+	}
+	units, ok := new(big.Int).SetString(value, 10)
+	if !ok {
+		return 0, errors.New("invalid amount " + value)
+	}
+	amount, _ := new(big.Float).Quo(new(big.Float).SetInt(units), new(big.Float).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimals)), nil))).Float64()
+	return amount, nil
+}
+
+// newHistory annotates a page of the backend with the journal. The sends unknown to the
+// backend are listed on the first page.
+func newHistory(tag string, page historyPage, entries []models.SendJournalEntry, q historyQuery) models.History {
+	txs := page.txs
+	var oldest time.Time
+	for _, tx := range txs {
+		if !tx.Time.IsZero() && (oldest.IsZero() || tx.Time.Before(oldest)) {
+			oldest = tx.Time
+		}
+	}
+	total := page.total
+	for _, tx := range annotateHistory(txs, entries) {
+		// older sends belong to the next pages of the backend
+		if q.page > 1 || page.totalPages > 1 && tx.Time.Before(oldest) {
+			continue
+		}
+		total++
+		txs = append(txs, tx)
+	}
+	sortHistory(txs)
+	if txs == nil {
+		txs = []models.HistoryTx{}
+	}
+	return models.History{
+		Coin:         tag,
+		Page:         q.page,
+		Limit:        q.limit,
+		Total:        total,
+		TotalPages:   page.totalPages,
+		Transactions: txs,
+	}
+}
+
+// newPeriodHistory annotates the transactions with the journal, keeps those of the period and
+// returns the requested page of them.
+func newPeriodHistory(tag string, txs []models.HistoryTx, entries []models.SendJournalEntry, q historyQuery) models.History {
+	txs = append(txs, annotateHistory(txs, entries)...)
+	filtered := []models.HistoryTx{}
+	for _, tx := range txs {
+		if tx.Time.IsZero() {
+			// the unconfirmed transactions belong to the periods up to now
+			if !q.to.IsZero() {
+				continue
+			}
+		} else if tx.Time.Before(q.from) || !q.to.IsZero() && tx.Time.After(q.to) {
+			continue
+		}
+		filtered = append(filtered, tx)
+	}
+	sortHistory(filtered)
+	history := models.History{
+		Coin:         tag,
+		Page:         q.page,
+		Limit:        q.limit,
+		Total:        len(filtered),
+		TotalPages:   (len(filtered) + q.limit - 1) / q.limit,
+		Transactions: []models.HistoryTx{},
+	}
+	start := (q.page - 1) * q.limit
+	if start < len(filtered) {
+		end := start + q.limit
+		if end > len(filtered) {
+			end = len(filtered)
+		}
+		history.Transactions = filtered[start:end]
+	}
+	return history
+}
+
+// annotateHistory attaches the journal entries to the transactions and returns the sends
+// unknown to the backend. The replaced sends are dropped by the nodes.
+func annotateHistory(txs []models.HistoryTx, entries []models.SendJournalEntry) []models.HistoryTx {
+	journal := make(map[string]models.SendJournalEntry)
+	for _, entry := range entries {
+		journal[strings.ToLower(entry.Txid)] = entry
+	}
+	for i := range txs {
+		txid := strings.ToLower(txs[i].Txid)
+		if entry, ok := journal[txid]; ok {
+			txs[i].Send = &entry
+			delete(journal, txid)
+		}
+	}
+	var unseen []models.HistoryTx
+	for _, entry := range journal {
+		if entry.ReplacedBy != "" {
+			continue
+		}
+		entry := entry
+		unseen = append(unseen, models.HistoryTx{
+			Txid:      entry.Txid,
+			Time:      entry.CreatedAt,
+			Direction: directionOut,
+			Amount:    -entry.Amount,
+			Fee:       entry.Fee,
+			Addresses: []string{entry.Address},
+			Send:      &entry,
+			Unseen:    true,
+		})
+	}
+	return unseen
+}
+
+// sortHistory lists the unconfirmed transactions first, then the newest first.
+func sortHistory(txs []models.HistoryTx) {
+	sort.SliceStable(txs, func(i, j int) bool {
+		if (txs[i].Confirmations == 0) != (txs[j].Confirmations == 0) {
+			return txs[i].Confirmations == 0
+		}
+		return txs[i].Time.After(txs[j].Time)
+	})
+}
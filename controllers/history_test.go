@@ -0,0 +1,194 @@
+package controllers
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/grupokindynos/plutus/models"
+)
+
+const testHistoryAddress = "0x673153460D01a22f9DAc129F2eA59Be3681921A4"
+
+var historyQueryTests = []struct {
+	params ParamsV2
+	valid  bool
+}{
+	{ParamsV2{}, true},
+	{ParamsV2{Page: "2", Limit: "10"}, true},
+	{ParamsV2{From: "1577836800", To: "2020-02-01T00:00:00Z"}, true},
+	{ParamsV2{Page: "0"}, false},
+	{ParamsV2{Limit: "1001"}, false},
+	{ParamsV2{From: "yesterday"}, false},
+	{ParamsV2{From: "2020-02-01T00:00:00Z", To: "1577836800"}, false},
+}
+
+func TestParseHistoryQuery(t *testing.T) {
+	for _, test := range historyQueryTests {
+		_, err := parseHistoryQuery(test.params)
+		if (err == nil) != test.valid {
+			t.Error("unexpected result for the history query " + test.params.Page + " " + test.params.Limit + " " + test.params.From + " " + test.params.To)
+		}
+	}
+}
+
+var utxoHistoryTests = []struct {
+	tx        blockbookHistoryTx
+	direction string
+	amount    float64
+	fee       float64
+}{
+	{blockbookHistoryTx{
+		Vin:  []blockbookHistoryIO{{Value: "150000"}},
+		Vout: []blockbookHistoryIO{{Value: "100000", Addresses: []string{"ours"}, IsOwn: true}, {Value: "40000"}},
+		Fees: "10000",
+	}, directionIn, 0.001, 0},
+	{blockbookHistoryTx{
+		Vin:  []blockbookHistoryIO{{Value: "150000", IsOwn: true}},
+		Vout: []blockbookHistoryIO{{Value: "100000", Addresses: []string{"theirs"}}, {Value: "40000", IsOwn: true}},
+		Fees: "10000",
+	}, directionOut, -0.0011, 0.0001},
+	{blockbookHistoryTx{
+		Vin:  []blockbookHistoryIO{{Value: "150000", IsOwn: true}},
+		Vout: []blockbookHistoryIO{{Value: "140000", IsOwn: true}},
+		Fees: "10000",
+	}, directionSelf, -0.0001, 0.0001},
+}
+
+func TestUtxoHistoryTx(t *testing.T) {
+	for i, test := range utxoHistoryTests {
+		tx, err := utxoHistoryTx(test.tx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tx.Direction != test.direction || tx.Amount != test.amount || tx.Fee != test.fee {
+			t.Error("unexpected history transaction for test " + strconv.Itoa(i))
+		}
+	}
+	tx, _ := utxoHistoryTx(utxoHistoryTests[1].tx)
+	if len(tx.Addresses) != 1 || tx.Addresses[0] != "theirs" {
+		t.Error("the addresses of a send must be its destinations")
+	}
+}
+
+func TestTokenHistoryTx(t *testing.T) {
+	contract := "0xdAC17F958D2ee523a2206206994597C13D831ec7"
+	tx := blockbookHistoryTx{Txid: "0x01", Fees: "21000000000000"}
+	tx.TokenTransfers = append(tx.TokenTransfers, struct {
+		From     string `json:"from"`
+		To       string `json:"to"`
+		Token    string `json:"token"`
+		Decimals int    `json:"decimals"`
+		Value    string `json:"value"`
+	}{From: testHistoryAddress, To: "0x0000000000000000000000000000000000000001", Token: contract, Decimals: 6, Value: "2500000"})
+	historyTx, ok, err := tokenHistoryTx(tx, testHistoryAddress, contract)
+	if err != nil || !ok {
+		t.Fatal("the transfer must be found")
+	}
+	if historyTx.Direction != directionOut || historyTx.Amount != -2.5 || historyTx.Fee != 0.000021 {
+		t.Error("unexpected token history transaction")
+	}
+	if _, ok, _ := tokenHistoryTx(tx, testHistoryAddress, "0x0000000000000000000000000000000000000002"); ok {
+		t.Error("the transfers of other contracts must be ignored")
+	}
+}
+
+func TestNewHistory(t *testing.T) {
+	now := time.Now().UTC()
+	txs := []models.HistoryTx{
+		{Txid: "a", Time: now.Add(-3 * time.Hour), Confirmations: 10, Direction: directionIn},
+		{Txid: "b", Time: now.Add(-2 * time.Hour), Confirmations: 5, Direction: directionOut},
+		{Txid: "c", Time: now.Add(-48 * time.Hour), Confirmations: 100, Direction: directionIn},
+		{Txid: "d", Time: now.Add(-time.Minute), Direction: directionIn},
+	}
+	entries := []models.SendJournalEntry{
+		{Coin: "BTC", Txid: "B", Amount: 1, CreatedAt: now.Add(-2 * time.Hour)},
+		{Coin: "BTC", Txid: "e", Amount: 2, Address: "theirs", CreatedAt: now.Add(-time.Hour)},
+	}
+	page := historyPage{txs: append([]models.HistoryTx{}, txs...), total: 4, totalPages: 1}
+	q := historyQuery{page: 1, limit: 10}
+	history := newHistory("BTC", page, entries, q)
+	if history.Total != 5 || len(history.Transactions) != 5 {
+		t.Fatal("the history must hold the transactions of the page and the unseen send")
+	}
+	order := ""
+	for _, tx := range history.Transactions {
+		order += tx.Txid
+	}
+	if order != "debac" {
+		t.Error("unexpected order " + order)
+	}
+	if history.Transactions[2].Send == nil || history.Transactions[2].Send.Amount != 1 {
+		t.Error("the send must be annotated with its journal entry")
+	}
+	unseen := history.Transactions[1]
+	if !unseen.Unseen || unseen.Amount != -2 || unseen.Addresses[0] != "theirs" {
+		t.Error("the send unknown to the backend must be listed")
+	}
+	q = historyQuery{page: 2, limit: 3}
+	history = newHistory("BTC", historyPage{txs: txs[2:3], total: 4, totalPages: 2}, entries, q)
+	if history.Total != 4 || history.TotalPages != 2 || len(history.Transactions) != 1 || history.Transactions[0].Txid != "c" {
+		t.Error("the unseen sends must only be listed on the first page")
+	}
+	q = historyQuery{page: 1, limit: 1}
+	history = newHistory("BTC", historyPage{txs: txs[:1], total: 4, totalPages: 4}, entries[1:], q)
+	if len(history.Transactions) != 2 {
+		t.Error("a send newer than the page must be listed")
+	}
+	history = newHistory("BTC", historyPage{txs: txs[3:], total: 4, totalPages: 4}, entries[1:], q)
+	for _, tx := range history.Transactions {
+		if tx.Unseen {
+			t.Error("a send older than the page must not be listed")
+		}
+	}
+}
+
+func TestNewPeriodHistory(t *testing.T) {
+	now := time.Now().UTC()
+	txs := []models.HistoryTx{
+		{Txid: "d", Direction: directionIn},
+		{Txid: "b", Time: now.Add(-2 * time.Hour), Confirmations: 5, Direction: directionOut},
+		{Txid: "a", Time: now.Add(-3 * time.Hour), Confirmations: 10, Direction: directionIn},
+		{Txid: "c", Time: now.Add(-48 * time.Hour), Confirmations: 100, Direction: directionIn},
+	}
+	entries := []models.SendJournalEntry{
+		{Coin: "BTC", Txid: "e", Amount: 2, Address: "theirs", CreatedAt: now.Add(-time.Hour)},
+		{Coin: "BTC", Txid: "f", Amount: 1, CreatedAt: now.Add(-72 * time.Hour)},
+	}
+	q := historyQuery{page: 1, limit: 2, from: now.Add(-24 * time.Hour)}
+	history := newPeriodHistory("BTC", txs, entries, q)
+	if history.Total != 4 || history.TotalPages != 2 || len(history.Transactions) != 2 || history.Transactions[0].Txid != "e" || history.Transactions[1].Txid != "d" {
+		t.Error("the total and the pages must count the transactions of the period")
+	}
+	q.page = 2
+	history = newPeriodHistory("BTC", txs, entries, q)
+	if len(history.Transactions) != 2 || history.Transactions[0].Txid != "b" || history.Transactions[1].Txid != "a" {
+		t.Error("unexpected second page of the period")
+	}
+	q = historyQuery{page: 1, limit: 10, from: now.Add(-96 * time.Hour), to: now.Add(-24 * time.Hour)}
+	history = newPeriodHistory("BTC", txs, entries, q)
+	if history.Total != 2 || history.Transactions[0].Txid != "f" || history.Transactions[1].Txid != "c" {
+		t.Error("a period in the past must not list the unconfirmed transactions")
+	}
+}
+
+func TestPeriodHistory(t *testing.T) {
+	now := time.Now().UTC()
+	var reads int
+	read := func(page int, pageSize int) (historyPage, error) {
+		reads++
+		// a transaction per day, a page per day of the backend
+		tx := models.HistoryTx{Txid: strconv.Itoa(page), Time: now.Add(-time.Duration(page) * 24 * time.Hour), Confirmations: page}
+		return historyPage{txs: []models.HistoryTx{tx}, total: 100, totalPages: 100}, nil
+	}
+	txs, err := periodHistory(read, historyQuery{page: 1, limit: 10, from: now.Add(-72*time.Hour - time.Minute)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(txs) != 4 || reads != 4 {
+		t.Error("the backend must be read until the start of the period")
+	}
+	if _, err := periodHistory(read, historyQuery{page: 1, limit: 10, to: now}); err == nil {
+		t.Error("a period longer than the pages read must be refused")
+	}
+}
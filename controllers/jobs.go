@@ -0,0 +1,47 @@
+package controllers
+
+import (
+	"github.com/grupokindynos/plutus/scheduler"
+)
+
+// JobsController exposes the background jobs registered on the scheduler.
+type JobsController struct {
+	Scheduler *scheduler.Scheduler
+}
+
+func (c *JobsController) GetJobs(params ParamsV2) (interface{}, error) {
+	return c.Scheduler.Jobs(), nil
+}
+
+func (c *JobsController) GetJob(params ParamsV2) (interface{}, error) {
+	return c.Scheduler.Job(params.Job)
+}
+
+func (c *JobsController) GetLastRun(params ParamsV2) (interface{}, error) {
+	return c.Scheduler.LastRun(params.Job)
+}
+
+func (c *JobsController) PauseJob(params ParamsV2) (interface{}, error) {
+	err := c.Scheduler.Pause(params.Job)
+	if err != nil {
+		return nil, err
+	}
+	return c.Scheduler.Job(params.Job)
+}
+
+func (c *JobsController) ResumeJob(params ParamsV2) (interface{}, error) {
+	err := c.Scheduler.Resume(params.Job)
+	if err != nil {
+		return nil, err
+	}
+	return c.Scheduler.Job(params.Job)
+}
+
+// RunJob triggers an immediate run, the outcome is available through GetLastRun once finished.
+func (c *JobsController) RunJob(params ParamsV2) (interface{}, error) {
+	err := c.Scheduler.Trigger(params.Job)
+	if err != nil {
+		return nil, err
+	}
+	return c.Scheduler.Job(params.Job)
+}
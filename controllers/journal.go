@@ -0,0 +1,67 @@
+package controllers
+
+import (
+	"time"
+
+	"github.com/grupokindynos/plutus/logger"
+	"github.com/grupokindynos/plutus/models"
+	"github.com/grupokindynos/plutus/store"
+)
+
+// journalBucket keeps the transactions sent by Plutus, keyed by coin and txid, to annotate
+// the history of the wallets.
+const journalBucket = "send_journal"
+
+// sendOrigin tells who requested a send.
+type sendOrigin struct {
+	service   string
+	requestID string
+}
+
+func journalKey(tag string, txid string) string {
+	return tag + ":" + txid
+}
+
+// journalSend records a sent transaction and returns the recorded entry. The transaction is
+// already broadcast, a failure is only logged.
+func journalSend(entry models.SendJournalEntry, origin sendOrigin, log *logger.Logger) models.SendJournalEntry {
+	entry.Service = origin.service
+	entry.RequestID = origin.requestID
+	entry.CreatedAt = time.Now().UTC()
+	err := stateStore.Put(journalBucket, journalKey(entry.Coin, entry.Txid), entry)
+	if err != nil {
+		log.Error("journalSend: unable to record the transaction", "coin", entry.Coin, "txid", entry.Txid, "err", err)
+	}
+	return entry
+}
+
+// journalEntry returns the journal entry of a transaction, nil when it was not sent by Plutus.
+func journalEntry(tag string, txid string) (*models.SendJournalEntry, error) {
+	var entry models.SendJournalEntry
+	err := stateStore.Get(journalBucket, journalKey(tag, txid), &entry)
+	if err == store.ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// journalEntries returns the journal entries of a coin.
+func journalEntries(tag string) ([]models.SendJournalEntry, error) {
+	var entries []models.SendJournalEntry
+	prefix := journalKey(tag, "")
+	for _, key := range stateStore.Keys(journalBucket) {
+		if len(key) <= len(prefix) || key[:len(prefix)] != prefix {
+			continue
+		}
+		var entry models.SendJournalEntry
+		err := stateStore.Get(journalBucket, key, &entry)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
@@ -0,0 +1,234 @@
+package controllers
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"sync"
+
+	"github.com/eabz/btcutil"
+	"github.com/eabz/btcutil/hdkeychain"
+	"github.com/ethereum/go-ethereum/accounts"
+	coinfactory "github.com/grupokindynos/common/coin-factory"
+	"github.com/grupokindynos/common/coin-factory/coins"
+	hdwallet "github.com/miguelmota/go-ethereum-hdwallet"
+	"github.com/tyler-smith/go-bip39"
+)
+
+// ethDerivationPath is the standard path used by ethereum wallets like Metamask.
+const ethDerivationPath = "m/44'/60'/0'/0/0"
+
+var errKeyCacheClosed = errors.New("the key cache was wiped, the service is shutting down")
+
+type accountKeys struct {
+	priv *hdkeychain.ExtendedKey
+	pub  *hdkeychain.ExtendedKey
+	// fingerprint identifies the master key in the key origins of the PSBTs.
+	fingerprint uint32
+}
+
+type ethKeys struct {
+	wallet  *hdwallet.Wallet
+	account accounts.Account
+}
+
+// keyCache keeps the BIP44 account keys and the ethereum wallets derived from the mnemonics,
+// so the BIP39 seed (2048 PBKDF2 rounds) and the BIP32 path are derived once per mnemonic
+// instead of on every request. Entries are keyed by a hash of the mnemonic so a coin whose
+// mnemonic is swapped per service, like ETHV2, never reuses the keys of another mnemonic.
+type keyCache struct {
+	mu       sync.RWMutex
+	closed   bool
+	accounts map[string]accountKeys
+	eth      map[string]ethKeys
+}
+
+var keys = newKeyCache()
+
+func newKeyCache() *keyCache {
+	return &keyCache{
+		accounts: make(map[string]accountKeys),
+		eth:      make(map[string]ethKeys),
+	}
+}
+
+func mnemonicHash(mnemonic string, password string) string {
+	sum := sha256.Sum256([]byte(mnemonic + "\x00" + password))
+	return hex.EncodeToString(sum[:])
+}
+
+// account returns the BIP44 account key of the coin, deriving it on the first use.
+func (k *keyCache) account(coinConfig *coins.Coin, priv bool) (*hdkeychain.ExtendedKey, error) {
+	cached, err := k.accountKeys(coinConfig)
+	if err != nil {
+		return nil, err
+	}
+	if priv {
+		return cached.priv, nil
+	}
+	return cached.pub, nil
+}
+
+// masterFingerprint returns the BIP32 fingerprint of the master key of the coin.
+func (k *keyCache) masterFingerprint(coinConfig *coins.Coin) (uint32, error) {
+	cached, err := k.accountKeys(coinConfig)
+	if err != nil {
+		return 0, err
+	}
+	return cached.fingerprint, nil
+}
+
+func (k *keyCache) accountKeys(coinConfig *coins.Coin) (accountKeys, error) {
+	password := mnemonicPassword()
+	cacheKey := coinConfig.Info.Tag + ":" + mnemonicHash(coinConfig.Mnemonic, password)
+	k.mu.RLock()
+	cached, ok := k.accounts[cacheKey]
+	closed := k.closed
+	k.mu.RUnlock()
+	if closed {
+		return accountKeys{}, errKeyCacheClosed
+	}
+	if !ok {
+		var err error
+		cached, err = deriveAccount(coinConfig, password)
+		if err != nil {
+			return accountKeys{}, err
+		}
+		k.mu.Lock()
+		if k.closed {
+			k.mu.Unlock()
+			cached.priv.Zero()
+			return accountKeys{}, errKeyCacheClosed
+		}
+		if existing, ok := k.accounts[cacheKey]; ok {
+			// Another request derived it first, keep a single copy of the key.
+			cached.priv.Zero()
+			cached = existing
+		} else {
+			k.accounts[cacheKey] = cached
+		}
+		k.mu.Unlock()
+	}
+	return cached, nil
+}
+
+// ethAccount returns the wallet and the account of an ethereum mnemonic, deriving them on
+// the first use.
+func (k *keyCache) ethAccount(mnemonic string) (*hdwallet.Wallet, accounts.Account, error) {
+	cacheKey := mnemonicHash(mnemonic, "")
+	k.mu.RLock()
+	cached, ok := k.eth[cacheKey]
+	closed := k.closed
+	k.mu.RUnlock()
+	if closed {
+		return nil, accounts.Account{}, errKeyCacheClosed
+	}
+	if ok {
+		return cached.wallet, cached.account, nil
+	}
+	wallet, err := hdwallet.NewFromMnemonic(mnemonic)
+	if err != nil {
+		return nil, accounts.Account{}, err
+	}
+	path := hdwallet.MustParseDerivationPath(ethDerivationPath)
+	account, err := wallet.Derive(path, true)
+	if err != nil {
+		return nil, accounts.Account{}, err
+	}
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if k.closed {
+		return nil, accounts.Account{}, errKeyCacheClosed
+	}
+	if existing, ok := k.eth[cacheKey]; ok {
+		return existing.wallet, existing.account, nil
+	}
+	k.eth[cacheKey] = ethKeys{wallet: wallet, account: account}
+	return wallet, account, nil
+}
+
+// wipe zeroes the cached extended keys and drops every entry. The hdwallet library has no
+// way to wipe its keys, the ethereum wallets are dropped for the garbage collector.
+func (k *keyCache) wipe() {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	for cacheKey, cached := range k.accounts {
+		cached.priv.Zero()
+		cached.pub.Zero()
+		delete(k.accounts, cacheKey)
+	}
+	for cacheKey := range k.eth {
+		delete(k.eth, cacheKey)
+	}
+	k.closed = true
+}
+
+func deriveAccount(coinConfig *coins.Coin, password string) (accountKeys, error) {
+	seed := bip39.NewSeed(coinConfig.Mnemonic, password)
+	defer zeroBytes(seed)
+	mKey, err := hdkeychain.NewMaster(seed, coinConfig.NetParams)
+	if err != nil {
+		return accountKeys{}, err
+	}
+	defer mKey.Zero()
+	masterPub, err := mKey.ECPubKey()
+	if err != nil {
+		return accountKeys{}, err
+	}
+	fingerprint := binary.LittleEndian.Uint32(btcutil.Hash160(masterPub.SerializeCompressed())[:4])
+	purposeChild, err := mKey.Child(hdkeychain.HardenedKeyStart + 44)
+	if err != nil {
+		return accountKeys{}, err
+	}
+	defer purposeChild.Zero()
+	coinType, err := purposeChild.Child(hdkeychain.HardenedKeyStart + coinConfig.NetParams.HDCoinType)
+	if err != nil {
+		return accountKeys{}, err
+	}
+	defer coinType.Zero()
+	accChild, err := coinType.Child(hdkeychain.HardenedKeyStart + 0)
+	if err != nil {
+		return accountKeys{}, err
+	}
+	accPub, err := accChild.Neuter()
+	if err != nil {
+		accChild.Zero()
+		return accountKeys{}, err
+	}
+	return accountKeys{priv: accChild, pub: accPub, fingerprint: fingerprint}, nil
+}
+
+func zeroBytes(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
+
+// warmKeyCache derives the keys of every configured coin so the first requests don't pay
+// for the seed derivation.
+func warmKeyCache() {
+	if publicKeysOnly() {
+		return
+	}
+	for tag := range coinfactory.Coins {
+		coin, err := getCoin(tag)
+		if err != nil || coin.Mnemonic == "" {
+			continue
+		}
+		if coin.Info.Token || coin.Info.Tag == "ETH" {
+			_, _ = getEthAccFromMnemonic(coin)
+			continue
+		}
+		_, _ = getAccFromMnemonic(coin, false)
+	}
+	if v2Mnemonic := mnemonic(coinV2); v2Mnemonic != "" {
+		_, _, _ = keys.ethAccount(v2Mnemonic)
+	}
+}
+
+// WipeKeys zeroes the cached key material. It must be called on shutdown, once the server
+// stopped serving requests, any later signing request fails.
+func WipeKeys() {
+	keys.wipe()
+}
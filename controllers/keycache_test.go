@@ -0,0 +1,86 @@
+package controllers
+
+import (
+	"testing"
+
+	coinfactory "github.com/grupokindynos/common/coin-factory"
+	hdwallet "github.com/miguelmota/go-ethereum-hdwallet"
+)
+
+func TestKeyCacheWipe(t *testing.T) {
+	coin := coinfactory.Coins["BTC"]
+	coin.Mnemonic = testMnemonic
+	cache := newKeyCache()
+	acc, err := cache.account(coin, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cached, err := cache.account(coin, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cached != acc {
+		t.Error("the account key was derived twice")
+	}
+	cache.wipe()
+	if acc.String() != "zeroed extended key" {
+		t.Error("the cached key was not zeroed")
+	}
+	_, err = cache.account(coin, true)
+	if err != errKeyCacheClosed {
+		t.Error("the cache must refuse to derive keys once wiped")
+	}
+}
+
+func BenchmarkGetAccFromMnemonicCached(b *testing.B) {
+	coin := coinfactory.Coins["BTC"]
+	coin.Mnemonic = testMnemonic
+	_, _ = getAccFromMnemonic(coin, true)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, err := getAccFromMnemonic(coin, true)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkDeriveAccount measures the derivation every request used to run.
+func BenchmarkDeriveAccount(b *testing.B) {
+	coin := coinfactory.Coins["BTC"]
+	coin.Mnemonic = testMnemonic
+	for i := 0; i < b.N; i++ {
+		keys, err := deriveAccount(coin, mnemonicPassword())
+		if err != nil {
+			b.Fatal(err)
+		}
+		keys.priv.Zero()
+	}
+}
+
+func BenchmarkGetEthAccFromMnemonicCached(b *testing.B) {
+	coin := coinfactory.Coins["ETH"]
+	coin.Mnemonic = testMnemonic
+	_, _ = getEthAccFromMnemonic(coin)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, err := getEthAccFromMnemonic(coin)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkNewEthWallet measures the wallet creation every ethereum request used to run.
+func BenchmarkNewEthWallet(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		wallet, err := hdwallet.NewFromMnemonic(testMnemonic)
+		if err != nil {
+			b.Fatal(err)
+		}
+		_, err = wallet.Derive(hdwallet.MustParseDerivationPath(ethDerivationPath), true)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,755 @@
+package controllers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eabz/btcutil"
+	"github.com/eabz/btcutil/chaincfg"
+	"github.com/eabz/btcutil/hdkeychain"
+	"github.com/eabz/btcutil/txscript"
+	"github.com/grupokindynos/common/blockbook"
+	"github.com/grupokindynos/common/coin-factory/coins"
+	"github.com/grupokindynos/common/plutus"
+	"github.com/grupokindynos/plutus/logger"
+	"github.com/grupokindynos/plutus/metrics"
+	"github.com/grupokindynos/plutus/models"
+	"github.com/grupokindynos/plutus/multisig"
+	"github.com/grupokindynos/plutus/psbt"
+	"github.com/grupokindynos/plutus/store"
+	"github.com/martinboehm/btcd/btcec"
+	"github.com/martinboehm/btcd/wire"
+)
+
+// The coins with a multisig wallet receive on m-of-n addresses made of the key of Plutus, at
+// index of the receive chain of its BIP44 account, and the keys of the cosigners at the same
+// index of their receive chains. The keys are sorted in the scripts (BIP67). Spends are
+// created as PSBT, signed by Plutus and tracked until the cosigners add the missing signatures.
+
+const (
+	multisigIndexBucket = "multisig_index"
+	multisigTxBucket    = "multisig_tx"
+)
+
+var (
+	multisigConfig multisig.Config
+	// multisigMu serializes the updates of the tracked spends.
+	multisigMu sync.Mutex
+)
+
+var errNoMultisig = errors.New("the coin has no multisig wallet")
+
+// SetMultisig enables the multisig wallets of config. It must be called before the controllers
+// are created.
+func SetMultisig(config multisig.Config) error {
+	if err := config.Validate(); err != nil {
+		return err
+	}
+	multisigConfig = config
+	return nil
+}
+
+type multisigIndex struct {
+	Next uint32 `json:"next"`
+}
+
+// multisigKey is a key of a multisig address with its origin.
+type multisigKey struct {
+	pubKey      []byte
+	fingerprint uint32
+	path        []uint32
+}
+
+// CreateMultisigTxV2 builds a spend of the multisig wallet, adds the signature of Plutus
+// and tracks it until the cosigners sign it.
+func (c *ControllerV2) CreateMultisigTxV2(params ParamsV2) (interface{}, error) {
+	var SendToAddressData plutus.SendAddressBodyReq
+	log := logger.Default().WithRequestID(params.RequestID).With("service", params.Service)
+	err := json.Unmarshal(params.Body, &SendToAddressData)
+	if err != nil {
+		return nil, err
+	}
+	coinConfig, wallet, err := c.multisigCoin(SendToAddressData.Coin)
+	if err != nil {
+		return nil, err
+	}
+	inputs, err := multisigUtxos(coinConfig, wallet, log)
+	if err != nil {
+		return nil, err
+	}
+	p, err := newPayment(coinConfig, inputs, SendToAddressData.Address, SendToAddressData.Amount, multisigFee(wallet), log)
+	if err != nil {
+		return nil, err
+	}
+	packet, err := newMultisigPSBT(coinConfig, wallet, p)
+	if err != nil {
+		log.Error("CreateMultisigTxV2: unable to create the psbt", "coin", coinConfig.Info.Tag, "err", err)
+		return nil, err
+	}
+	if !watchOnly() {
+		if _, err := signMultisig(coinConfig, packet); err != nil {
+			log.Error("CreateMultisigTxV2: unable to sign the psbt", "coin", coinConfig.Info.Tag, "err", err)
+			return nil, err
+		}
+	}
+	now := time.Now().UTC()
+	record := &models.MultisigTx{
+		ID:        p.tx.TxHash().String(),
+		Coin:      coinConfig.Info.Tag,
+		Address:   SendToAddressData.Address,
+		Amount:    SendToAddressData.Amount,
+		Fee:       p.fee.ToBTC(),
+		Required:  wallet.Required,
+		CreatedAt: now,
+	}
+	multisigMu.Lock()
+	defer multisigMu.Unlock()
+	if err := updateMultisigTx(coinConfig, record, packet, false, sendOrigin{}, log); err != nil {
+		return nil, err
+	}
+	log.Info("CreateMultisigTxV2: multisig spend created", "coin", coinConfig.Info.Tag, "id", record.ID)
+	return record, nil
+}
+
+// SignMultisigTxV2 adds the signatures of a cosigner to a tracked spend. Once every input
+// has the required signatures the transaction is finalized and, if requested, broadcast.
+func (c *ControllerV2) SignMultisigTxV2(params ParamsV2) (interface{}, error) {
+	var MultisigData models.MultisigBodyReq
+	log := logger.Default().WithRequestID(params.RequestID).With("service", params.Service)
+	err := json.Unmarshal(params.Body, &MultisigData)
+	if err != nil {
+		return nil, err
+	}
+	coinConfig, _, err := c.multisigCoin(MultisigData.Coin)
+	if err != nil {
+		return nil, err
+	}
+	incoming, err := psbt.Decode(MultisigData.PSBT)
+	if err != nil {
+		return nil, err
+	}
+	multisigMu.Lock()
+	defer multisigMu.Unlock()
+	record, packet, err := getMultisigTx(incoming.UnsignedTx.TxHash().String())
+	if err != nil {
+		return nil, err
+	}
+	if record.Coin != coinConfig.Info.Tag {
+		return nil, errors.New("the transaction belongs to the " + record.Coin + " wallet")
+	}
+	if !record.Complete {
+		added, err := mergeSignatures(coinConfig, packet, incoming)
+		if err != nil {
+			log.Warn("SignMultisigTxV2: rejected signatures", "coin", coinConfig.Info.Tag, "id", record.ID, "err", err)
+			return nil, err
+		}
+		log.Info("SignMultisigTxV2: signatures added", "coin", coinConfig.Info.Tag, "id", record.ID, "signatures", added)
+	}
+	if err := updateMultisigTx(coinConfig, record, packet, MultisigData.Broadcast, sendOrigin{params.Service, params.RequestID}, log); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// GetMultisigTxV2 returns a tracked spend by the hash of its unsigned transaction.
+func (c *ControllerV2) GetMultisigTxV2(params ParamsV2) (interface{}, error) {
+	record, _, err := getMultisigTx(params.Txid)
+	if err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+func (c *ControllerV2) multisigCoin(tag string) (*coins.Coin, multisig.Wallet, error) {
+	coinConfig, err := c.psbtCoin(tag)
+	if err != nil {
+		return nil, multisig.Wallet{}, err
+	}
+	wallet, ok := multisigWallet(coinConfig)
+	if !ok {
+		return nil, multisig.Wallet{}, errNoMultisig
+	}
+	return coinConfig, wallet, nil
+}
+
+// multisigWallet returns the multisig wallet of the coin, if configured.
+func multisigWallet(coinConfig *coins.Coin) (multisig.Wallet, bool) {
+	if coinConfig.Info.Token || coinConfig.Info.Tag == "ETH" {
+		return multisig.Wallet{}, false
+	}
+	return multisigConfig.Wallet(coinConfig.Info.Tag)
+}
+
+func getMultisigTx(id string) (*models.MultisigTx, *psbt.Packet, error) {
+	var record models.MultisigTx
+	err := stateStore.Get(multisigTxBucket, id, &record)
+	if err == store.ErrNotFound {
+		return nil, nil, errors.New("unknown multisig transaction " + id)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	packet, err := psbt.Decode(record.PSBT)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &record, packet, nil
+}
+
+// updateMultisigTx counts the signatures of packet, finalizes it once the threshold is met
+// on every input, broadcasts it if requested and stores the record.
+func updateMultisigTx(coinConfig *coins.Coin, record *models.MultisigTx, packet *psbt.Packet, broadcast bool, origin sendOrigin, log *logger.Logger) error {
+	if !record.Complete {
+		if err := finalizeMultisig(packet, record.Required); err != nil {
+			return err
+		}
+	}
+	record.Signatures = make([]int, len(packet.Inputs))
+	for i, in := range packet.Inputs {
+		record.Signatures[i] = len(in.PartialSigs)
+		if packet.Finalized(i) {
+			record.Signatures[i] = record.Required
+		}
+	}
+	record.Complete = packet.Complete()
+	encoded, err := packet.Encode()
+	if err != nil {
+		return err
+	}
+	record.PSBT = encoded
+	var tx *wire.MsgTx
+	if record.Complete {
+		tx, err = packet.Extract()
+		if err != nil {
+			return err
+		}
+		var buf bytes.Buffer
+		if err := tx.BtcEncode(&buf, 0, wire.WitnessEncoding); err != nil {
+			return err
+		}
+		record.RawTx = hex.EncodeToString(buf.Bytes())
+	}
+	record.UpdatedAt = time.Now().UTC()
+	if err := stateStore.Put(multisigTxBucket, record.ID, record); err != nil {
+		return err
+	}
+	if !broadcast || !record.Complete || record.Txid != "" {
+		return nil
+	}
+	txid, err := broadcastTx(coinConfig, tx, log)
+	metrics.ObserveSend(coinConfig.Info.Tag, err)
+	if err != nil {
+		log.Error("updateMultisigTx: broadcast failed", "coin", coinConfig.Info.Tag, "id", record.ID, "err", err)
+		return err
+	}
+	metrics.FeePaid.WithLabelValues(coinConfig.Info.Tag).Add(record.Fee)
+	entry := psbtJournalEntry(coinConfig, packet, txid, record.Fee)
+	entry.Address, entry.Amount = record.Address, record.Amount
+	journalSend(entry, origin, log)
+	record.Txid = txid
+	return stateStore.Put(multisigTxBucket, record.ID, record)
+}
+
+// nextMultisigAddress issues the next receive address of the multisig wallet and returns it
+// with its index.
+func nextMultisigAddress(coinConfig *coins.Coin, wallet multisig.Wallet) (string, int, error) {
+	var index multisigIndex
+	var address string
+	var issued uint32
+	err := stateStore.Update(multisigIndexBucket, coinConfig.Info.Tag, &index, func(found bool) error {
+		addr, _, err := multisigAddress(coinConfig, wallet, index.Next)
+		if err != nil {
+			return err
+		}
+		address = addr.EncodeAddress()
+		issued = index.Next
+		index.Next++
+		return nil
+	})
+	return address, int(issued), err
+}
+
+// multisigAddrInfo returns the addresses issued by the multisig wallet of the coin, if any.
+func multisigAddrInfo(coinConfig *coins.Coin) ([]models.AddrInfo, error) {
+	wallet, ok := multisigWallet(coinConfig)
+	if !ok {
+		return nil, nil
+	}
+	var index multisigIndex
+	err := stateStore.Get(multisigIndexBucket, coinConfig.Info.Tag, &index)
+	if err != nil && err != store.ErrNotFound {
+		return nil, err
+	}
+	var addrs []models.AddrInfo
+	for i := uint32(0); i < index.Next; i++ {
+		addr, _, err := multisigAddress(coinConfig, wallet, i)
+		if err != nil {
+			return nil, err
+		}
+		addrs = append(addrs, models.AddrInfo{Addr: addr.EncodeAddress(), Path: int(i), Chain: externalChain, Multisig: true})
+	}
+	return addrs, nil
+}
+
+// multisigDescriptor returns the output descriptor of the receive addresses of the wallet,
+// the backend derives them to answer for every address in a single request.
+func multisigDescriptor(coinConfig *coins.Coin, wallet multisig.Wallet) (string, error) {
+	accPub, err := getAccPub(coinConfig)
+	if err != nil {
+		return "", err
+	}
+	fingerprint, err := getMasterFingerprint(coinConfig)
+	if err != nil {
+		return "", err
+	}
+	keys := []string{descriptorKey(fingerprint, accountPath(coinConfig, 0)[:3], accPub.String())}
+	for _, cosigner := range wallet.Cosigners {
+		fingerprint, err := multisig.ParseFingerprint(cosigner.Fingerprint)
+		if err != nil {
+			return "", err
+		}
+		origin, err := multisig.ParsePath(cosigner.Path)
+		if err != nil {
+			return "", err
+		}
+		keys = append(keys, descriptorKey(fingerprint, origin, cosigner.Xpub))
+	}
+	descriptor := "sortedmulti(" + strconv.Itoa(wallet.Required) + "," + strings.Join(keys, ",") + ")"
+	if wallet.Type == multisig.P2WSH {
+		return "wsh(" + descriptor + ")", nil
+	}
+	return "sh(" + descriptor + ")", nil
+}
+
+// descriptorKey returns the receive chain of an account xpub with its origin, as written in
+// the output descriptors.
+func descriptorKey(fingerprint uint32, path []uint32, xpub string) string {
+	var fp [4]byte
+	binary.LittleEndian.PutUint32(fp[:], fingerprint)
+	key := "[" + hex.EncodeToString(fp[:])
+	for _, index := range path {
+		if index >= hdkeychain.HardenedKeyStart {
+			key += "/" + strconv.FormatUint(uint64(index-hdkeychain.HardenedKeyStart), 10) + "'"
+		} else {
+			key += "/" + strconv.FormatUint(uint64(index), 10)
+		}
+	}
+	return key + "]" + xpub + "/0/*"
+}
+
+// multisigBalance returns the confirmed and unconfirmed satoshis of the multisig wallet of
+// the coin, zero when it has none.
+func multisigBalance(coinConfig *coins.Coin) (float64, float64, error) {
+	wallet, ok := multisigWallet(coinConfig)
+	if !ok {
+		return 0, 0, nil
+	}
+	descriptor, err := multisigDescriptor(coinConfig, wallet)
+	if err != nil {
+		return 0, 0, err
+	}
+	blockBookWrap := blockbook.NewBlockBookWrapper(coinConfig.Info.Blockbook)
+	start := time.Now()
+	info, err := blockBookWrap.GetXpub(url.PathEscape(descriptor))
+	metrics.ObserveBlockbook(coinConfig.Info.Tag, "xpub", start, err)
+	if err != nil {
+		return 0, 0, err
+	}
+	confirmed, err := strconv.ParseFloat(info.Balance, 64)
+	if err != nil {
+		return 0, 0, err
+	}
+	unconfirmed, err := strconv.ParseFloat(info.UnconfirmedBalance, 64)
+	if err != nil {
+		return 0, 0, err
+	}
+	return confirmed, unconfirmed, nil
+}
+
+// multisigUtxos returns the outputs of every address issued by the multisig wallet.
+func multisigUtxos(coinConfig *coins.Coin, wallet multisig.Wallet, log *logger.Logger) ([]spentOutput, error) {
+	issued, err := multisigAddrInfo(coinConfig)
+	if err != nil {
+		return nil, err
+	}
+	descriptor, err := multisigDescriptor(coinConfig, wallet)
+	if err != nil {
+		return nil, err
+	}
+	chaincfg.ResetParams()
+	_ = chaincfg.Register(coinConfig.NetParams)
+	addrs := make(map[string]models.AddrInfo)
+	for _, addr := range issued {
+		addrs[addr.Addr] = addr
+	}
+	blockBookWrap := blockbook.NewBlockBookWrapper(coinConfig.Info.Blockbook)
+	start := time.Now()
+	utxos, err := blockBookWrap.GetUtxo(url.PathEscape(descriptor), false)
+	metrics.ObserveBlockbook(coinConfig.Info.Tag, "utxo", start, err)
+	if err != nil {
+		return nil, err
+	}
+	var inputs []spentOutput
+	for _, utxo := range utxos {
+		addr, ok := addrs[utxo.Address]
+		if !ok {
+			log.Warn("multisigUtxos: skipping an output of an address not issued", "address", utxo.Address, "txid", utxo.Txid)
+			continue
+		}
+		intValue, err := strconv.ParseInt(utxo.Value, 10, 64)
+		if err != nil {
+			log.Error("multisigUtxos: invalid utxo value", "value", utxo.Value, "err", err)
+			return nil, err
+		}
+		decoded, err := btcutil.DecodeAddress(addr.Addr, coinConfig.NetParams)
+		if err != nil {
+			return nil, err
+		}
+		script, err := txscript.PayToAddrScript(decoded)
+		if err != nil {
+			return nil, err
+		}
+		inputs = append(inputs, spentOutput{
+			txid:    utxo.Txid,
+			vout:    uint32(utxo.Vout),
+			value:   btcutil.Amount(intValue),
+			address: addr.Addr,
+			index:   uint32(addr.Path),
+			script:  script,
+		})
+	}
+	return inputs, nil
+}
+
+// multisigFee estimates the fee of a spend of the wallet. The payment and the change outputs
+// are added once the fee is known.
+func multisigFee(wallet multisig.Wallet) func(feeRate int64, inputs int, outputs int) btcutil.Amount {
+	scriptSize := 3 + wallet.Keys()*34
+	// Outpoint and sequence, OP_0, the signatures and the script.
+	inputSize := 41 + 1 + wallet.Required*74 + scriptSize + 3
+	if wallet.Type == multisig.P2WSH {
+		inputSize = 41 + (1+1+wallet.Required*74+scriptSize+3)/4
+	}
+	return func(feeRate int64, inputs int, outputs int) btcutil.Amount {
+		txSize := 10 + inputs*inputSize + (outputs+2)*43
+		feeSats := float64(feeRate) / 1024.0 * float64(txSize)
+		return btcutil.Amount(int64(feeSats))
+	}
+}
+
+// multisigKeys returns the keys of the wallet at index, sorted as in the scripts.
+func multisigKeys(coinConfig *coins.Coin, wallet multisig.Wallet, index uint32) ([]multisigKey, error) {
+	accPub, err := getAccPub(coinConfig)
+	if err != nil {
+		return nil, err
+	}
+	fingerprint, err := getMasterFingerprint(coinConfig)
+	if err != nil {
+		return nil, err
+	}
+	pubKey, err := getPubKeyFromPath(accPub, index)
+	if err != nil {
+		return nil, err
+	}
+	keys := []multisigKey{{pubKey: pubKey, fingerprint: fingerprint, path: accountPath(coinConfig, index)}}
+	for _, cosigner := range wallet.Cosigners {
+		acc, err := hdkeychain.NewKeyFromString(cosigner.Xpub)
+		if err != nil {
+			return nil, err
+		}
+		if acc.IsPrivate() {
+			return nil, errors.New("the cosigners must be configured with public keys")
+		}
+		pubKey, err := getPubKeyFromPath(acc, index)
+		if err != nil {
+			return nil, err
+		}
+		fingerprint, err := multisig.ParseFingerprint(cosigner.Fingerprint)
+		if err != nil {
+			return nil, err
+		}
+		origin, err := multisig.ParsePath(cosigner.Path)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, multisigKey{pubKey: pubKey, fingerprint: fingerprint, path: append(origin, 0, index)})
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return bytes.Compare(keys[i].pubKey, keys[j].pubKey) < 0
+	})
+	for i := 1; i < len(keys); i++ {
+		if bytes.Equal(keys[i-1].pubKey, keys[i].pubKey) {
+			return nil, errors.New("the multisig wallet uses the same key twice")
+		}
+	}
+	return keys, nil
+}
+
+// multisigScript returns the m-of-n redeem or witness script of keys.
+func multisigScript(required int, keys []multisigKey) ([]byte, error) {
+	builder := txscript.NewScriptBuilder().AddInt64(int64(required))
+	for _, key := range keys {
+		builder.AddData(key.pubKey)
+	}
+	return builder.AddInt64(int64(len(keys))).AddOp(txscript.OP_CHECKMULTISIG).Script()
+}
+
+// multisigAddress returns the address of the wallet at index and its keys.
+func multisigAddress(coinConfig *coins.Coin, wallet multisig.Wallet, index uint32) (btcutil.Address, []multisigKey, error) {
+	keys, err := multisigKeys(coinConfig, wallet, index)
+	if err != nil {
+		return nil, nil, err
+	}
+	script, err := multisigScript(wallet.Required, keys)
+	if err != nil {
+		return nil, nil, err
+	}
+	if wallet.Type == multisig.P2WSH {
+		if coinConfig.NetParams.Bech32HRPSegwit == "" {
+			return nil, nil, errors.New(coinConfig.Info.Tag + " doesn't support segwit addresses")
+		}
+		hash := sha256.Sum256(script)
+		addr, err := btcutil.NewAddressWitnessScriptHash(hash[:], coinConfig.NetParams)
+		return addr, keys, err
+	}
+	addr, err := btcutil.NewAddressScriptHash(script, coinConfig.NetParams)
+	return addr, keys, err
+}
+
+// newMultisigPSBT returns the unsigned packet of a spend of the multisig wallet.
+func newMultisigPSBT(coinConfig *coins.Coin, wallet multisig.Wallet, p *payment) (*psbt.Packet, error) {
+	packet, err := psbt.New(p.tx)
+	if err != nil {
+		return nil, err
+	}
+	for i, input := range p.inputs {
+		prevTx, err := getPrevTx(coinConfig, input.txid)
+		if err != nil {
+			return nil, err
+		}
+		_, keys, err := multisigAddress(coinConfig, wallet, input.index)
+		if err != nil {
+			return nil, err
+		}
+		if err := setMultisigInput(&packet.Inputs[i], wallet, keys, prevTx, input.vout); err != nil {
+			return nil, err
+		}
+		// Let the signers recognize the change output.
+		for j, out := range p.tx.TxOut {
+			if bytes.Equal(out.PkScript, input.script) && len(packet.Outputs[j].Bip32Derivation) == 0 {
+				packet.Outputs[j].RedeemScript = packet.Inputs[i].RedeemScript
+				packet.Outputs[j].WitnessScript = packet.Inputs[i].WitnessScript
+				packet.Outputs[j].Bip32Derivation = packet.Inputs[i].Bip32Derivation
+			}
+		}
+	}
+	return packet, nil
+}
+
+// setMultisigInput fills the fields the signers need to sign an input spending the output
+// vout of prevTx, paid to the address of keys.
+func setMultisigInput(in *psbt.Input, wallet multisig.Wallet, keys []multisigKey, prevTx *wire.MsgTx, vout uint32) error {
+	if int(vout) >= len(prevTx.TxOut) {
+		return errors.New("the previous transaction has no output " + strconv.Itoa(int(vout)))
+	}
+	script, err := multisigScript(wallet.Required, keys)
+	if err != nil {
+		return err
+	}
+	in.NonWitnessUtxo = prevTx
+	in.SighashType = uint32(txscript.SigHashAll)
+	if wallet.Type == multisig.P2WSH {
+		in.WitnessUtxo = prevTx.TxOut[vout]
+		in.WitnessScript = script
+	} else {
+		in.RedeemScript = script
+	}
+	in.Bip32Derivation = make([]psbt.Bip32Derivation, 0, len(keys))
+	for _, key := range keys {
+		in.Bip32Derivation = append(in.Bip32Derivation, psbt.Bip32Derivation{
+			PubKey:      key.pubKey,
+			Fingerprint: key.fingerprint,
+			Path:        key.path,
+		})
+	}
+	return nil
+}
+
+// signMultisig adds the signature of Plutus to the inputs of a packet built by
+// newMultisigPSBT and returns how many it signed.
+func signMultisig(coinConfig *coins.Coin, packet *psbt.Packet) (int, error) {
+	accPub, err := getAccPub(coinConfig)
+	if err != nil {
+		return 0, err
+	}
+	fingerprint, err := getMasterFingerprint(coinConfig)
+	if err != nil {
+		return 0, err
+	}
+	var signed int
+	for i := range packet.Inputs {
+		in := &packet.Inputs[i]
+		if packet.Finalized(i) {
+			continue
+		}
+		index, pubKey, ok := walletKey(coinConfig, accPub, fingerprint, in.Bip32Derivation)
+		if !ok || hasPartialSig(in, pubKey) {
+			continue
+		}
+		digest, err := multisigSigHash(coinConfig, packet, i)
+		if err != nil {
+			return signed, err
+		}
+		signature, sigPubKey, err := txSigner.SignDigest(coinConfig.Info.Tag, index, digest)
+		if err != nil {
+			return signed, err
+		}
+		if !bytes.Equal(sigPubKey, pubKey) {
+			return signed, errors.New("the signer used another key for input " + strconv.Itoa(i))
+		}
+		if err := packet.AddPartialSig(i, pubKey, append(signature, byte(txscript.SigHashAll))); err != nil {
+			return signed, err
+		}
+		signed++
+	}
+	return signed, nil
+}
+
+// mergeSignatures copies to packet the signatures of incoming made by the keys of the
+// inputs, after checking them. It returns how many signatures were added.
+func mergeSignatures(coinConfig *coins.Coin, packet *psbt.Packet, incoming *psbt.Packet) (int, error) {
+	if len(incoming.Inputs) != len(packet.Inputs) {
+		return 0, errors.New("the psbt doesn't match the multisig transaction")
+	}
+	var added int
+	for i, in := range incoming.Inputs {
+		if packet.Finalized(i) || len(in.PartialSigs) == 0 {
+			continue
+		}
+		digest, err := multisigSigHash(coinConfig, packet, i)
+		if err != nil {
+			return added, err
+		}
+		for _, sig := range in.PartialSigs {
+			if hasPartialSig(&packet.Inputs[i], sig.PubKey) {
+				continue
+			}
+			if !hasDerivation(&packet.Inputs[i], sig.PubKey) {
+				return added, errors.New("input " + strconv.Itoa(i) + " is signed by a key of another wallet")
+			}
+			if err := verifySignature(digest, sig); err != nil {
+				return added, errors.New("input " + strconv.Itoa(i) + ": " + err.Error())
+			}
+			if err := packet.AddPartialSig(i, sig.PubKey, sig.Signature); err != nil {
+				return added, err
+			}
+			added++
+		}
+	}
+	return added, nil
+}
+
+// finalizeMultisig builds the final scripts of the inputs with at least required signatures.
+// The signatures are taken in the order of the keys of the script.
+func finalizeMultisig(packet *psbt.Packet, required int) error {
+	for i := range packet.Inputs {
+		in := packet.Inputs[i]
+		if packet.Finalized(i) || len(in.PartialSigs) < required {
+			continue
+		}
+		sigs := append([]psbt.PartialSig(nil), in.PartialSigs...)
+		sort.Slice(sigs, func(a, b int) bool {
+			return bytes.Compare(sigs[a].PubKey, sigs[b].PubKey) < 0
+		})
+		sigs = sigs[:required]
+		if len(in.WitnessScript) > 0 {
+			// The empty item is consumed by the extra pop of OP_CHECKMULTISIG.
+			witness := [][]byte{{}}
+			for _, sig := range sigs {
+				witness = append(witness, sig.Signature)
+			}
+			if err := packet.SetFinal(i, nil, append(witness, in.WitnessScript)); err != nil {
+				return err
+			}
+			continue
+		}
+		if len(in.RedeemScript) == 0 {
+			return errors.New("input " + strconv.Itoa(i) + " is missing its redeem script")
+		}
+		builder := txscript.NewScriptBuilder().AddOp(txscript.OP_0)
+		for _, sig := range sigs {
+			builder.AddData(sig.Signature)
+		}
+		sigScript, err := builder.AddData(in.RedeemScript).Script()
+		if err != nil {
+			return err
+		}
+		if err := packet.SetFinal(i, sigScript, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// multisigSigHash returns the digest signed by the keys of the input i.
+func multisigSigHash(coinConfig *coins.Coin, packet *psbt.Packet, i int) ([]byte, error) {
+	in := packet.Inputs[i]
+	if len(in.WitnessScript) > 0 {
+		spent, err := packet.SpentOutput(i)
+		if err != nil {
+			return nil, err
+		}
+		return witnessSigHash(packet.UnsignedTx, i, in.WitnessScript, spent.Value, coinConfig.Info.Tag == "GRS")
+	}
+	if len(in.RedeemScript) == 0 {
+		return nil, errors.New("input " + strconv.Itoa(i) + " is missing its redeem script")
+	}
+	return sigHash(packet.UnsignedTx, i, in.RedeemScript, coinConfig.Info.Tag == "GRS")
+}
+
+// verifySignature checks a SIGHASH_ALL signature of digest.
+func verifySignature(digest []byte, sig psbt.PartialSig) error {
+	if len(sig.Signature) < 2 || sig.Signature[len(sig.Signature)-1] != byte(txscript.SigHashAll) {
+		return errors.New("only SIGHASH_ALL signatures are accepted")
+	}
+	signature, err := btcec.ParseDERSignature(sig.Signature[:len(sig.Signature)-1], btcec.S256())
+	if err != nil {
+		return err
+	}
+	pubKey, err := btcec.ParsePubKey(sig.PubKey, btcec.S256())
+	if err != nil {
+		return err
+	}
+	if !signature.Verify(digest, pubKey) {
+		return errors.New("invalid signature")
+	}
+	return nil
+}
+
+func hasPartialSig(in *psbt.Input, pubKey []byte) bool {
+	for _, sig := range in.PartialSigs {
+		if bytes.Equal(sig.PubKey, pubKey) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasDerivation(in *psbt.Input, pubKey []byte) bool {
+	for _, derivation := range in.Bip32Derivation {
+		if bytes.Equal(derivation.PubKey, pubKey) {
+			return true
+		}
+	}
+	return false
+}
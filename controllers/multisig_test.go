@@ -0,0 +1,213 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/eabz/btcutil/hdkeychain"
+	"github.com/eabz/btcutil/txscript"
+	"github.com/grupokindynos/common/coin-factory/coins"
+	"github.com/grupokindynos/common/plutus"
+	"github.com/grupokindynos/plutus/models"
+	"github.com/grupokindynos/plutus/multisig"
+	"github.com/grupokindynos/plutus/psbt"
+	"github.com/grupokindynos/plutus/store"
+	"github.com/martinboehm/btcd/btcec"
+	"github.com/martinboehm/btcd/wire"
+)
+
+func TestMultisigSpend(t *testing.T) {
+	defaultSigner := txSigner
+	defer SetSigner(defaultSigner)
+	defaultStore := stateStore
+	defer SetStore(defaultStore)
+	defer func() { multisigConfig = multisig.Config{} }()
+	for _, test := range testXpup {
+		for _, walletType := range []string{multisig.P2SH, multisig.P2WSH} {
+			if walletType == multisig.P2WSH && test.coin.NetParams.Bech32HRPSegwit == "" {
+				continue
+			}
+			name := test.coin.Info.Tag + " " + walletType
+			acc, _ := useTestKey(t, test)
+			// The cosigner account is a hardened child of the account of Plutus.
+			cosignerAcc, err := acc.Child(hdkeychain.HardenedKeyStart + 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			cosignerPub, err := cosignerAcc.Neuter()
+			if err != nil {
+				t.Fatal(err)
+			}
+			cosignerKey, err := getPrivKeyFromPath(cosignerAcc, test.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			wallet := multisig.Wallet{Type: walletType, Required: 2, Cosigners: []multisig.Cosigner{
+				{Xpub: cosignerPub.String(), Fingerprint: "d34db33f", Path: "m/48'/0'/0'/2'"},
+			}}
+			db, _ := store.Open("")
+			SetStore(db)
+			err = SetMultisig(multisig.Config{Coins: map[string]multisig.Wallet{test.coin.Info.Tag: wallet}})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			testMultisigAddresses(t, test.coin, name)
+
+			addr, keys, err := multisigAddress(test.coin, wallet, test.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(keys) != 2 || bytes.Compare(keys[0].pubKey, keys[1].pubKey) >= 0 {
+				t.Error("the keys must be sorted for " + name)
+			}
+			script, err := txscript.PayToAddrScript(addr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			prevTx, tx := testPrevTx(200000, script)
+			tx.AddTxOut(wire.NewTxOut(150000, script))
+			packet, err := psbt.New(tx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := setMultisigInput(&packet.Inputs[0], wallet, keys, prevTx, 0); err != nil {
+				t.Fatal(err)
+			}
+
+			signed, err := signMultisig(test.coin, packet)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if signed != 1 {
+				t.Error("the key of plutus didn't sign for " + name)
+			}
+			if err := finalizeMultisig(packet, wallet.Required); err != nil {
+				t.Fatal(err)
+			}
+			if packet.Complete() {
+				t.Error("a single signature must not complete a 2 of 2 spend for " + name)
+			}
+
+			digest, err := multisigSigHash(test.coin, packet, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			signature, err := cosignerKey.Sign(digest)
+			if err != nil {
+				t.Fatal(err)
+			}
+			cosignerPubKey := cosignerKey.PubKey().SerializeCompressed()
+			incoming, err := psbt.New(tx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			wrong, _ := cosignerKey.Sign(bytes.Repeat([]byte{1}, 32))
+			_ = incoming.AddPartialSig(0, cosignerPubKey, append(wrong.Serialize(), byte(txscript.SigHashAll)))
+			if _, err := mergeSignatures(test.coin, packet, incoming); err == nil {
+				t.Error("a signature of another digest must be rejected for " + name)
+			}
+			_ = incoming.AddPartialSig(0, cosignerPubKey, append(signature.Serialize(), byte(txscript.SigHashAll)))
+			added, err := mergeSignatures(test.coin, packet, incoming)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if added != 1 {
+				t.Error("the signature of the cosigner was not added for " + name)
+			}
+			// The signatures must follow the order of the keys in the script.
+			var sigs [][]byte
+			for _, key := range keys {
+				for _, sig := range packet.Inputs[0].PartialSigs {
+					if bytes.Equal(sig.PubKey, key.pubKey) {
+						sigs = append(sigs, sig.Signature)
+					}
+				}
+			}
+			if err := finalizeMultisig(packet, wallet.Required); err != nil {
+				t.Fatal(err)
+			}
+			redeemScript, _ := multisigScript(wallet.Required, keys)
+			finalTx, err := packet.Extract()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if walletType == multisig.P2WSH {
+				witness := finalTx.TxIn[0].Witness
+				if len(finalTx.TxIn[0].SignatureScript) != 0 || len(witness) != 4 || len(witness[0]) != 0 || !bytes.Equal(witness[1], sigs[0]) || !bytes.Equal(witness[3], redeemScript) {
+					t.Error("unexpected witness for " + name)
+				}
+				continue
+			}
+			builder := txscript.NewScriptBuilder().AddOp(txscript.OP_0)
+			for _, sig := range sigs {
+				builder.AddData(sig)
+			}
+			expected, _ := builder.AddData(redeemScript).Script()
+			if !bytes.Equal(finalTx.TxIn[0].SignatureScript, expected) {
+				t.Error("unexpected signature script for " + name)
+			}
+		}
+	}
+}
+
+// testMultisigAddresses checks that the issued multisig addresses are ours and that their
+// balance is added to the balance of the coin.
+func testMultisigAddresses(t *testing.T, coin *coins.Coin, name string) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.Path, "sortedmulti(2,[d34db33f/48'/0'/0'/2']") {
+			_, _ = w.Write([]byte(`{"balance":"200000","unconfirmedBalance":"1000"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"balance":"100000","unconfirmedBalance":"0"}`))
+	}))
+	defer backend.Close()
+	defaultBlockbook := coin.Info.Blockbook
+	coin.Info.Blockbook = backend.URL
+	defer func() { coin.Info.Blockbook = defaultBlockbook }()
+
+	ctrl := &ControllerV2{Address: make(map[string]AddrInfo), availability: newCoinAvailability()}
+	issued, err := ctrl.GetAddressV2(ParamsV2{Coin: coin.Info.Tag})
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := json.Marshal(models.AddressValidationBodyReq{Coin: coin.Info.Tag, Address: issued.(string)})
+	if valid, err := ctrl.ValidateAddressV2(ParamsV2{Body: body}); err != nil || valid != true {
+		t.Error("an issued multisig address must be ours for " + name)
+	}
+	mine, err := ownScripts(coin, ctrl.addrInfo(coin.Info.Tag))
+	if err != nil || len(mine) != 1 {
+		t.Error("the payments to the multisig addresses must be ours for " + name)
+	}
+	balance, err := ctrl.GetBalanceV2(ParamsV2{Coin: coin.Info.Tag})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if balance.(plutus.Balance).Confirmed != 0.003 || balance.(plutus.Balance).Unconfirmed != 0.00001 {
+		t.Error("the balance must include the multisig wallet for " + name)
+	}
+}
+
+func TestMultisigSignatureCheck(t *testing.T) {
+	privKey, err := btcec.NewPrivateKey(btcec.S256())
+	if err != nil {
+		t.Fatal(err)
+	}
+	digest := bytes.Repeat([]byte{7}, 32)
+	signature, err := privKey.Sign(digest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig := psbt.PartialSig{PubKey: privKey.PubKey().SerializeCompressed(), Signature: append(signature.Serialize(), byte(txscript.SigHashAll))}
+	if err := verifySignature(digest, sig); err != nil {
+		t.Error("a valid signature was rejected")
+	}
+	sig.Signature[len(sig.Signature)-1] = byte(txscript.SigHashSingle)
+	if err := verifySignature(digest, sig); err == nil {
+		t.Error("only SIGHASH_ALL signatures must be accepted")
+	}
+}
@@ -8,10 +8,9 @@ import (
 	"math"
 	"math/big"
 	"net/http"
-	"os"
 	"reflect"
 	"strconv"
-	"strings"
+	"sync"
 	"time"
 
 	"github.com/eabz/btcutil"
@@ -21,39 +20,40 @@ import (
 	"github.com/ethereum/go-ethereum/accounts"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
-	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/grupokindynos/common/blockbook"
 	coinfactory "github.com/grupokindynos/common/coin-factory"
 	"github.com/grupokindynos/common/coin-factory/coins"
 	"github.com/grupokindynos/common/plutus"
+	"github.com/grupokindynos/plutus/logger"
+	"github.com/grupokindynos/plutus/metrics"
 	"github.com/grupokindynos/plutus/models"
 	"github.com/martinboehm/btcd/btcec"
-	"github.com/martinboehm/btcd/chaincfg/chainhash"
-	"github.com/martinboehm/btcd/wire"
-	hdwallet "github.com/miguelmota/go-ethereum-hdwallet"
-	"github.com/tyler-smith/go-bip39"
 	"golang.org/x/crypto/sha3"
 )
 
 type Params struct {
-	Coin string
-	Body []byte
-	Txid string
+	Coin      string
+	Body      []byte
+	Txid      string
+	RequestID string
 }
 
-var ethWallet *hdwallet.Wallet
-
 var myClient = &http.Client{Timeout: 10 * time.Second}
 
 const addrGap = 20
 
 type AddrInfo struct {
-	LastUsed int
-	AddrInfo []models.AddrInfo
+	// LastUsed and LastUsedInternal are the indexes of the last used addresses of the
+	// external and internal chains, -1 when none was used.
+	LastUsed         int
+	LastUsedInternal int
+	AddrInfo         []models.AddrInfo
 }
 
 type Controller struct {
-	Address map[string]AddrInfo
+	Address      map[string]AddrInfo
+	mu           sync.RWMutex
+	availability *coinAvailability
 }
 
 type GasStation struct {
@@ -68,13 +68,16 @@ type GasStation struct {
 }
 
 func (c *Controller) GetBalance(params Params) (interface{}, error) {
-	coinConfig, err := coinfactory.GetCoin(params.Coin)
+	coinConfig, err := getCoin(params.Coin)
 	if err != nil {
 		return nil, err
 	}
+	if err := c.availability.check(coinConfig); err != nil {
+		return nil, err
+	}
 	if !coinConfig.Info.Token && coinConfig.Info.Tag != "ETH" {
 		blockBookWrap := blockbook.NewBlockBookWrapper(coinConfig.Info.Blockbook)
-		acc, err := getAccFromMnemonic(coinConfig, false)
+		acc, err := getAccPub(coinConfig)
 		if err != nil {
 			return nil, err
 		}
@@ -82,7 +85,9 @@ func (c *Controller) GetBalance(params Params) (interface{}, error) {
 		if err != nil {
 			return nil, err
 		}
+		start := time.Now()
 		info, err := blockBookWrap.GetXpub(pub.String())
+		metrics.ObserveBlockbook(coinConfig.Info.Tag, "xpub", start, err)
 		if err != nil {
 			return nil, err
 		}
@@ -91,22 +96,32 @@ func (c *Controller) GetBalance(params Params) (interface{}, error) {
 			return nil, err
 		}
 		unconfirmed, err := strconv.ParseFloat(info.UnconfirmedBalance, 64)
+		multisigConfirmed, multisigUnconfirmed, err := multisigBalance(coinConfig)
+		if err != nil {
+			return nil, err
+		}
+		confirmed += multisigConfirmed
+		unconfirmed += multisigUnconfirmed
 		response := plutus.Balance{
 			Confirmed:   confirmed / 1e8,
 			Unconfirmed: unconfirmed / 1e8,
 		}
+		metrics.HotBalance.WithLabelValues(coinConfig.Info.Tag, "confirmed").Set(response.Confirmed)
+		metrics.HotBalance.WithLabelValues(coinConfig.Info.Tag, "unconfirmed").Set(response.Unconfirmed)
 		return response, nil
 	} else {
-		ethConfig, err := coinfactory.GetCoin("ETH")
+		ethConfig, err := getCoin("ETH")
 		if err != nil {
 			return nil, err
 		}
-		acc, err := getEthAccFromMnemonic(ethConfig, false)
+		address, err := ethAddress("ETH")
 		if err != nil {
 			return nil, err
 		}
 		blockBookWrap := blockbook.NewBlockBookWrapper(ethConfig.Info.Blockbook)
-		info, err := blockBookWrap.GetEthAddress(acc.Address.Hex())
+		start := time.Now()
+		info, err := blockBookWrap.GetEthAddress(address.Hex())
+		metrics.ObserveBlockbook(ethConfig.Info.Tag, "eth_address", start, err)
 		if err != nil {
 			return nil, err
 		}
@@ -116,6 +131,7 @@ func (c *Controller) GetBalance(params Params) (interface{}, error) {
 				response := plutus.Balance{
 					Confirmed: 0,
 				}
+				metrics.HotBalance.WithLabelValues(coinConfig.Info.Tag, "confirmed").Set(0)
 				return response, nil
 			}
 			balance, err := strconv.ParseFloat(tokenInfo.Balance, 64)
@@ -126,6 +142,7 @@ func (c *Controller) GetBalance(params Params) (interface{}, error) {
 			response := plutus.Balance{
 				Confirmed: balance,
 			}
+			metrics.HotBalance.WithLabelValues(coinConfig.Info.Tag, "confirmed").Set(response.Confirmed)
 			return response, nil
 		} else {
 			balance, err := strconv.ParseFloat(info.Balance, 64)
@@ -135,6 +152,7 @@ func (c *Controller) GetBalance(params Params) (interface{}, error) {
 			response := plutus.Balance{
 				Confirmed: balance / 1e18,
 			}
+			metrics.HotBalance.WithLabelValues(coinConfig.Info.Tag, "confirmed").Set(response.Confirmed)
 			return response, nil
 		}
 	}
@@ -152,252 +170,125 @@ func ercDetails(info blockbook.EthAddr, contract string) *blockbook.EthTokens {
 }
 
 func (c *Controller) GetAddress(params Params) (interface{}, error) {
-	coinConfig, err := coinfactory.GetCoin(params.Coin)
+	coinConfig, err := getCoin(params.Coin)
 	if err != nil {
 		return nil, err
 	}
+	meta := models.AddressMeta{Coin: coinConfig.Info.Tag, RequestID: params.RequestID}
 	if coinConfig.Info.Token || coinConfig.Info.Tag == "ETH" {
-		var acc accounts.Account
-		if coinConfig.Mnemonic == "" {
-			ethConfig, err := coinfactory.GetCoin("ETH")
-			if err != nil {
-				return nil, err
-			}
-			acc, err = getEthAccFromMnemonic(ethConfig, false)
-			if err != nil {
-				return nil, err
-			}
-		} else {
-			acc, err = getEthAccFromMnemonic(coinConfig, false)
-			if err != nil {
-				return nil, err
-			}
+		address, err := tokenAddress(coinConfig)
+		if err != nil {
+			return nil, err
 		}
-		return acc.Address.Hex(), nil
+		meta.Address = address.Hex()
+		if err := recordAccountAddress(meta); err != nil {
+			return nil, err
+		}
+		return meta.Address, nil
 	}
-	acc, err := getAccFromMnemonic(coinConfig, false)
-	if err != nil {
+	if err := c.availability.check(coinConfig); err != nil {
 		return nil, err
 	}
-	// Create a new xpub and derive the address from the hdwallet
-	directExtended, err := acc.Child(0)
-	if err != nil {
-		return nil, err
+	if wallet, ok := multisigWallet(coinConfig); ok {
+		meta.Address, meta.Path, err = nextMultisigAddress(coinConfig, wallet)
+		meta.Multisig = true
+		if err == nil {
+			c.trackAddress(coinConfig.Info.Tag, models.AddrInfo{Addr: meta.Address, Path: meta.Path, Multisig: true})
+		}
+	} else {
+		meta.Address, meta.Path, err = c.nextAddress(coinConfig)
 	}
-	addrExtPub, err := directExtended.Child(uint32(c.Address[coinConfig.Info.Tag].LastUsed + 1))
 	if err != nil {
 		return nil, err
 	}
-	addr, err := addrExtPub.Address(coinConfig.NetParams)
-	if err != nil {
+	if err := recordAddress(meta); err != nil {
 		return nil, err
 	}
-	newAddrInfo := AddrInfo{
-		LastUsed: c.Address[coinConfig.Info.Tag].LastUsed + 1,
-		AddrInfo: c.Address[coinConfig.Info.Tag].AddrInfo,
-	}
-	newAddrInfo.AddrInfo = append(newAddrInfo.AddrInfo, models.AddrInfo{
-		Addr: addr.String(), Path: c.Address[coinConfig.Info.Tag].LastUsed + 1,
-	})
-	c.Address[coinConfig.Info.Tag] = newAddrInfo
-	return addr.String(), nil
+	return meta.Address, nil
 }
 
-func (c *Controller) SendToAddress(params Params) (interface{}, error) {
-	var SendToAddressData plutus.SendAddressBodyReq
-	err := json.Unmarshal(params.Body, &SendToAddressData)
-	if err != nil {
-		return nil, err
-	}
-	coinConfig, err := coinfactory.GetCoin(SendToAddressData.Coin)
+// nextAddress derives the receive address following the last used and the last issued ones.
+func (c *Controller) nextAddress(coinConfig *coins.Coin) (string, int, error) {
+	acc, err := getAccPub(coinConfig)
 	if err != nil {
-		return "", err
-	}
-	var txid string
-	if coinConfig.Info.Token || coinConfig.Info.Tag == "ETH" {
-		txid, err = c.sendToAddressEth(SendToAddressData, coinConfig)
-		if err != nil {
-			return nil, err
-		}
-	} else {
-		txid, err = c.sendToAddress(SendToAddressData, coinConfig)
-		if err != nil {
-			return nil, err
-		}
+		return "", 0, err
 	}
-	return txid, nil
-}
-
-func (c *Controller) sendToAddress(SendToAddressData plutus.SendAddressBodyReq, coinConfig *coins.Coin) (string, error) {
-	value, err := btcutil.NewAmount(SendToAddressData.Amount)
-	if err != nil {
-		return "", err
-	}
-	acc, err := getAccFromMnemonic(coinConfig, true)
+	// Create a new xpub and derive the address from the hdwallet
+	directExtended, err := acc.Child(0)
 	if err != nil {
-		return "", err
+		return "", 0, err
 	}
-	accPub, err := acc.Neuter()
+	path, err := reserveAddressIndex(coinConfig.Info.Tag, c.addrInfo(coinConfig.Info.Tag).LastUsed)
 	if err != nil {
-		return "", err
+		return "", 0, err
 	}
-	blockBookWrap := blockbook.NewBlockBookWrapper(coinConfig.Info.Blockbook)
-	utxos, err := blockBookWrap.GetUtxo(accPub.String(), false)
+	addrExtPub, err := directExtended.Child(uint32(path))
 	if err != nil {
-		return "", err
-	}
-	if len(utxos) == 0 {
-		return "", errors.New("no balance available")
+		return "", 0, err
 	}
-	var Tx wire.MsgTx
-	var txVersion int32
-	if coinConfig.Info.Tag == "POLIS" || coinConfig.Info.Tag == "DASH" || coinConfig.Info.Tag == "GRS" {
-		txVersion = 2
-	} else {
-		txVersion = 1
-	}
-	var availableAmount btcutil.Amount
-	var changeAddrPubKeyHash string
-	// Add the inputs without signatures
-	for i, utxo := range utxos {
-		if i == 0 {
-			changeAddrPubKeyHash = utxo.Address
-		}
-		intValue, err := strconv.ParseInt(utxo.Value, 10, 64)
-		if err != nil {
-			return "", err
-		}
-		utxoAmount := btcutil.Amount(intValue)
-		availableAmount += utxoAmount
-		txidHash, err := chainhash.NewHashFromStr(utxo.Txid)
-		if err != nil {
-			return "", err
-		}
-		prevOut := wire.NewOutPoint(txidHash, uint32(utxo.Vout))
-		in := wire.NewTxIn(prevOut, nil, nil)
-		Tx.AddTxIn(in)
-	}
-	// To prevent address collision we need to de-register all networks and register just the network using
-	chaincfg.ResetParams()
-	chaincfg.Register(coinConfig.NetParams)
-	// Retrieve information for outputs
-	payAddr, err := btcutil.DecodeAddress(SendToAddressData.Address, coinConfig.NetParams)
-	if err != nil {
-		return "", err
-	}
-	changeAddr, err := btcutil.DecodeAddress(changeAddrPubKeyHash, coinConfig.NetParams)
+	addr, err := addrExtPub.Address(coinConfig.NetParams)
 	if err != nil {
-		return "", err
+		return "", 0, err
 	}
-	pkScriptPay, err := txscript.PayToAddrScript(payAddr)
+	c.trackAddress(coinConfig.Info.Tag, models.AddrInfo{Addr: addr.String(), Path: path, Chain: externalChain})
+	return addr.String(), path, nil
+}
+
+func (c *Controller) SendToAddress(params Params) (interface{}, error) {
+	var SendToAddressData plutus.SendAddressBodyReq
+	err := json.Unmarshal(params.Body, &SendToAddressData)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	pkScriptChange, err := txscript.PayToAddrScript(changeAddr)
+	coinConfig, err := getCoin(SendToAddressData.Coin)
 	if err != nil {
 		return "", err
 	}
-	txOut := &wire.TxOut{
-		Value:    int64(value.ToUnit(btcutil.AmountSatoshi)),
-		PkScript: pkScriptPay,
+	if err := c.availability.check(coinConfig); err != nil {
+		metrics.ObserveSend(coinConfig.Info.Tag, err)
+		return nil, err
 	}
-	var fee blockbook.Fee
-	if SendToAddressData.Coin == "BTC" {
-		fee, err = blockBookWrap.GetFee("4")
-		if err != nil {
-			return "", err
-		}
-	} else {
-		fee, err = blockBookWrap.GetFee("2")
-		if err != nil {
-			return "", err
-		}
+	if watchOnly() {
+		metrics.ObserveSend(coinConfig.Info.Tag, errWatchOnly)
+		return nil, errWatchOnly
 	}
-	var feeRate int64
-	if fee.Result == "-1" || fee.Result == "0" || fee.Result == "" {
-		feeRate = 4000
-	} else {
-		feeParse, err := strconv.ParseFloat(fee.Result, 64)
-		if err != nil {
-			return "", err
-		}
-		feeRate = int64(feeParse * 1e8)
-	}
-	txSize := (len(Tx.TxIn) * 180) + (len(Tx.TxOut) * 34) + 124
-	feeSats := float64(feeRate) / 1024.0 * float64(txSize)
-	payingFee := btcutil.Amount(int64(feeSats))
-	if availableAmount-payingFee-value > 0 {
-		txOutChange := &wire.TxOut{
-			Value:    int64(((availableAmount - value) - payingFee).ToUnit(btcutil.AmountSatoshi)),
-			PkScript: pkScriptChange,
-		}
-		Tx.AddTxOut(txOutChange)
+	var txid string
+	if coinConfig.Info.Token || coinConfig.Info.Tag == "ETH" {
+		txid, err = c.sendToAddressEth(SendToAddressData, coinConfig, sendOrigin{requestID: params.RequestID})
 	} else {
-		txOut.Value = int64((value - payingFee).ToUnit(btcutil.AmountSatoshi))
-	}
-	Tx.AddTxOut(txOut)
-	Tx.Version = txVersion
-	// To prevent address collision we need to de-register all networks and register just the network using
-	chaincfg.ResetParams()
-	chaincfg.Register(coinConfig.NetParams)
-
-	// Create the signatures
-	for i, utxo := range utxos {
-		path := strings.Split(utxo.Path, "/")
-		pathParse, err := strconv.ParseInt(path[5], 10, 64)
-		if err != nil {
-			return "", err
-		}
-		privKey, err := getPrivKeyFromPath(acc, uint32(pathParse))
-		if err != nil {
-			return "", err
-		}
-		addr, err := btcutil.DecodeAddress(utxo.Address, coinConfig.NetParams)
-		if err != nil {
-			return "", err
-		}
-		subscript, err := txscript.PayToAddrScript(addr)
-		if err != nil {
-			return "", err
-		}
-		var sigHash txscript.SigHashHasher
-		if coinConfig.Info.Tag == "GRS" {
-			sigHash = txscript.Sha256
-		} else {
-			sigHash = txscript.Sha256d
-		}
-		sigScript, err := txscript.SignatureScript(&Tx, i, subscript, txscript.SigHashAll, privKey, true, sigHash)
-		if err != nil {
-			return "", err
-		}
-		Tx.TxIn[i].SignatureScript = sigScript
+		txid, err = c.sendToAddress(SendToAddressData, coinConfig, sendOrigin{requestID: params.RequestID})
 	}
-	buf := bytes.NewBuffer([]byte{})
-	err = Tx.BtcEncode(buf, 0, wire.BaseEncoding)
+	metrics.ObserveSend(coinConfig.Info.Tag, err)
 	if err != nil {
-		return "", err
+		logger.Default().WithRequestID(params.RequestID).Error("SendToAddress: send failed", "coin", coinConfig.Info.Tag, "address", SendToAddressData.Address, "amount", SendToAddressData.Amount, "err", err)
+		return nil, err
 	}
-	rawTx := hex.EncodeToString(buf.Bytes())
-	return blockBookWrap.SendTx(rawTx)
+	return txid, nil
+}
+
+func (c *Controller) sendToAddress(SendToAddressData plutus.SendAddressBodyReq, coinConfig *coins.Coin, origin sendOrigin) (string, error) {
+	return sendPayment(coinConfig, SendToAddressData.Address, SendToAddressData.Amount, origin, logger.Default().WithRequestID(origin.requestID))
 }
 
-func (c *Controller) sendToAddressEth(SendToAddressData plutus.SendAddressBodyReq, coinConfig *coins.Coin) (string, error) {
+func (c *Controller) sendToAddressEth(SendToAddressData plutus.SendAddressBodyReq, coinConfig *coins.Coin, origin sendOrigin) (string, error) {
 	// using the ethereum account to hl the tokens
-	ethConfig, err := coinfactory.GetCoin("ETH")
+	ethConfig, err := getCoin("ETH")
 	if err != nil {
 		return "", err
 	}
 	//**get the account that holds the private keys and addresses
-	account, err := getEthAccFromMnemonic(ethConfig, true)
+	address, err := ethAddress("ETH")
 	if err != nil {
 		return "", err
 	}
-	ethAccount := account.Address.Hex()
+	ethAccount := address.Hex()
 
 	blockBookWrap := blockbook.NewBlockBookWrapper(ethConfig.Info.Blockbook)
 
 	//** get the balance, check if its > 0 or less than the amount
+	start := time.Now()
 	info, err := blockBookWrap.GetEthAddress(ethAccount)
+	metrics.ObserveBlockbook(ethConfig.Info.Tag, "eth_address", start, err)
 	if err != nil {
 		return "", err
 	}
@@ -439,16 +330,11 @@ func (c *Controller) sendToAddressEth(SendToAddressData plutus.SendAddressBodyRe
 	//** Retrieve information for outputs: out address
 	toAddress := common.HexToAddress(SendToAddressData.Address)
 	//**calculate fee/gas cost
-	gasLimit := uint64(21000)
-	if coinConfig.Info.Tag != "ETH" {
-		gasLimit = uint64(200000)
-	}
-	gasStation := GasStation{}
-	err = getJSON("https://ethgasstation.info/json/ethgasAPI.json", &gasStation)
+	gasLimit := ethGasLimit(coinConfig)
+	gasPrice, err := getGasPrice()
 	if err != nil {
-		return "", errors.New("could not retrieve the gas price")
+		return "", err
 	}
-	gasPrice := big.NewInt(int64(1000000000 * (gasStation.Average / 10))) //(10^9*(gweiValue/10))
 	var data []byte
 	var tx *types.Transaction
 
@@ -481,14 +367,28 @@ func (c *Controller) sendToAddressEth(SendToAddressData plutus.SendAddressBodyRe
 		tx = types.NewTransaction(nonce, toAddress, value, gasLimit, gasPrice, data)
 	}
 	// **sign and send
-	signedTx, err := signEthTx(ethConfig, account, tx, nil)
+	signedTx, err := txSigner.SignEthTx(ethConfig.Info.Tag, tx, nil)
 	if err != nil {
 		return "", errors.New("failed to sign transaction")
 	}
 	ts := types.Transactions{signedTx}
 	rawTxBytes := ts.GetRlp(0)
 	rawTxHex := hex.EncodeToString(rawTxBytes)
-	return blockBookWrap.SendTx("0x" + rawTxHex)
+	start = time.Now()
+	txid, err := blockBookWrap.SendTx("0x" + rawTxHex)
+	metrics.ObserveBlockbook(ethConfig.Info.Tag, "send_tx", start, err)
+	if err != nil {
+		return "", err
+	}
+	metrics.FeePaid.WithLabelValues(ethConfig.Info.Tag).Add(gasFee(gasPrice, gasLimit))
+	journalSend(models.SendJournalEntry{
+		Coin:    coinConfig.Info.Tag,
+		Txid:    txid,
+		Address: SendToAddressData.Address,
+		Amount:  SendToAddressData.Amount,
+		Fee:     gasFee(gasPrice, gasLimit),
+	}, origin, logger.Default().WithRequestID(origin.requestID))
+	return txid, nil
 	//return "", nil
 }
 
@@ -508,23 +408,22 @@ func (c *Controller) ValidateAddress(params Params) (interface{}, error) {
 	if err != nil {
 		return nil, err
 	}
-	coinConfig, err := coinfactory.GetCoin(ValidateAddressData.Coin)
+	coinConfig, err := getCoin(ValidateAddressData.Coin)
 	if err != nil {
 		return nil, err
 	}
 	if coinConfig.Info.Token || coinConfig.Info.Tag == "ETH" {
-		coinConfig, err = coinfactory.GetCoin("ETH")
-		if err != nil {
-			return nil, err
-		}
-		acc, err := getEthAccFromMnemonic(coinConfig, false)
+		address, err := ethAddress("ETH")
 		if err != nil {
 			return nil, err
 		}
-		return reflect.DeepEqual(ValidateAddressData.Address, acc.Address.Hex()), nil
+		return reflect.DeepEqual(ValidateAddressData.Address, address.Hex()), nil
+	}
+	if err := c.availability.check(coinConfig); err != nil {
+		return nil, err
 	}
 	var isMine bool
-	for _, addr := range c.Address[coinConfig.Info.Tag].AddrInfo {
+	for _, addr := range c.addrInfo(coinConfig.Info.Tag).AddrInfo {
 		if addr.Addr == ValidateAddressData.Address {
 			isMine = true
 		}
@@ -538,40 +437,32 @@ func (c *Controller) ValidateRawTx(params Params) (interface{}, error) {
 	if err != nil {
 		return nil, err
 	}
-	coinConfig, err := coinfactory.GetCoin(ValidateTxData.Coin)
+	coinConfig, err := getCoin(ValidateTxData.Coin)
 	if err != nil {
 		return nil, err
 	}
+	if err := c.availability.check(coinConfig); err != nil {
+		return nil, err
+	}
 
 	var isValue, isAddress bool
 
 	//ethereum-like coins (and ERC20)
 	if coinConfig.Info.Token || coinConfig.Info.Tag == "ETH" {
 		value := ValidateTxData.Amount
-		var tx *types.Transaction
-		if ValidateTxData.RawTx[0:2] == "0x" {
-			ValidateTxData.RawTx = ValidateTxData.RawTx[2:]
-		}
-		rawtx, err := hex.DecodeString(ValidateTxData.RawTx)
+		tx, err := decodeEthTx(ValidateTxData.RawTx)
 		if err != nil {
 			return nil, err
 		}
-		err = rlp.DecodeBytes(rawtx, &tx)
+		txAddr, txBodyAmount, ok, err := ethPayment(coinConfig, tx)
 		if err != nil {
 			return nil, err
 		}
-		//compare amount from the tx and the input body
-		var txBodyAmount int64
-		var txAddr common.Address
-		if coinConfig.Info.Token && coinConfig.Info.Tag != "ETH" {
-			address, amount := DecodeERC20Data([]byte(hex.EncodeToString(tx.Data())))
-			txAddr = common.HexToAddress(string(address))
-			txBodyAmount = amount.Int64()
-		} else {
-			txBodyAmount = tx.Value().Int64()
-			txAddr = *tx.To()
+		if !ok {
+			return false, nil
 		}
-		if txBodyAmount == value {
+		//compare amount from the tx and the input body
+		if txBodyAmount.Cmp(big.NewInt(value)) == 0 {
 			isValue = true
 		}
 		bodyAddr := common.HexToAddress(ValidateTxData.Address)
@@ -596,11 +487,9 @@ func (c *Controller) ValidateRawTx(params Params) (interface{}, error) {
 		chaincfg.ResetParams()
 		chaincfg.Register(coinConfig.NetParams)
 		for _, out := range tx.MsgTx().TxOut {
-			outAmount := btcutil.Amount(out.Value)
-			if outAmount == value {
-				isValue = true
-			}
-			for _, addr := range c.Address[coinConfig.Info.Tag].AddrInfo {
+			// the amount must be paid to us on a single output
+			var isMine bool
+			for _, addr := range c.addrInfo(coinConfig.Info.Tag).AddrInfo {
 				Addr, err := btcutil.DecodeAddress(addr.Addr, coinConfig.NetParams)
 				if err != nil {
 					return nil, err
@@ -610,7 +499,13 @@ func (c *Controller) ValidateRawTx(params Params) (interface{}, error) {
 					return nil, err
 				}
 				if bytes.Equal(scriptAddr, out.PkScript) {
-					isAddress = true
+					isMine = true
+				}
+			}
+			if isMine {
+				isAddress = true
+				if btcutil.Amount(out.Value) == value {
+					isValue = true
 				}
 			}
 		}
@@ -623,99 +518,96 @@ func (c *Controller) ValidateRawTx(params Params) (interface{}, error) {
 	}
 }
 
-func (c *Controller) getAddrs(coinConfig *coins.Coin) error {
-	acc, err := getAccFromMnemonic(coinConfig, false)
+// ValidateRawTxReport returns the detailed validation of a raw transaction.
+func (c *Controller) ValidateRawTxReport(params Params) (interface{}, error) {
+	var ValidateTxData plutus.ValidateRawTxReq
+	err := json.Unmarshal(params.Body, &ValidateTxData)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	blockBookWrap := blockbook.NewBlockBookWrapper(coinConfig.Info.Blockbook)
-	info, err := blockBookWrap.GetXpub(acc.String())
+	coinConfig, err := getCoin(ValidateTxData.Coin)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	var addrInfoSlice []models.AddrInfo
-	for i := info.UsedTokens; i < info.UsedTokens+addrGap; i++ {
-		addr, err := getPubKeyHashFromPath(acc, coinConfig, uint32(i))
-		if err != nil {
-			return err
-		}
-		addrInfo := models.AddrInfo{Addr: addr, Path: i}
-		addrInfoSlice = append(addrInfoSlice, addrInfo)
+	if err := c.availability.check(coinConfig); err != nil {
+		return nil, err
 	}
-	c.Address[coinConfig.Info.Tag] = AddrInfo{
-		LastUsed: info.UsedTokens,
-		AddrInfo: addrInfoSlice,
+	return rawTxReport(coinConfig, ValidateTxData.RawTx, ValidateTxData.Address, ValidateTxData.Amount, c.addrInfo(coinConfig.Info.Tag))
+}
+
+func (c *Controller) getAddrs(coinConfig *coins.Coin) error {
+	info, err := discoverAddrs(coinConfig)
+	if err != nil {
+		return err
 	}
+	c.mu.Lock()
+	c.Address[coinConfig.Info.Tag] = info
+	c.mu.Unlock()
+	markSynced(coinConfig.Info.Tag)
 	return nil
 }
 
-func getAccFromMnemonic(coinConfig *coins.Coin, priv bool) (*hdkeychain.ExtendedKey, error) {
-	chaincfg.ResetParams()
-	_ = chaincfg.Register(coinConfig.NetParams)
-	if coinConfig.Mnemonic == "" {
-		return nil, errors.New("the coin is not available")
-	}
-	seed := bip39.NewSeed(coinConfig.Mnemonic, os.Getenv("MNEMONIC_PASSWORD"))
-	mKey, err := hdkeychain.NewMaster(seed, coinConfig.NetParams)
+// Rescan runs the address discovery of a coin again and replaces its known addresses.
+func (c *Controller) Rescan(params Params) (interface{}, error) {
+	coinConfig, err := getCoin(params.Coin)
 	if err != nil {
 		return nil, err
 	}
-	purposeChild, err := mKey.Child(hdkeychain.HardenedKeyStart + 44)
-	if err != nil {
-		return nil, err
+	if !syncsAddresses(coinConfig) {
+		return nil, errors.New("the coin has no addresses to rescan")
 	}
-	coinType, err := purposeChild.Child(hdkeychain.HardenedKeyStart + coinConfig.NetParams.HDCoinType)
-	if err != nil {
+	if err := c.getAddrs(coinConfig); err != nil {
 		return nil, err
 	}
-	accChild, err := coinType.Child(hdkeychain.HardenedKeyStart + 0)
-	if err != nil {
-		return nil, err
-	}
-	if priv {
-		return accChild, nil
-	}
-	return accChild.Neuter()
+	c.availability.markAvailable(coinConfig.Info.Tag)
+	return rescanResponse(coinConfig, c.addrInfo(coinConfig.Info.Tag)), nil
 }
 
-func getEthAccFromMnemonic(coinConfig *coins.Coin, saveWallet bool) (accounts.Account, error) {
-	if coinConfig.Mnemonic == "" {
-		return accounts.Account{}, errors.New("the coin is not available")
-	}
-	wallet, err := hdwallet.NewFromMnemonic(coinConfig.Mnemonic)
-	if err != nil {
-		return accounts.Account{}, err
+// trackAddress adds an issued address to the known addresses of the coin, once. A receive
+// address moves the last used index of the coin.
+func (c *Controller) trackAddress(tag string, addr models.AddrInfo) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	info := c.Address[tag]
+	if !addr.Multisig && addr.Chain == externalChain && addr.Path > info.LastUsed {
+		info.LastUsed = addr.Path
 	}
-	// standard for eth wallets like Metamask
-	path := hdwallet.MustParseDerivationPath("m/44'/60'/0'/0/0")
-	account, err := wallet.Derive(path, true)
-	if err != nil {
-		return accounts.Account{}, err
+	known := false
+	for _, knownAddr := range info.AddrInfo {
+		known = known || knownAddr.Addr == addr.Addr
 	}
-	if saveWallet {
-		ethWallet = wallet
+	if !known {
+		info.AddrInfo = append(info.AddrInfo, addr)
 	}
-	return account, nil
+	c.Address[tag] = info
+}
+
+func (c *Controller) addrInfo(tag string) AddrInfo {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.Address[tag]
+}
+
+// Unavailable returns the coins that failed to sync together with the reason.
+func (c *Controller) Unavailable() map[string]string {
+	return c.availability.list()
 }
 
-func signEthTx(coinConfig *coins.Coin, account accounts.Account, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+func getAccFromMnemonic(coinConfig *coins.Coin, priv bool) (*hdkeychain.ExtendedKey, error) {
+	chaincfg.ResetParams()
+	_ = chaincfg.Register(coinConfig.NetParams)
 	if coinConfig.Mnemonic == "" {
 		return nil, errors.New("the coin is not available")
 	}
-	signedTx, err := ethWallet.SignTx(account, tx, chainID)
-	if err != nil {
-		return nil, err
-	}
-	return signedTx, nil
+	return keys.account(coinConfig, priv)
 }
 
-func DecodeERC20Data(b []byte) ([]byte, *big.Int) {
-	to := b[32:72]
-	tokens := b[74:136]
-	hexed, _ := hex.DecodeString(string(tokens))
-	amount := big.NewInt(0)
-	amount.SetBytes(hexed)
-	return to, amount
+func getEthAccFromMnemonic(coinConfig *coins.Coin) (accounts.Account, error) {
+	if coinConfig.Mnemonic == "" {
+		return accounts.Account{}, errors.New("the coin is not available")
+	}
+	_, account, err := keys.ethAccount(coinConfig.Mnemonic)
+	return account, err
 }
 
 func decimalToToken(decimalAmount float64, decimals int) *big.Int {
@@ -758,19 +650,19 @@ func getPrivKeyFromPath(acc *hdkeychain.ExtendedKey, path uint32) (*btcec.Privat
 
 func NewPlutusController() *Controller {
 	ctrl := &Controller{
-		Address: make(map[string]AddrInfo),
+		Address:      make(map[string]AddrInfo),
+		availability: newCoinAvailability(),
 	}
+	warmKeyCache()
 	// Here we handle only active coins
 	for tag := range coinfactory.Coins {
-		coin, err := coinfactory.GetCoin(tag)
+		coin, err := getCoin(tag)
 		if err != nil {
-			panic(err)
+			logger.Error("NewPlutusController: unable to load the coin", "coin", tag, "err", err)
+			continue
 		}
-		if !coin.Info.Token && coin.Info.Tag != "ETH" && coin.Info.Tag != "XSG" && coin.Info.Tag != "DAPS" {
-			err := ctrl.getAddrs(coin)
-			if err != nil {
-				panic(errors.New(err.Error() + " " + coin.Info.Tag))
-			}
+		if syncsAddresses(coin) {
+			ctrl.availability.syncCoin(coin, ctrl.getAddrs)
 		}
 	}
 	return ctrl
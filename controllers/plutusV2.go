@@ -7,51 +7,72 @@ import (
 	"errors"
 	"github.com/eabz/btcutil"
 	"github.com/eabz/btcutil/txscript"
-	"github.com/ethereum/go-ethereum/accounts"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
-	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/grupokindynos/common/blockbook"
 	coinfactory "github.com/grupokindynos/common/coin-factory"
 	"github.com/grupokindynos/common/coin-factory/coins"
 	"github.com/grupokindynos/common/plutus"
+	"github.com/grupokindynos/plutus/logger"
+	"github.com/grupokindynos/plutus/metrics"
 	"github.com/grupokindynos/plutus/models"
-	"github.com/martinboehm/btcd/chaincfg/chainhash"
-	"github.com/martinboehm/btcd/wire"
-	hdwallet "github.com/miguelmota/go-ethereum-hdwallet"
 	"golang.org/x/crypto/sha3"
-	"log"
 	"math"
 	"math/big"
-	"os"
 	"reflect"
 	"strconv"
-	"strings"
+	"sync"
+	"time"
 )
 
 type ParamsV2 struct {
-	Coin    string
-	Body    []byte
-	Txid    string
-	Service string
+	Coin      string
+	Body      []byte
+	Txid      string
+	Address   string
+	Service   string
+	Job       string
+	RequestID string
+	// Label and Reference are attached to the addresses issued by GetAddressV2.
+	Label     string
+	Reference string
+	// ID and Status select the webhook deliveries of the deposit watcher.
+	ID     string
+	Status string
+	// Page, Limit, From and To select the transactions of the history.
+	Page  string
+	Limit string
+	From  string
+	To    string
 }
 
 type ControllerV2 struct {
-	Address map[string]AddrInfo
+	Address      map[string]AddrInfo
+	mu           sync.RWMutex
+	availability *coinAvailability
 }
 
-var ethWalletV2 *hdwallet.Wallet
-
 const coinV2 = "ETHV2"
 
+// ethAccountTag returns the ethereum mnemonic used by a service, tyche and ladon have their own.
+func ethAccountTag(service string) string {
+	if service == "tyche" || service == "ladon" {
+		return coinV2
+	}
+	return "ETH"
+}
+
 func (c *ControllerV2) GetBalanceV2(params ParamsV2) (interface{}, error) {
-	coinConfig, err := coinfactory.GetCoin(params.Coin)
+	coinConfig, err := getCoin(params.Coin)
 	if err != nil {
 		return nil, err
 	}
+	if err := c.availability.check(coinConfig); err != nil {
+		return nil, err
+	}
 	if !coinConfig.Info.Token && coinConfig.Info.Tag != "ETH" {
 		blockBookWrap := blockbook.NewBlockBookWrapper(coinConfig.Info.Blockbook)
-		acc, err := getAccFromMnemonic(coinConfig, false)
+		acc, err := getAccPub(coinConfig)
 		if err != nil {
 			return nil, err
 		}
@@ -59,7 +80,9 @@ func (c *ControllerV2) GetBalanceV2(params ParamsV2) (interface{}, error) {
 		if err != nil {
 			return nil, err
 		}
+		start := time.Now()
 		info, err := blockBookWrap.GetXpub(pub.String())
+		metrics.ObserveBlockbook(coinConfig.Info.Tag, "xpub", start, err)
 		if err != nil {
 			return nil, err
 		}
@@ -68,25 +91,32 @@ func (c *ControllerV2) GetBalanceV2(params ParamsV2) (interface{}, error) {
 			return nil, err
 		}
 		unconfirmed, err := strconv.ParseFloat(info.UnconfirmedBalance, 64)
+		multisigConfirmed, multisigUnconfirmed, err := multisigBalance(coinConfig)
+		if err != nil {
+			return nil, err
+		}
+		confirmed += multisigConfirmed
+		unconfirmed += multisigUnconfirmed
 		response := plutus.Balance{
 			Confirmed:   confirmed / 1e8,
 			Unconfirmed: unconfirmed / 1e8,
 		}
+		metrics.HotBalance.WithLabelValues(coinConfig.Info.Tag, "confirmed").Set(response.Confirmed)
+		metrics.HotBalance.WithLabelValues(coinConfig.Info.Tag, "unconfirmed").Set(response.Unconfirmed)
 		return response, nil
 	} else {
-		ethConfig, err := coinfactory.GetCoin("ETH")
+		ethConfig, err := getCoin("ETH")
 		if err != nil {
 			return nil, err
 		}
-		if params.Service == "tyche" || params.Service == "ladon" {
-			ethConfig.Mnemonic = os.Getenv("MNEMONIC_" + coinV2)
-		}
-		acc, err := getEthAccFromMnemonicV2(ethConfig, false)
+		address, err := ethAddress(ethAccountTag(params.Service))
 		if err != nil {
 			return nil, err
 		}
 		blockBookWrap := blockbook.NewBlockBookWrapper(ethConfig.Info.Blockbook)
-		info, err := blockBookWrap.GetEthAddress(acc.Address.Hex())
+		start := time.Now()
+		info, err := blockBookWrap.GetEthAddress(address.Hex())
+		metrics.ObserveBlockbook(ethConfig.Info.Tag, "eth_address", start, err)
 		if err != nil {
 			return nil, err
 		}
@@ -96,6 +126,7 @@ func (c *ControllerV2) GetBalanceV2(params ParamsV2) (interface{}, error) {
 				response := plutus.Balance{
 					Confirmed: 0,
 				}
+				metrics.HotBalance.WithLabelValues(coinConfig.Info.Tag, "confirmed").Set(0)
 				return response, nil
 			}
 			balance, err := strconv.ParseFloat(tokenInfo.Balance, 64)
@@ -106,6 +137,7 @@ func (c *ControllerV2) GetBalanceV2(params ParamsV2) (interface{}, error) {
 			response := plutus.Balance{
 				Confirmed: balance,
 			}
+			metrics.HotBalance.WithLabelValues(coinConfig.Info.Tag, "confirmed").Set(response.Confirmed)
 			return response, nil
 		} else {
 			balance, err := strconv.ParseFloat(info.Balance, 64)
@@ -115,270 +147,149 @@ func (c *ControllerV2) GetBalanceV2(params ParamsV2) (interface{}, error) {
 			response := plutus.Balance{
 				Confirmed: balance / 1e18,
 			}
+			metrics.HotBalance.WithLabelValues(coinConfig.Info.Tag, "confirmed").Set(response.Confirmed)
 			return response, nil
 		}
 	}
 }
 
 func (c *ControllerV2) GetAddressV2(params ParamsV2) (interface{}, error) {
-	coinConfig, err := coinfactory.GetCoin(params.Coin)
+	coinConfig, err := getCoin(params.Coin)
 	if err != nil {
 		return nil, err
 	}
+	meta := models.AddressMeta{
+		Coin:      coinConfig.Info.Tag,
+		Service:   params.Service,
+		Label:     params.Label,
+		Reference: params.Reference,
+		RequestID: params.RequestID,
+	}
 	if coinConfig.Info.Token || coinConfig.Info.Tag == "ETH" {
-		ethConfig, err := coinfactory.GetCoin("ETH")
+		address, err := ethAddress(ethAccountTag(params.Service))
 		if err != nil {
 			return nil, err
 		}
-		if params.Service == "tyche" || params.Service == "ladon" {
-			ethConfig.Mnemonic = os.Getenv("MNEMONIC_" + coinV2)
-		}
-		var acc accounts.Account
-		acc, err = getEthAccFromMnemonicV2(ethConfig, false)
-		if err != nil {
+		meta.Address = address.Hex()
+		if err := recordAccountAddress(meta); err != nil {
 			return nil, err
 		}
-		return acc.Address.Hex(), nil
+		return meta.Address, nil
 	}
-	acc, err := getAccFromMnemonic(coinConfig, false)
-	if err != nil {
+	if err := c.availability.check(coinConfig); err != nil {
 		return nil, err
 	}
-	// Create a new xpub and derive the address from the hdwallet
-	directExtended, err := acc.Child(0)
-	if err != nil {
-		return nil, err
+	if wallet, ok := multisigWallet(coinConfig); ok {
+		meta.Address, meta.Path, err = nextMultisigAddress(coinConfig, wallet)
+		meta.Multisig = true
+		if err == nil {
+			c.trackAddress(coinConfig.Info.Tag, models.AddrInfo{Addr: meta.Address, Path: meta.Path, Multisig: true})
+		}
+	} else {
+		meta.Address, meta.Path, err = c.nextAddress(coinConfig)
 	}
-	addrExtPub, err := directExtended.Child(uint32(c.Address[coinConfig.Info.Tag].LastUsed + 1))
 	if err != nil {
 		return nil, err
 	}
-	addr, err := addrExtPub.Address(coinConfig.NetParams)
-	if err != nil {
+	if err := recordAddress(meta); err != nil {
 		return nil, err
 	}
-	newAddrInfo := AddrInfo{
-		LastUsed: c.Address[coinConfig.Info.Tag].LastUsed + 1,
-		AddrInfo: c.Address[coinConfig.Info.Tag].AddrInfo,
-	}
-	newAddrInfo.AddrInfo = append(newAddrInfo.AddrInfo, models.AddrInfo{
-		Addr: addr.String(), Path: c.Address[coinConfig.Info.Tag].LastUsed + 1,
-	})
-	c.Address[coinConfig.Info.Tag] = newAddrInfo
-	return addr.String(), nil
+	return meta.Address, nil
 }
 
-func (c *ControllerV2) SendToAddressV2(params ParamsV2) (interface{}, error) {
-	var SendToAddressData plutus.SendAddressBodyReq
-	err := json.Unmarshal(params.Body, &SendToAddressData)
+// GetAddressInfoV2 returns who requested an address issued by GetAddressV2 and when.
+func (c *ControllerV2) GetAddressInfoV2(params ParamsV2) (interface{}, error) {
+	coinConfig, err := getCoin(params.Coin)
 	if err != nil {
-		log.Println("ERROR::SendToAddressV2::Unmarshalling data", err, params.Body)
 		return nil, err
 	}
-	coinConfig, err := coinfactory.GetCoin(SendToAddressData.Coin)
-	if err != nil {
-		return "", err
-	}
-	var txid string
-	if coinConfig.Info.Token || coinConfig.Info.Tag == "ETH" {
-		txid, err = c.sendToAddressEthV2(SendToAddressData, coinConfig, params.Service)
-		if err != nil {
-			log.Println("ERROR::SendToAddressV2::sendToAddressEthV2", err, SendToAddressData)
-			return nil, err
-		}
-	} else {
-		txid, err = c.sendToAddress(SendToAddressData, coinConfig)
-		if err != nil {
-			log.Println("ERROR::SendToAddressV2::sendToAddress", err, SendToAddressData)
-			return nil, err
-		}
-	}
-	return txid, nil
+	return addressMeta(coinConfig, params.Address)
 }
 
-func (c *ControllerV2) sendToAddress(SendToAddressData plutus.SendAddressBodyReq, coinConfig *coins.Coin) (string, error) {
-	value, err := btcutil.NewAmount(SendToAddressData.Amount)
+// nextAddress derives the receive address following the last used and the last issued ones.
+func (c *ControllerV2) nextAddress(coinConfig *coins.Coin) (string, int, error) {
+	acc, err := getAccPub(coinConfig)
 	if err != nil {
-		return "", err
-	}
-	acc, err := getAccFromMnemonic(coinConfig, true)
-	if err != nil {
-		return "", err
+		return "", 0, err
 	}
-	accPub, err := acc.Neuter()
+	// Create a new xpub and derive the address from the hdwallet
+	directExtended, err := acc.Child(0)
 	if err != nil {
-		return "", err
+		return "", 0, err
 	}
-	blockBookWrap := blockbook.NewBlockBookWrapper(coinConfig.Info.Blockbook)
-	utxos, err := blockBookWrap.GetUtxo(accPub.String(), false)
+	path, err := reserveAddressIndex(coinConfig.Info.Tag, c.addrInfo(coinConfig.Info.Tag).LastUsed)
 	if err != nil {
-		return "", err
-	}
-	if len(utxos) == 0 {
-		return "", errors.New("no balance available")
+		return "", 0, err
 	}
-	var Tx wire.MsgTx
-	var txVersion int32
-	if coinConfig.Info.Tag == "POLIS" || coinConfig.Info.Tag == "DASH" || coinConfig.Info.Tag == "GRS" {
-		txVersion = 2
-	} else {
-		txVersion = 1
-	}
-	var availableAmount btcutil.Amount
-	var changeAddrPubKeyHash string
-	// Add the inputs without signatures
-	for i, utxo := range utxos {
-		if i == 0 {
-			changeAddrPubKeyHash = utxo.Address
-		}
-		intValue, err := strconv.ParseInt(utxo.Value, 10, 64)
-		if err != nil {
-			log.Println("ERROR::sendToAddress::ParseInt", utxo.Value)
-			return "", err
-		}
-		utxoAmount := btcutil.Amount(intValue)
-		availableAmount += utxoAmount
-		txidHash, err := chainhash.NewHashFromStr(utxo.Txid)
-		if err != nil {
-			log.Println("ERROR::sendToAddress::NewHashFromStr", utxo.Txid)
-			return "", err
-		}
-		prevOut := wire.NewOutPoint(txidHash, uint32(utxo.Vout))
-		in := wire.NewTxIn(prevOut, nil, nil)
-		Tx.AddTxIn(in)
-	}
-	// Retrieve information for outputs
-	payAddr, err := btcutil.DecodeAddress(SendToAddressData.Address, coinConfig.NetParams)
+	addrExtPub, err := directExtended.Child(uint32(path))
 	if err != nil {
-		log.Println("ERROR::sendToAddress::DecodeAddress", SendToAddressData.Address, " ", coinConfig.NetParams)
-		return "", err
+		return "", 0, err
 	}
-	changeAddr, err := btcutil.DecodeAddress(changeAddrPubKeyHash, coinConfig.NetParams)
+	addr, err := addrExtPub.Address(coinConfig.NetParams)
 	if err != nil {
-		log.Println("ERROR::sendToAddress::DecodeAddress::Change", changeAddrPubKeyHash, " ", coinConfig.NetParams)
-		return "", err
+		return "", 0, err
 	}
-	pkScriptPay, err := txscript.PayToAddrScript(payAddr)
+	c.trackAddress(coinConfig.Info.Tag, models.AddrInfo{Addr: addr.String(), Path: path, Chain: externalChain})
+	return addr.String(), path, nil
+}
+
+func (c *ControllerV2) SendToAddressV2(params ParamsV2) (interface{}, error) {
+	var SendToAddressData plutus.SendAddressBodyReq
+	log := logger.Default().WithRequestID(params.RequestID).With("service", params.Service)
+	err := json.Unmarshal(params.Body, &SendToAddressData)
 	if err != nil {
-		log.Println("ERROR::sendToAddress::PayToAddrScript", payAddr)
-		return "", err
+		log.Error("SendToAddressV2: unable to decode the request body", "err", err)
+		return nil, err
 	}
-	pkScriptChange, err := txscript.PayToAddrScript(changeAddr)
+	coinConfig, err := getCoin(SendToAddressData.Coin)
 	if err != nil {
-		log.Println("ERROR::sendToAddress::PayToAddrScript::Change", changeAddr)
 		return "", err
 	}
-	txOut := &wire.TxOut{
-		Value:    int64(value.ToUnit(btcutil.AmountSatoshi)),
-		PkScript: pkScriptPay,
+	if err := c.availability.check(coinConfig); err != nil {
+		metrics.ObserveSend(coinConfig.Info.Tag, err)
+		return nil, err
 	}
-	var fee blockbook.Fee
-	if SendToAddressData.Coin == "BTC" {
-		fee, err = blockBookWrap.GetFee("4")
-		if err != nil {
-			log.Println("ERROR::sendToAddress::GetFee")
-			return "", err
-		}
-	} else {
-		fee, err = blockBookWrap.GetFee("2")
-		if err != nil {
-			log.Println("ERROR::sendToAddress::GetFee")
-			return "", err
-		}
+	if watchOnly() {
+		metrics.ObserveSend(coinConfig.Info.Tag, errWatchOnly)
+		return nil, errWatchOnly
 	}
-	var feeRate int64
-	if fee.Result == "-1" || fee.Result == "0" || fee.Result == "" {
-		feeRate = 4000
-	} else {
-		feeParse, err := strconv.ParseFloat(fee.Result, 64)
-		if err != nil {
-			log.Println("ERROR::sendToAddress::ParseFloat", fee.Result)
-			return "", err
-		}
-		feeRate = int64(feeParse * 1e8)
-	}
-	txSize := (len(Tx.TxIn) * 180) + (len(Tx.TxOut) * 34) + 124
-	feeSats := float64(feeRate) / 1024.0 * float64(txSize)
-	payingFee := btcutil.Amount(int64(feeSats))
-	if availableAmount-payingFee-value > 0 {
-		txOutChange := &wire.TxOut{
-			Value:    int64(((availableAmount - value) - payingFee).ToUnit(btcutil.AmountSatoshi)),
-			PkScript: pkScriptChange,
-		}
-
-		Tx.AddTxOut(txOutChange)
+	var txid string
+	if coinConfig.Info.Token || coinConfig.Info.Tag == "ETH" {
+		txid, err = c.sendToAddressEthV2(SendToAddressData, coinConfig, sendOrigin{params.Service, params.RequestID})
 	} else {
-		txOut.Value = int64((value - payingFee).ToUnit(btcutil.AmountSatoshi))
-	}
-	Tx.AddTxOut(txOut)
-	Tx.Version = txVersion
-	// Create the signatures
-	for i, utxo := range utxos {
-		path := strings.Split(utxo.Path, "/")
-		pathParse, err := strconv.ParseInt(path[5], 10, 64)
-		if err != nil {
-			log.Println("ERROR::sendToAddress::ParseInt")
-			return "", err
-		}
-		privKey, err := getPrivKeyFromPath(acc, uint32(pathParse))
-		if err != nil {
-			log.Println("ERROR::sendToAddress::getPrivKeyFromPath::", acc)
-			return "", err
-		}
-		addr, err := btcutil.DecodeAddress(utxo.Address, coinConfig.NetParams)
-		if err != nil {
-			log.Println("ERROR::sendToAddress::DecodeAddress::utxo", utxo.Address)
-			return "", err
-		}
-		subscript, err := txscript.PayToAddrScript(addr)
-		if err != nil {
-			log.Println("ERROR::sendToAddress::PayToAddrScript::utxo", addr)
-			return "", err
-		}
-		var sigHash txscript.SigHashHasher
-		if coinConfig.Info.Tag == "GRS" {
-			sigHash = txscript.Sha256
-		} else {
-			sigHash = txscript.Sha256d
-		}
-		sigScript, err := txscript.SignatureScript(&Tx, i, subscript, txscript.SigHashAll, privKey, true, sigHash)
-		if err != nil {
-			log.Println("ERROR::sendToAddress::SignatureScript::utxo", Tx, privKey)
-			return "", err
-		}
-		Tx.TxIn[i].SignatureScript = sigScript
+		txid, err = c.sendToAddress(SendToAddressData, coinConfig, sendOrigin{params.Service, params.RequestID}, log)
 	}
-	buf := bytes.NewBuffer([]byte{})
-	err = Tx.BtcEncode(buf, 0, wire.BaseEncoding)
+	metrics.ObserveSend(coinConfig.Info.Tag, err)
 	if err != nil {
-		log.Println("ERROR::sendToAddress::BtcEncode::", buf)
-		return "", err
+		log.Error("SendToAddressV2: send failed", "coin", coinConfig.Info.Tag, "address", SendToAddressData.Address, "amount", SendToAddressData.Amount, "err", err)
+		return nil, err
 	}
-	rawTx := hex.EncodeToString(buf.Bytes())
-	log.Println("INFO:: RAW TX :: ", rawTx)
-	return blockBookWrap.SendTx(rawTx)
+	return txid, nil
+}
+
+func (c *ControllerV2) sendToAddress(SendToAddressData plutus.SendAddressBodyReq, coinConfig *coins.Coin, origin sendOrigin, log *logger.Logger) (string, error) {
+	return sendPayment(coinConfig, SendToAddressData.Address, SendToAddressData.Amount, origin, log)
 }
 
-func (c *ControllerV2) sendToAddressEthV2(SendToAddressData plutus.SendAddressBodyReq, coinConfig *coins.Coin, service string) (string, error) {
+func (c *ControllerV2) sendToAddressEthV2(SendToAddressData plutus.SendAddressBodyReq, coinConfig *coins.Coin, origin sendOrigin) (string, error) {
 	// using the ethereum account to hl the tokens
-	ethConfig, err := coinfactory.GetCoin("ETH")
+	ethConfig, err := getCoin("ETH")
 	if err != nil {
 		return "", err
 	}
 	//**get the account that holds the private keys and addresses
-	if service == "tyche" || service == "ladon" {
-		ethConfig.Mnemonic = os.Getenv("MNEMONIC_" + coinV2)
-	}
-	account, err := getEthAccFromMnemonicV2(ethConfig, true)
+	address, err := ethAddress(ethAccountTag(origin.service))
 	if err != nil {
 		return "", err
 	}
-	ethAccount := account.Address.Hex()
+	ethAccount := address.Hex()
 
 	blockBookWrap := blockbook.NewBlockBookWrapper(ethConfig.Info.Blockbook)
 
 	//** get the balance, check if its > 0 or less than the amount
+	start := time.Now()
 	info, err := blockBookWrap.GetEthAddress(ethAccount)
+	metrics.ObserveBlockbook(ethConfig.Info.Tag, "eth_address", start, err)
 	if err != nil {
 		return "", err
 	}
@@ -420,16 +331,11 @@ func (c *ControllerV2) sendToAddressEthV2(SendToAddressData plutus.SendAddressBo
 	//** Retrieve information for outputs: out address
 	toAddress := common.HexToAddress(SendToAddressData.Address)
 	//**calculate fee/gas cost
-	gasLimit := uint64(21000)
-	if coinConfig.Info.Tag != "ETH" {
-		gasLimit = uint64(200000)
-	}
-	var gasStation GasStation
-	err = getJSON("https://ethgasstation.info/json/ethgasAPI.json", &gasStation)
+	gasLimit := ethGasLimit(coinConfig)
+	gasPrice, err := getGasPrice()
 	if err != nil {
-		return "", errors.New("could not retrieve the gas price")
+		return "", err
 	}
-	gasPrice := big.NewInt(int64(1000000000 * (gasStation.Average / 10))) //(10^9*(gweiValue/10))
 	var data []byte
 	var tx *types.Transaction
 
@@ -462,14 +368,28 @@ func (c *ControllerV2) sendToAddressEthV2(SendToAddressData plutus.SendAddressBo
 		tx = types.NewTransaction(nonce, toAddress, value, gasLimit, gasPrice, data)
 	}
 	// **sign and send
-	signedTx, err := signEthTxV2(ethConfig, account, tx, nil)
+	signedTx, err := txSigner.SignEthTx(ethAccountTag(origin.service), tx, nil)
 	if err != nil {
 		return "", errors.New("failed to sign transaction")
 	}
 	ts := types.Transactions{signedTx}
 	rawTxBytes := ts.GetRlp(0)
 	rawTxHex := hex.EncodeToString(rawTxBytes)
-	return blockBookWrap.SendTx("0x" + rawTxHex)
+	start = time.Now()
+	txid, err := blockBookWrap.SendTx("0x" + rawTxHex)
+	metrics.ObserveBlockbook(ethConfig.Info.Tag, "send_tx", start, err)
+	if err != nil {
+		return "", err
+	}
+	metrics.FeePaid.WithLabelValues(ethConfig.Info.Tag).Add(gasFee(gasPrice, gasLimit))
+	journalSend(models.SendJournalEntry{
+		Coin:    coinConfig.Info.Tag,
+		Txid:    txid,
+		Address: SendToAddressData.Address,
+		Amount:  SendToAddressData.Amount,
+		Fee:     gasFee(gasPrice, gasLimit),
+	}, origin, logger.Default().WithRequestID(origin.requestID).With("service", origin.service))
+	return txid, nil
 	//return "", nil
 }
 
@@ -479,26 +399,22 @@ func (c *ControllerV2) ValidateAddressV2(params ParamsV2) (interface{}, error) {
 	if err != nil {
 		return nil, err
 	}
-	coinConfig, err := coinfactory.GetCoin(ValidateAddressData.Coin)
+	coinConfig, err := getCoin(ValidateAddressData.Coin)
 	if err != nil {
 		return nil, err
 	}
 	if coinConfig.Info.Token || coinConfig.Info.Tag == "ETH" {
-		coinConfig, err = coinfactory.GetCoin("ETH")
-		if err != nil {
-			return nil, err
-		}
-		if params.Service == "tyche" || params.Service == "ladon" {
-			coinConfig.Mnemonic = os.Getenv("MNEMONIC_" + coinV2)
-		}
-		acc, err := getEthAccFromMnemonicV2(coinConfig, false)
+		address, err := ethAddress(ethAccountTag(params.Service))
 		if err != nil {
 			return nil, err
 		}
-		return reflect.DeepEqual(ValidateAddressData.Address, acc.Address.Hex()), nil
+		return reflect.DeepEqual(ValidateAddressData.Address, address.Hex()), nil
+	}
+	if err := c.availability.check(coinConfig); err != nil {
+		return nil, err
 	}
 	var isMine bool
-	for _, addr := range c.Address[coinConfig.Info.Tag].AddrInfo {
+	for _, addr := range c.addrInfo(coinConfig.Info.Tag).AddrInfo {
 		if addr.Addr == ValidateAddressData.Address {
 			isMine = true
 		}
@@ -512,48 +428,32 @@ func (c *ControllerV2) ValidateRawTxV2(params ParamsV2) (interface{}, error) {
 	if err != nil {
 		return nil, err
 	}
-	coinConfig, err := coinfactory.GetCoin(ValidateTxData.Coin)
+	coinConfig, err := getCoin(ValidateTxData.Coin)
 	if err != nil {
 		return nil, err
 	}
+	if err := c.availability.check(coinConfig); err != nil {
+		return nil, err
+	}
 
 	var isValue, isAddress bool
 
 	//ethereum-like coins (and ERC20)
 	if coinConfig.Info.Token || coinConfig.Info.Tag == "ETH" {
-		value := ValidateTxData.Amount
-		if (params.Service == "ladon" || params.Service == "tyche") && coinConfig.Info.Tag != "ETH" {
-			//remove the 1e8
-			valueNoSatoshi := float64(value) / 1e8
-			value = decimalToToken(valueNoSatoshi, coinConfig.Info.Decimals).Int64()
-		} else if (params.Service == "ladon" || params.Service == "tyche") && coinConfig.Info.Tag == "ETH" {
-			value = value * 1e10
-		}
-		var tx *types.Transaction
-		if ValidateTxData.RawTx[0:2] == "0x" {
-			ValidateTxData.RawTx = ValidateTxData.RawTx[2:]
-		}
-		//if ValidateTxData.RawTx[]
-		rawtx, err := hex.DecodeString(ValidateTxData.RawTx)
+		value := evmAmount(coinConfig, params.Service, ValidateTxData.Amount)
+		tx, err := decodeEthTx(ValidateTxData.RawTx)
 		if err != nil {
 			return nil, err
 		}
-		err = rlp.DecodeBytes(rawtx, &tx)
+		txAddr, txBodyAmount, ok, err := ethPayment(coinConfig, tx)
 		if err != nil {
 			return nil, err
 		}
-		//compare amount from the tx and the input body
-		var txBodyAmount int64
-		var txAddr common.Address
-		if coinConfig.Info.Token && coinConfig.Info.Tag != "ETH" {
-			address, amount := DecodeERC20Data([]byte(hex.EncodeToString(tx.Data())))
-			txAddr = common.HexToAddress(string(address))
-			txBodyAmount = amount.Int64()
-		} else {
-			txBodyAmount = tx.Value().Int64()
-			txAddr = *tx.To()
+		if !ok {
+			return false, nil
 		}
-		if txBodyAmount == value {
+		//compare amount from the tx and the input body
+		if txBodyAmount.Cmp(big.NewInt(value)) == 0 {
 			isValue = true
 		}
 		bodyAddr := common.HexToAddress(ValidateTxData.Address)
@@ -575,11 +475,9 @@ func (c *ControllerV2) ValidateRawTxV2(params ParamsV2) (interface{}, error) {
 			return nil, err
 		}
 		for _, out := range tx.MsgTx().TxOut {
-			outAmount := btcutil.Amount(out.Value)
-			if outAmount == value {
-				isValue = true
-			}
-			for _, addr := range c.Address[coinConfig.Info.Tag].AddrInfo {
+			// the amount must be paid to us on a single output
+			var isMine bool
+			for _, addr := range c.addrInfo(coinConfig.Info.Tag).AddrInfo {
 				Addr, err := btcutil.DecodeAddress(addr.Addr, coinConfig.NetParams)
 				if err != nil {
 					return nil, err
@@ -589,7 +487,13 @@ func (c *ControllerV2) ValidateRawTxV2(params ParamsV2) (interface{}, error) {
 					return nil, err
 				}
 				if bytes.Equal(scriptAddr, out.PkScript) {
-					isAddress = true
+					isMine = true
+				}
+			}
+			if isMine {
+				isAddress = true
+				if btcutil.Amount(out.Value) == value {
+					isValue = true
 				}
 			}
 		}
@@ -602,78 +506,113 @@ func (c *ControllerV2) ValidateRawTxV2(params ParamsV2) (interface{}, error) {
 	}
 }
 
-func getEthAccFromMnemonicV2(coinConfig *coins.Coin, saveWallet bool) (accounts.Account, error) {
-	if coinConfig.Mnemonic == "" {
-		return accounts.Account{}, errors.New("the coin is not available")
-	}
-	wallet, err := hdwallet.NewFromMnemonic(coinConfig.Mnemonic)
+// ValidateRawTxReportV2 returns the detailed validation of a raw transaction.
+func (c *ControllerV2) ValidateRawTxReportV2(params ParamsV2) (interface{}, error) {
+	var ValidateTxData plutus.ValidateRawTxReq
+	err := json.Unmarshal(params.Body, &ValidateTxData)
 	if err != nil {
-		return accounts.Account{}, err
+		return nil, err
 	}
-	// standard for eth wallets like Metamask
-	path := hdwallet.MustParseDerivationPath("m/44'/60'/0'/0/0")
-	account, err := wallet.Derive(path, true)
+	coinConfig, err := getCoin(ValidateTxData.Coin)
 	if err != nil {
-		return accounts.Account{}, err
+		return nil, err
 	}
-	if saveWallet {
-		ethWalletV2 = wallet
+	if err := c.availability.check(coinConfig); err != nil {
+		return nil, err
+	}
+	amount := ValidateTxData.Amount
+	if coinConfig.Info.Token || coinConfig.Info.Tag == "ETH" {
+		amount = evmAmount(coinConfig, params.Service, amount)
 	}
-	return account, nil
+	return rawTxReport(coinConfig, ValidateTxData.RawTx, ValidateTxData.Address, amount, c.addrInfo(coinConfig.Info.Tag))
 }
 
-func signEthTxV2(coinConfig *coins.Coin, account accounts.Account, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
-	if coinConfig.Mnemonic == "" {
-		return nil, errors.New("the coin is not available")
+// evmAmount converts the satoshis sent by ladon and tyche to wei or token units.
+func evmAmount(coinConfig *coins.Coin, service string, value int64) int64 {
+	if service != "ladon" && service != "tyche" {
+		return value
 	}
-	signedTx, err := ethWalletV2.SignTx(account, tx, chainID)
-	if err != nil {
-		return nil, err
+	if coinConfig.Info.Tag == "ETH" {
+		return value * 1e10
 	}
-	return signedTx, nil
+	//remove the 1e8
+	valueNoSatoshi := float64(value) / 1e8
+	return decimalToToken(valueNoSatoshi, coinConfig.Info.Decimals).Int64()
 }
 
 func (c *ControllerV2) getAddrs(coinConfig *coins.Coin) error {
-	acc, err := getAccFromMnemonic(coinConfig, false)
+	info, err := discoverAddrs(coinConfig)
 	if err != nil {
 		return err
 	}
-	blockBookWrap := blockbook.NewBlockBookWrapper(coinConfig.Info.Blockbook)
-	info, err := blockBookWrap.GetXpub(acc.String())
+	c.mu.Lock()
+	c.Address[coinConfig.Info.Tag] = info
+	c.mu.Unlock()
+	markSynced(coinConfig.Info.Tag)
+	return nil
+}
+
+// RescanV2 runs the address discovery of a coin again and replaces its known addresses.
+func (c *ControllerV2) RescanV2(params ParamsV2) (interface{}, error) {
+	coinConfig, err := getCoin(params.Coin)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	var addrInfoSlice []models.AddrInfo
-	for i := info.UsedTokens; i < info.UsedTokens+addrGap; i++ {
-		addr, err := getPubKeyHashFromPath(acc, coinConfig, uint32(i))
-		if err != nil {
-			return err
-		}
-		addrInfo := models.AddrInfo{Addr: addr, Path: i}
-		addrInfoSlice = append(addrInfoSlice, addrInfo)
+	if !syncsAddresses(coinConfig) {
+		return nil, errors.New("the coin has no addresses to rescan")
 	}
-	c.Address[coinConfig.Info.Tag] = AddrInfo{
-		LastUsed: info.UsedTokens,
-		AddrInfo: addrInfoSlice,
+	if err := c.getAddrs(coinConfig); err != nil {
+		return nil, err
 	}
-	return nil
+	c.availability.markAvailable(coinConfig.Info.Tag)
+	return rescanResponse(coinConfig, c.addrInfo(coinConfig.Info.Tag)), nil
+}
+
+// trackAddress adds an issued address to the known addresses of the coin, once. A receive
+// address moves the last used index of the coin.
+func (c *ControllerV2) trackAddress(tag string, addr models.AddrInfo) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	info := c.Address[tag]
+	if !addr.Multisig && addr.Chain == externalChain && addr.Path > info.LastUsed {
+		info.LastUsed = addr.Path
+	}
+	known := false
+	for _, knownAddr := range info.AddrInfo {
+		known = known || knownAddr.Addr == addr.Addr
+	}
+	if !known {
+		info.AddrInfo = append(info.AddrInfo, addr)
+	}
+	c.Address[tag] = info
+}
+
+func (c *ControllerV2) addrInfo(tag string) AddrInfo {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.Address[tag]
+}
+
+// Unavailable returns the coins that failed to sync together with the reason.
+func (c *ControllerV2) Unavailable() map[string]string {
+	return c.availability.list()
 }
 
 func NewPlutusControllerV2() *ControllerV2 {
 	ctrl := &ControllerV2{
-		Address: make(map[string]AddrInfo),
+		Address:      make(map[string]AddrInfo),
+		availability: newCoinAvailability(),
 	}
+	warmKeyCache()
 	// Here we handle only active coins
 	for tag := range coinfactory.Coins {
-		coin, err := coinfactory.GetCoin(tag)
+		coin, err := getCoin(tag)
 		if err != nil {
-			panic(err)
+			logger.Error("NewPlutusControllerV2: unable to load the coin", "coin", tag, "err", err)
+			continue
 		}
-		if !coin.Info.Token && coin.Info.Tag != "ETH" && coin.Info.Tag != "XSG" && coin.Info.Tag != "DAPS" {
-			err := ctrl.getAddrs(coin)
-			if err != nil {
-				panic(errors.New(err.Error() + " " + coin.Info.Tag))
-			}
+		if syncsAddresses(coin) {
+			ctrl.availability.syncCoin(coin, ctrl.getAddrs)
 		}
 	}
 	return ctrl
@@ -0,0 +1,427 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/eabz/btcutil"
+	"github.com/eabz/btcutil/hdkeychain"
+	"github.com/eabz/btcutil/txscript"
+	"github.com/grupokindynos/common/coin-factory/coins"
+	"github.com/grupokindynos/common/plutus"
+	"github.com/grupokindynos/plutus/logger"
+	"github.com/grupokindynos/plutus/metrics"
+	"github.com/grupokindynos/plutus/models"
+	"github.com/grupokindynos/plutus/psbt"
+	"github.com/martinboehm/btcd/wire"
+)
+
+var errNoUtxoCoin = errors.New("psbt are only available for utxo coins")
+
+// CreatePSBTV2 builds the same transaction as SendToAddressV2 and returns it unsigned, with the
+// previous transactions and the key origins of the inputs, for review or external signing.
+func (c *ControllerV2) CreatePSBTV2(params ParamsV2) (interface{}, error) {
+	var SendToAddressData plutus.SendAddressBodyReq
+	log := logger.Default().WithRequestID(params.RequestID).With("service", params.Service)
+	err := json.Unmarshal(params.Body, &SendToAddressData)
+	if err != nil {
+		return nil, err
+	}
+	coinConfig, err := c.psbtCoin(SendToAddressData.Coin)
+	if err != nil {
+		return nil, err
+	}
+	p, err := buildPayment(coinConfig, SendToAddressData.Address, SendToAddressData.Amount, log)
+	if err != nil {
+		return nil, err
+	}
+	packet, err := newPSBT(coinConfig, p)
+	if err != nil {
+		log.Error("CreatePSBTV2: unable to create the psbt", "coin", coinConfig.Info.Tag, "err", err)
+		return nil, err
+	}
+	return psbtResponse(packet, 0)
+}
+
+// SignPSBTV2 adds the signatures of the wallet keys to every input spending an output of the
+// wallet. Inputs of other keys are left untouched.
+func (c *ControllerV2) SignPSBTV2(params ParamsV2) (interface{}, error) {
+	var PSBTData models.PSBTBodyReq
+	log := logger.Default().WithRequestID(params.RequestID).With("service", params.Service)
+	err := json.Unmarshal(params.Body, &PSBTData)
+	if err != nil {
+		return nil, err
+	}
+	if watchOnly() {
+		return nil, errWatchOnly
+	}
+	coinConfig, err := c.psbtCoin(PSBTData.Coin)
+	if err != nil {
+		return nil, err
+	}
+	packet, err := psbt.Decode(PSBTData.PSBT)
+	if err != nil {
+		return nil, err
+	}
+	signed, err := signPSBT(coinConfig, packet)
+	if err != nil {
+		log.Error("SignPSBTV2: unable to sign the psbt", "coin", coinConfig.Info.Tag, "err", err)
+		return nil, err
+	}
+	return psbtResponse(packet, signed)
+}
+
+// FinalizePSBTV2 builds the final scripts of the signed inputs and, once every input is
+// finalized and broadcast is requested, sends the transaction.
+func (c *ControllerV2) FinalizePSBTV2(params ParamsV2) (interface{}, error) {
+	var PSBTData models.PSBTBodyReq
+	log := logger.Default().WithRequestID(params.RequestID).With("service", params.Service)
+	err := json.Unmarshal(params.Body, &PSBTData)
+	if err != nil {
+		return nil, err
+	}
+	coinConfig, err := c.psbtCoin(PSBTData.Coin)
+	if err != nil {
+		return nil, err
+	}
+	packet, err := psbt.Decode(PSBTData.PSBT)
+	if err != nil {
+		return nil, err
+	}
+	if err := finalizePSBT(coinConfig, packet); err != nil {
+		return nil, err
+	}
+	response, err := psbtResponse(packet, 0)
+	if err != nil {
+		return nil, err
+	}
+	if !response.Complete {
+		return response, nil
+	}
+	tx, err := packet.Extract()
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := tx.BtcEncode(&buf, 0, wire.WitnessEncoding); err != nil {
+		return nil, err
+	}
+	response.RawTx = hex.EncodeToString(buf.Bytes())
+	if !PSBTData.Broadcast {
+		return response, nil
+	}
+	response.Txid, err = broadcastTx(coinConfig, tx, log)
+	metrics.ObserveSend(coinConfig.Info.Tag, err)
+	if err != nil {
+		log.Error("FinalizePSBTV2: broadcast failed", "coin", coinConfig.Info.Tag, "err", err)
+		return nil, err
+	}
+	metrics.FeePaid.WithLabelValues(coinConfig.Info.Tag).Add(response.Fee)
+	journalSend(psbtJournalEntry(coinConfig, packet, response.Txid, response.Fee), sendOrigin{params.Service, params.RequestID}, log)
+	return response, nil
+}
+
+func (c *ControllerV2) psbtCoin(tag string) (*coins.Coin, error) {
+	coinConfig, err := getCoin(tag)
+	if err != nil {
+		return nil, err
+	}
+	if coinConfig.Info.Token || coinConfig.Info.Tag == "ETH" {
+		return nil, errNoUtxoCoin
+	}
+	if err := c.availability.check(coinConfig); err != nil {
+		return nil, err
+	}
+	return coinConfig, nil
+}
+
+func psbtResponse(packet *psbt.Packet, signed int) (*models.PSBTResponse, error) {
+	encoded, err := packet.Encode()
+	if err != nil {
+		return nil, err
+	}
+	fee, err := packet.Fee()
+	if err != nil {
+		return nil, err
+	}
+	return &models.PSBTResponse{
+		PSBT:     encoded,
+		Fee:      btcutil.Amount(fee).ToBTC(),
+		Signed:   signed,
+		Complete: packet.Complete(),
+	}, nil
+}
+
+// psbtJournalEntry returns the journal entry of a broadcast packet. The destination is the
+// first output without key origins, the change outputs carry ours.
+func psbtJournalEntry(coinConfig *coins.Coin, packet *psbt.Packet, txid string, fee float64) models.SendJournalEntry {
+	entry := models.SendJournalEntry{
+		Coin: coinConfig.Info.Tag,
+		Txid: txid,
+		Fee:  fee,
+	}
+	for i, out := range packet.UnsignedTx.TxOut {
+		if len(packet.Outputs[i].Bip32Derivation) != 0 && i < len(packet.UnsignedTx.TxOut)-1 {
+			continue
+		}
+		entry.Address = outputAddress(coinConfig, out.PkScript)
+		entry.Amount = btcutil.Amount(out.Value).ToBTC()
+		break
+	}
+	for i, in := range packet.UnsignedTx.TxIn {
+		input := models.JournalInput{
+			Txid: in.PreviousOutPoint.Hash.String(),
+			Vout: in.PreviousOutPoint.Index,
+		}
+		if spent, err := packet.SpentOutput(i); err == nil {
+			input.Value = spent.Value
+			input.Address = outputAddress(coinConfig, spent.PkScript)
+		}
+		if derivations := packet.Inputs[i].Bip32Derivation; len(derivations) != 0 && len(derivations[0].Path) != 0 {
+			input.Path = derivations[0].Path[len(derivations[0].Path)-1]
+		}
+		entry.Inputs = append(entry.Inputs, input)
+	}
+	return entry
+}
+
+// newPSBT returns the unsigned packet of a payment.
+func newPSBT(coinConfig *coins.Coin, p *payment) (*psbt.Packet, error) {
+	packet, err := psbt.New(p.tx)
+	if err != nil {
+		return nil, err
+	}
+	accPub, err := getAccPub(coinConfig)
+	if err != nil {
+		return nil, err
+	}
+	fingerprint, err := getMasterFingerprint(coinConfig)
+	if err != nil {
+		return nil, err
+	}
+	for i, input := range p.inputs {
+		prevTx, err := getPrevTx(coinConfig, input.txid)
+		if err != nil {
+			return nil, err
+		}
+		pubKey, err := getPubKeyFromPath(accPub, input.index)
+		if err != nil {
+			return nil, err
+		}
+		derivation := psbt.Bip32Derivation{
+			PubKey:      pubKey,
+			Fingerprint: fingerprint,
+			Path:        accountPath(coinConfig, input.index),
+		}
+		packet.Inputs[i].NonWitnessUtxo = prevTx
+		packet.Inputs[i].SighashType = uint32(txscript.SigHashAll)
+		packet.Inputs[i].Bip32Derivation = []psbt.Bip32Derivation{derivation}
+		// Let the signers recognize the change output.
+		for j, out := range p.tx.TxOut {
+			if bytes.Equal(out.PkScript, input.script) && len(packet.Outputs[j].Bip32Derivation) == 0 {
+				packet.Outputs[j].Bip32Derivation = []psbt.Bip32Derivation{derivation}
+			}
+		}
+	}
+	return packet, nil
+}
+
+// signPSBT signs the inputs spending an output of the wallet and returns how many it signed.
+func signPSBT(coinConfig *coins.Coin, packet *psbt.Packet) (int, error) {
+	if _, err := packet.Fee(); err != nil {
+		return 0, err
+	}
+	accPub, err := getAccPub(coinConfig)
+	if err != nil {
+		return 0, err
+	}
+	fingerprint, err := getMasterFingerprint(coinConfig)
+	if err != nil {
+		return 0, err
+	}
+	var signed int
+	for i := range packet.Inputs {
+		in := &packet.Inputs[i]
+		if packet.Finalized(i) {
+			continue
+		}
+		index, pubKey, ok := walletKey(coinConfig, accPub, fingerprint, in.Bip32Derivation)
+		if !ok {
+			continue
+		}
+		if in.SighashType != 0 && in.SighashType != uint32(txscript.SigHashAll) {
+			return signed, errors.New("input " + strconv.Itoa(i) + " requests an unsupported sighash type")
+		}
+		if in.NonWitnessUtxo == nil {
+			return signed, errors.New("input " + strconv.Itoa(i) + " is missing the previous transaction")
+		}
+		spent, err := packet.SpentOutput(i)
+		if err != nil {
+			return signed, err
+		}
+		script, err := p2pkhScript(coinConfig, pubKey)
+		if err != nil {
+			return signed, err
+		}
+		if !bytes.Equal(spent.PkScript, script) {
+			return signed, errors.New("input " + strconv.Itoa(i) + " doesn't spend the address of its key")
+		}
+		digest, err := sigHash(packet.UnsignedTx, i, spent.PkScript, coinConfig.Info.Tag == "GRS")
+		if err != nil {
+			return signed, err
+		}
+		signature, sigPubKey, err := txSigner.SignDigest(coinConfig.Info.Tag, index, digest)
+		if err != nil {
+			return signed, err
+		}
+		if !bytes.Equal(sigPubKey, pubKey) {
+			return signed, errors.New("the signer used another key for input " + strconv.Itoa(i))
+		}
+		if err := packet.AddPartialSig(i, pubKey, append(signature, byte(txscript.SigHashAll))); err != nil {
+			return signed, err
+		}
+		signed++
+	}
+	if signed == 0 {
+		return 0, errors.New("no input of the psbt spends an output of the wallet")
+	}
+	return signed, nil
+}
+
+// finalizePSBT builds the final scripts of the inputs that have the signatures they need.
+func finalizePSBT(coinConfig *coins.Coin, packet *psbt.Packet) error {
+	for i := range packet.Inputs {
+		if packet.Finalized(i) {
+			continue
+		}
+		spent, err := packet.SpentOutput(i)
+		if err != nil {
+			return err
+		}
+		for _, sig := range packet.Inputs[i].PartialSigs {
+			script, err := p2pkhScript(coinConfig, sig.PubKey)
+			if err != nil {
+				return err
+			}
+			if !bytes.Equal(spent.PkScript, script) {
+				continue
+			}
+			digest, err := sigHash(packet.UnsignedTx, i, spent.PkScript, coinConfig.Info.Tag == "GRS")
+			if err != nil {
+				return err
+			}
+			if err := verifySignature(digest, sig); err != nil {
+				return errors.New("the signature of input " + strconv.Itoa(i) + " is not valid: " + err.Error())
+			}
+			sigScript, err := txscript.NewScriptBuilder().AddData(sig.Signature).AddData(sig.PubKey).Script()
+			if err != nil {
+				return err
+			}
+			if err := packet.SetFinal(i, sigScript, nil); err != nil {
+				return err
+			}
+			break
+		}
+	}
+	return nil
+}
+
+// walletKey returns the receive index and the public key of the derivation that belongs to
+// the account of the coin, if any.
+func walletKey(coinConfig *coins.Coin, accPub *hdkeychain.ExtendedKey, fingerprint uint32, derivations []psbt.Bip32Derivation) (uint32, []byte, bool) {
+	for _, derivation := range derivations {
+		if derivation.Fingerprint != fingerprint || len(derivation.Path) != 5 {
+			continue
+		}
+		index := derivation.Path[4]
+		expected := accountPath(coinConfig, index)
+		if !equalPath(derivation.Path, expected) {
+			continue
+		}
+		pubKey, err := getPubKeyFromPath(accPub, index)
+		if err != nil || !bytes.Equal(pubKey, derivation.PubKey) {
+			continue
+		}
+		return index, pubKey, true
+	}
+	return 0, nil, false
+}
+
+// accountPath returns the BIP44 path of the address at index of the receive chain.
+func accountPath(coinConfig *coins.Coin, index uint32) []uint32 {
+	return []uint32{
+		hdkeychain.HardenedKeyStart + 44,
+		hdkeychain.HardenedKeyStart + coinConfig.NetParams.HDCoinType,
+		hdkeychain.HardenedKeyStart + 0,
+		0,
+		index,
+	}
+}
+
+func equalPath(a []uint32, b []uint32) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func p2pkhScript(coinConfig *coins.Coin, pubKey []byte) ([]byte, error) {
+	addr, err := btcutil.NewAddressPubKeyHash(btcutil.Hash160(pubKey), coinConfig.NetParams)
+	if err != nil {
+		return nil, err
+	}
+	return txscript.PayToAddrScript(addr)
+}
+
+func getPubKeyFromPath(acc *hdkeychain.ExtendedKey, path uint32) ([]byte, error) {
+	directExtended, err := acc.Child(0)
+	if err != nil {
+		return nil, err
+	}
+	addrExtPub, err := directExtended.Child(path)
+	if err != nil {
+		return nil, err
+	}
+	pubKey, err := addrExtPub.ECPubKey()
+	if err != nil {
+		return nil, err
+	}
+	return pubKey.SerializeCompressed(), nil
+}
+
+type blockbookTx struct {
+	Hex string `json:"hex"`
+}
+
+// getPrevTx returns the transaction with txid from the coin backend.
+func getPrevTx(coinConfig *coins.Coin, txid string) (*wire.MsgTx, error) {
+	var res blockbookTx
+	start := time.Now()
+	err := getJSON(strings.TrimRight(coinConfig.Info.Blockbook, "/")+"/api/v2/tx/"+txid, &res)
+	metrics.ObserveBlockbook(coinConfig.Info.Tag, "tx", start, err)
+	if err != nil {
+		return nil, err
+	}
+	rawTx, err := hex.DecodeString(res.Hex)
+	if err != nil {
+		return nil, err
+	}
+	tx := new(wire.MsgTx)
+	if err := tx.Deserialize(bytes.NewReader(rawTx)); err != nil {
+		return nil, err
+	}
+	if tx.TxHash().String() != txid {
+		return nil, errors.New("the backend returned another transaction for " + txid)
+	}
+	return tx, nil
+}
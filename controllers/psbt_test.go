@@ -0,0 +1,84 @@
+package controllers
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/eabz/btcutil/txscript"
+	"github.com/grupokindynos/plutus/psbt"
+	"github.com/martinboehm/btcd/wire"
+)
+
+func TestSignAndFinalizePSBT(t *testing.T) {
+	defaultSigner := txSigner
+	defer SetSigner(defaultSigner)
+	for _, test := range testXpup {
+		_, privKey := useTestKey(t, test)
+		subscript := testAddrScript(t, test)
+		prevTx, tx := testPrevTx(200000, subscript)
+		tx.AddTxOut(wire.NewTxOut(150000, subscript))
+
+		packet, err := psbt.New(tx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		packet.Inputs[0].NonWitnessUtxo = prevTx
+		if _, err := signPSBT(test.coin, packet); err == nil {
+			t.Error("a psbt without key origins must not be signed for " + test.coin.Info.Tag)
+		}
+		accPub, err := getAccFromMnemonic(test.coin, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pubKey, err := getPubKeyFromPath(accPub, test.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fingerprint, err := keys.masterFingerprint(test.coin)
+		if err != nil {
+			t.Fatal(err)
+		}
+		packet.Inputs[0].Bip32Derivation = []psbt.Bip32Derivation{{
+			PubKey:      pubKey,
+			Fingerprint: fingerprint,
+			Path:        accountPath(test.coin, test.path),
+		}}
+		signed, err := signPSBT(test.coin, packet)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if signed != 1 || len(packet.Inputs[0].PartialSigs) != 1 {
+			t.Error("the input of the wallet was not signed for " + test.coin.Info.Tag)
+		}
+		signature := packet.Inputs[0].PartialSigs[0].Signature
+		tampered := append([]byte{}, signature...)
+		tampered[len(tampered)-2] ^= 0x01
+		packet.Inputs[0].PartialSigs[0].Signature = tampered
+		if err := finalizePSBT(test.coin, packet); err == nil || packet.Finalized(0) {
+			t.Error("an invalid signature must not be finalized for " + test.coin.Info.Tag)
+		}
+		packet.Inputs[0].PartialSigs[0].Signature = signature
+		if err := finalizePSBT(test.coin, packet); err != nil {
+			t.Fatal(err)
+		}
+		entry := psbtJournalEntry(test.coin, packet, tx.TxHash().String(), 0.0005)
+		if entry.Address != test.addr || entry.Amount != 0.0015 || len(entry.Inputs) != 1 || entry.Inputs[0].Value != 200000 || entry.Inputs[0].Path != test.path {
+			t.Error("unexpected journal entry for " + test.coin.Info.Tag)
+		}
+		finalTx, err := packet.Extract()
+		if err != nil {
+			t.Fatal(err)
+		}
+		hasher := txscript.Sha256d
+		if test.coin.Info.Tag == "GRS" {
+			hasher = txscript.Sha256
+		}
+		expected, err := txscript.SignatureScript(tx, 0, subscript, txscript.SigHashAll, privKey, true, hasher)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(finalTx.TxIn[0].SignatureScript, expected) {
+			t.Error("signature script doesn't match for " + test.coin.Info.Tag)
+		}
+	}
+}
@@ -0,0 +1,218 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eabz/btcutil"
+	"github.com/eabz/btcutil/chaincfg"
+	"github.com/eabz/btcutil/txscript"
+	"github.com/grupokindynos/common/coin-factory/coins"
+	"github.com/grupokindynos/plutus/logger"
+	"github.com/grupokindynos/plutus/metrics"
+	"github.com/grupokindynos/plutus/models"
+)
+
+// defaultRBFCoins are the coins whose nodes accept BIP125 replacements when RBF_COINS is unset.
+const defaultRBFCoins = "BTC,LTC"
+
+// minFeeRateIncrement is the minimum increase of the fee rate, in satoshis per kB, relayed
+// by the nodes for a replacement.
+const minFeeRateIncrement = 1000
+
+var (
+	errNoRBF          = errors.New("the coin doesn't accept replacements, use a child transaction to accelerate it")
+	errUnknownSend    = errors.New("the transaction was not sent by plutus")
+	errSendConfirmed  = errors.New("the transaction is already confirmed")
+	errNoChange       = errors.New("the transaction has no change to pay a higher fee without reducing the amount")
+	errNoJournalInput = errors.New("the journal doesn't hold the inputs of the transaction")
+	errSendSpent      = errors.New("an output of the transaction is already spent, replacing it would evict the spending transactions")
+	errOtherService   = errors.New("the transaction was sent for another service")
+)
+
+// bumpMu serializes the replacements and the children accelerating transactions, a send can
+// only be replaced once.
+var bumpMu sync.Mutex
+
+// replaceable reports whether the nodes of the coin accept BIP125 replacements, RBF_COINS
+// lists them separated by commas.
+func replaceable(coinConfig *coins.Coin) bool {
+	rbfCoins := os.Getenv("RBF_COINS")
+	if rbfCoins == "" {
+		rbfCoins = defaultRBFCoins
+	}
+	for _, tag := range strings.Split(rbfCoins, ",") {
+		if strings.EqualFold(strings.TrimSpace(tag), coinConfig.Info.Tag) {
+			return true
+		}
+	}
+	return false
+}
+
+type blockbookTxStatus struct {
+	Confirmations int `json:"confirmations"`
+	Vout          []struct {
+		Spent bool `json:"spent"`
+	} `json:"vout"`
+	// Error is set when the backend doesn't know the transaction.
+	Error string `json:"error"`
+}
+
+// BumpFeeV2 replaces an unconfirmed send with the same inputs, address and amount at a
+// higher fee rate, paid from the change, and returns the journal entry of the replacement.
+func (c *ControllerV2) BumpFeeV2(params ParamsV2) (interface{}, error) {
+	var req models.BumpFeeReq
+	log := logger.Default().WithRequestID(params.RequestID).With("service", params.Service)
+	err := json.Unmarshal(params.Body, &req)
+	if err != nil {
+		log.Error("BumpFeeV2: unable to decode the request body", "err", err)
+		return nil, err
+	}
+	coinConfig, err := getCoin(req.Coin)
+	if err != nil {
+		return nil, err
+	}
+	if coinConfig.Info.Token || coinConfig.Info.Tag == "ETH" || !replaceable(coinConfig) {
+		return nil, errNoRBF
+	}
+	if err := c.availability.check(coinConfig); err != nil {
+		return nil, err
+	}
+	if watchOnly() {
+		return nil, errWatchOnly
+	}
+	bumpMu.Lock()
+	defer bumpMu.Unlock()
+	entry, err := journalEntry(coinConfig.Info.Tag, req.Txid)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, errUnknownSend
+	}
+	var status blockbookTxStatus
+	start := time.Now()
+	err = getJSON(strings.TrimRight(coinConfig.Info.Blockbook, "/")+"/api/v2/tx/"+entry.Txid, &status)
+	metrics.ObserveBlockbook(coinConfig.Info.Tag, "tx", start, err)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkReplaceable(entry, status, params.Service); err != nil {
+		return nil, err
+	}
+	feeRate, err := bumpedFeeRate(coinConfig, entry.FeeRate, req.FeeRate)
+	if err != nil {
+		return nil, err
+	}
+	inputs, err := journalInputs(coinConfig, entry)
+	if err != nil {
+		return nil, err
+	}
+	p, err := newPaymentAtRate(coinConfig, inputs, entry.Address, entry.Amount, feeRate, estimateFee, log)
+	if err != nil {
+		return nil, err
+	}
+	if len(p.tx.TxOut) == 1 {
+		return nil, errNoChange
+	}
+	if err := p.sign(coinConfig, log); err != nil {
+		return nil, err
+	}
+	txid, err := broadcastTx(coinConfig, p.tx, log)
+	if err != nil {
+		log.Error("BumpFeeV2: unable to broadcast the replacement", "coin", coinConfig.Info.Tag, "txid", entry.Txid, "err", err)
+		return nil, err
+	}
+	metrics.FeePaid.WithLabelValues(coinConfig.Info.Tag).Add(p.fee.ToBTC() - entry.Fee)
+	replacement := p.journalEntry(coinConfig, txid, entry.Address, entry.Amount)
+	replacement.Replaces = entry.Txid
+	// the replacement keeps the origin of the send to trace it back to its request
+	replacement = journalSend(replacement, sendOrigin{entry.Service, entry.RequestID}, log)
+	err = stateStore.Update(journalBucket, journalKey(entry.Coin, entry.Txid), entry, func(found bool) error {
+		entry.ReplacedBy = txid
+		return nil
+	})
+	if err != nil {
+		log.Error("BumpFeeV2: unable to link the replaced transaction", "coin", coinConfig.Info.Tag, "txid", entry.Txid, "err", err)
+	}
+	return replacement, nil
+}
+
+// checkReplaceable returns why the send of entry, in the state reported by the backend, can't
+// be replaced on behalf of service.
+func checkReplaceable(entry *models.SendJournalEntry, status blockbookTxStatus, service string) error {
+	if entry.Service != service {
+		return errOtherService
+	}
+	if entry.ReplacedBy != "" {
+		return errors.New("the transaction was already replaced by " + entry.ReplacedBy)
+	}
+	if len(entry.Inputs) == 0 {
+		return errNoJournalInput
+	}
+	// a transaction unknown to the backend was dropped from the mempool and can be replaced
+	if status.Error != "" {
+		return nil
+	}
+	if status.Confirmations > 0 {
+		return errSendConfirmed
+	}
+	// the change may already be spent by a later send, the nodes reject a replacement that
+	// doesn't pay for the evicted transactions
+	for _, out := range status.Vout {
+		if out.Spent {
+			return errSendSpent
+		}
+	}
+	return nil
+}
+
+// bumpedFeeRate returns the fee rate of the replacement of a send paying feeRate, requested is
+// zero to use the estimation of the backend. The rate is raised to the minimum increment.
+func bumpedFeeRate(coinConfig *coins.Coin, feeRate int64, requested int64) (int64, error) {
+	if requested < 0 {
+		return 0, errors.New("the fee rate must be positive")
+	}
+	if requested == 0 {
+		estimated, err := getFeeRate(coinConfig)
+		if err != nil {
+			return 0, err
+		}
+		requested = estimated
+	}
+	if requested < feeRate+minFeeRateIncrement {
+		return feeRate + minFeeRateIncrement, nil
+	}
+	return requested, nil
+}
+
+// journalInputs returns the outputs of the wallet spent by a send of the journal.
+func journalInputs(coinConfig *coins.Coin, entry *models.SendJournalEntry) ([]spentOutput, error) {
+	// To prevent address collision we need to de-register all networks and register just the network using
+	chaincfg.ResetParams()
+	_ = chaincfg.Register(coinConfig.NetParams)
+	var inputs []spentOutput
+	for _, input := range entry.Inputs {
+		addr, err := btcutil.DecodeAddress(input.Address, coinConfig.NetParams)
+		if err != nil {
+			return nil, err
+		}
+		script, err := txscript.PayToAddrScript(addr)
+		if err != nil {
+			return nil, err
+		}
+		inputs = append(inputs, spentOutput{
+			txid:    input.Txid,
+			vout:    input.Vout,
+			value:   btcutil.Amount(input.Value),
+			address: input.Address,
+			index:   input.Path,
+			script:  script,
+		})
+	}
+	return inputs, nil
+}
@@ -0,0 +1,122 @@
+package controllers
+
+import (
+	"os"
+	"testing"
+
+	"github.com/grupokindynos/plutus/logger"
+	"github.com/grupokindynos/plutus/models"
+	"github.com/martinboehm/btcd/wire"
+)
+
+func TestReplaceable(t *testing.T) {
+	defer os.Unsetenv("RBF_COINS")
+	os.Unsetenv("RBF_COINS")
+	for _, test := range testXpup {
+		expected := test.coin.Info.Tag == "BTC" || test.coin.Info.Tag == "LTC"
+		if replaceable(test.coin) != expected {
+			t.Error("unexpected default replaceability for " + test.coin.Info.Tag)
+		}
+	}
+	os.Setenv("RBF_COINS", "dgb, GRS")
+	for _, test := range testXpup {
+		expected := test.coin.Info.Tag == "DGB" || test.coin.Info.Tag == "GRS"
+		if replaceable(test.coin) != expected {
+			t.Error("unexpected replaceability with RBF_COINS for " + test.coin.Info.Tag)
+		}
+	}
+}
+
+var bumpedFeeRateTests = []struct {
+	feeRate   int64
+	requested int64
+	expected  int64
+}{
+	{4000, 20000, 20000},
+	{4000, 4500, 5000},
+	{4000, 4000, 5000},
+	{4000, 5000, 5000},
+}
+
+func TestBumpedFeeRate(t *testing.T) {
+	for _, test := range bumpedFeeRateTests {
+		feeRate, err := bumpedFeeRate(testXpup[0].coin, test.feeRate, test.requested)
+		if err != nil || feeRate != test.expected {
+			t.Error("unexpected bumped fee rate")
+		}
+	}
+	if _, err := bumpedFeeRate(testXpup[0].coin, 4000, -1); err == nil {
+		t.Error("a negative fee rate must be rejected")
+	}
+}
+
+func TestReplacementPayment(t *testing.T) {
+	defer os.Unsetenv("RBF_COINS")
+	os.Unsetenv("RBF_COINS")
+	log := logger.Default()
+	for _, test := range testXpup {
+		entry := &models.SendJournalEntry{
+			Coin:    test.coin.Info.Tag,
+			Address: test.addr,
+			Amount:  0.5,
+			FeeRate: 4000,
+			Inputs: []models.JournalInput{
+				{Txid: testPrevTxid, Vout: 1, Value: 100000000, Address: test.addr, Path: test.path},
+			},
+		}
+		inputs, err := journalInputs(test.coin, entry)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(inputs) != 1 || inputs[0].index != test.path || inputs[0].value != 100000000 {
+			t.Error("unexpected journal inputs for " + test.coin.Info.Tag)
+		}
+		original, err := newPaymentAtRate(test.coin, inputs, entry.Address, entry.Amount, entry.FeeRate, estimateFee, log)
+		if err != nil {
+			t.Fatal(err)
+		}
+		replacement, err := newPaymentAtRate(test.coin, inputs, entry.Address, entry.Amount, entry.FeeRate+minFeeRateIncrement, estimateFee, log)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if replacement.fee <= original.fee || len(replacement.tx.TxOut) != 2 || replacement.tx.TxOut[1].Value != original.tx.TxOut[1].Value {
+			t.Error("the replacement must pay the same amount with a higher fee for " + test.coin.Info.Tag)
+		}
+		sequence := uint32(wire.MaxTxInSequenceNum)
+		if replaceable(test.coin) {
+			sequence = maxRBFSequence
+		}
+		if original.tx.TxIn[0].Sequence != sequence {
+			t.Error("unexpected input sequence for " + test.coin.Info.Tag)
+		}
+	}
+}
+
+func TestCheckReplaceable(t *testing.T) {
+	entry := &models.SendJournalEntry{Service: "ladon", Inputs: []models.JournalInput{{Txid: "01"}}}
+	var status blockbookTxStatus
+	if err := checkReplaceable(entry, status, "ladon"); err != nil {
+		t.Error("an unconfirmed send must be replaceable")
+	}
+	if err := checkReplaceable(entry, status, "tyche"); err != errOtherService {
+		t.Error("a send must only be replaced by its service")
+	}
+	status.Vout = append(status.Vout, struct {
+		Spent bool `json:"spent"`
+	}{Spent: true})
+	if err := checkReplaceable(entry, status, "ladon"); err != errSendSpent {
+		t.Error("a send whose outputs are spent must not be replaced")
+	}
+	status.Confirmations = 1
+	if err := checkReplaceable(entry, status, "ladon"); err != errSendConfirmed {
+		t.Error("a confirmed send must not be replaced")
+	}
+	status.Error = "transaction not found"
+	if err := checkReplaceable(entry, status, "ladon"); err != nil {
+		t.Error("a dropped send must be replaceable")
+	}
+	entry.ReplacedBy = "02"
+	if err := checkReplaceable(entry, status, "ladon"); err == nil {
+		t.Error("a send must only be replaced once")
+	}
+}
@@ -0,0 +1,68 @@
+package controllers
+
+import (
+	"os"
+	"sync"
+
+	coinfactory "github.com/grupokindynos/common/coin-factory"
+	"github.com/grupokindynos/common/coin-factory/coins"
+)
+
+// secrets holds the mnemonics unlocked from the keystore. Without a keystore the
+// mnemonics keep coming from the environment through the coin factory.
+var secrets struct {
+	sync.RWMutex
+	loaded           bool
+	mnemonics        map[string]string
+	mnemonicPassword string
+}
+
+// SetSecrets replaces the mnemonics of the environment with the ones of the keystore. It
+// must be called before the controllers are created.
+func SetSecrets(mnemonics map[string]string, mnemonicPassword string) {
+	secrets.Lock()
+	defer secrets.Unlock()
+	secrets.loaded = true
+	secrets.mnemonics = make(map[string]string, len(mnemonics))
+	for tag, mnemonic := range mnemonics {
+		secrets.mnemonics[tag] = mnemonic
+	}
+	secrets.mnemonicPassword = mnemonicPassword
+}
+
+// mnemonic returns the mnemonic configured for tag.
+func mnemonic(tag string) string {
+	secrets.RLock()
+	defer secrets.RUnlock()
+	if secrets.loaded {
+		return secrets.mnemonics[tag]
+	}
+	return os.Getenv("MNEMONIC_" + tag)
+}
+
+func mnemonicPassword() string {
+	secrets.RLock()
+	defer secrets.RUnlock()
+	if secrets.loaded {
+		return secrets.mnemonicPassword
+	}
+	return os.Getenv("MNEMONIC_PASSWORD")
+}
+
+// getCoin returns the configuration of a coin. When a keystore is loaded the returned value
+// is a copy carrying the mnemonic of the keystore, so it can be changed per request.
+func getCoin(tag string) (*coins.Coin, error) {
+	coin, err := coinfactory.GetCoin(tag)
+	if err != nil {
+		return nil, err
+	}
+	secrets.RLock()
+	loaded := secrets.loaded
+	secrets.RUnlock()
+	if !loaded {
+		return coin, nil
+	}
+	coinCopy := *coin
+	coinCopy.Mnemonic = mnemonic(coin.Info.Tag)
+	return &coinCopy, nil
+}
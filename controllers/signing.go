@@ -0,0 +1,148 @@
+package controllers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+
+	"github.com/eabz/btcutil/txscript"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/grupokindynos/common/coin-factory/coins"
+	"github.com/grupokindynos/plutus/signer"
+	"github.com/martinboehm/btcd/btcec"
+	"github.com/martinboehm/btcd/wire"
+	hdwallet "github.com/miguelmota/go-ethereum-hdwallet"
+)
+
+// txSigner signs the transactions built by the controllers. It defaults to signing in
+// process with the configured mnemonics.
+var txSigner signer.Signer = signer.NewLocal(keySource{})
+
+// SetSigner replaces the signer, e.g. with a remote signer process. It must be called
+// before the controllers are created.
+func SetSigner(s signer.Signer) {
+	txSigner = s
+}
+
+// KeySource returns the keys of the configured mnemonics, used by the signer process.
+func KeySource() signer.KeySource {
+	return keySource{}
+}
+
+type keySource struct{}
+
+func (keySource) PrivateKey(coin string, index uint32) (*btcec.PrivateKey, error) {
+	coinConfig, err := getCoin(coin)
+	if err != nil {
+		return nil, err
+	}
+	acc, err := getAccFromMnemonic(coinConfig, true)
+	if err != nil {
+		return nil, err
+	}
+	return getPrivKeyFromPath(acc, index)
+}
+
+func (keySource) EthWallet(tag string) (*hdwallet.Wallet, accounts.Account, error) {
+	ethMnemonic, err := ethMnemonic(tag)
+	if err != nil {
+		return nil, accounts.Account{}, err
+	}
+	return keys.ethAccount(ethMnemonic)
+}
+
+// signInput signs the P2PKH input idx of tx, spending an output of the address at index of
+// the receive chain, and sets its signature script.
+func signInput(tx *wire.MsgTx, idx int, subscript []byte, coinConfig *coins.Coin, index uint32) error {
+	digest, err := sigHash(tx, idx, subscript, coinConfig.Info.Tag == "GRS")
+	if err != nil {
+		return err
+	}
+	signature, pubKey, err := txSigner.SignDigest(coinConfig.Info.Tag, index, digest)
+	if err != nil {
+		return err
+	}
+	sigScript, err := txscript.NewScriptBuilder().
+		AddData(append(signature, byte(txscript.SigHashAll))).
+		AddData(pubKey).
+		Script()
+	if err != nil {
+		return err
+	}
+	tx.TxIn[idx].SignatureScript = sigScript
+	return nil
+}
+
+// sigHash returns the legacy SIGHASH_ALL digest of the input idx. Groestlcoin hashes the
+// transactions with a single sha256 instead of two.
+func sigHash(tx *wire.MsgTx, idx int, subscript []byte, singleSha256 bool) ([]byte, error) {
+	if idx < 0 || idx >= len(tx.TxIn) {
+		return nil, errors.New("input index out of range")
+	}
+	txCopy := tx.Copy()
+	for i := range txCopy.TxIn {
+		if i == idx {
+			txCopy.TxIn[i].SignatureScript = subscript
+		} else {
+			txCopy.TxIn[i].SignatureScript = nil
+		}
+	}
+	var buf bytes.Buffer
+	if err := txCopy.BtcEncode(&buf, 0, wire.BaseEncoding); err != nil {
+		return nil, err
+	}
+	var hashType [4]byte
+	binary.LittleEndian.PutUint32(hashType[:], uint32(txscript.SigHashAll))
+	buf.Write(hashType[:])
+	first := sha256.Sum256(buf.Bytes())
+	if singleSha256 {
+		return first[:], nil
+	}
+	second := sha256.Sum256(first[:])
+	return second[:], nil
+}
+
+// witnessSigHash returns the BIP143 SIGHASH_ALL digest of the segwit input idx spending
+// amount. Groestlcoin hashes with a single sha256 instead of two.
+func witnessSigHash(tx *wire.MsgTx, idx int, script []byte, amount int64, singleSha256 bool) ([]byte, error) {
+	if idx < 0 || idx >= len(tx.TxIn) {
+		return nil, errors.New("input index out of range")
+	}
+	hash := func(b []byte) []byte {
+		first := sha256.Sum256(b)
+		if singleSha256 {
+			return first[:]
+		}
+		second := sha256.Sum256(first[:])
+		return second[:]
+	}
+	var prevOuts, sequences, outputs bytes.Buffer
+	for _, in := range tx.TxIn {
+		prevOuts.Write(in.PreviousOutPoint.Hash[:])
+		_ = binary.Write(&prevOuts, binary.LittleEndian, in.PreviousOutPoint.Index)
+		_ = binary.Write(&sequences, binary.LittleEndian, in.Sequence)
+	}
+	for _, out := range tx.TxOut {
+		_ = binary.Write(&outputs, binary.LittleEndian, out.Value)
+		if err := wire.WriteVarBytes(&outputs, 0, out.PkScript); err != nil {
+			return nil, err
+		}
+	}
+	in := tx.TxIn[idx]
+	var buf bytes.Buffer
+	_ = binary.Write(&buf, binary.LittleEndian, tx.Version)
+	buf.Write(hash(prevOuts.Bytes()))
+	buf.Write(hash(sequences.Bytes()))
+	buf.Write(in.PreviousOutPoint.Hash[:])
+	_ = binary.Write(&buf, binary.LittleEndian, in.PreviousOutPoint.Index)
+	if err := wire.WriteVarBytes(&buf, 0, script); err != nil {
+		return nil, err
+	}
+	_ = binary.Write(&buf, binary.LittleEndian, amount)
+	_ = binary.Write(&buf, binary.LittleEndian, in.Sequence)
+	buf.Write(hash(outputs.Bytes()))
+	_ = binary.Write(&buf, binary.LittleEndian, tx.LockTime)
+	_ = binary.Write(&buf, binary.LittleEndian, uint32(txscript.SigHashAll))
+	return hash(buf.Bytes()), nil
+}
@@ -0,0 +1,35 @@
+package controllers
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/eabz/btcutil/txscript"
+	"github.com/martinboehm/btcd/wire"
+)
+
+func TestSignInputMatchesSignatureScript(t *testing.T) {
+	defaultSigner := txSigner
+	defer SetSigner(defaultSigner)
+	for _, test := range testXpup {
+		_, privKey := useTestKey(t, test)
+		subscript := testAddrScript(t, test)
+		tx := testSpendingTx(testPrevHash(), 0, 1)
+		tx.AddTxOut(wire.NewTxOut(100000, subscript))
+		hasher := txscript.Sha256d
+		if test.coin.Info.Tag == "GRS" {
+			hasher = txscript.Sha256
+		}
+		expected, err := txscript.SignatureScript(tx, 1, subscript, txscript.SigHashAll, privKey, true, hasher)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = signInput(tx, 1, subscript, test.coin, test.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(tx.TxIn[1].SignatureScript, expected) {
+			t.Error("signature script doesn't match for " + test.coin.Info.Tag)
+		}
+	}
+}
@@ -0,0 +1,15 @@
+package controllers
+
+import (
+	"github.com/grupokindynos/plutus/store"
+)
+
+// stateStore keeps the state of the controllers that must survive a restart: the issued
+// addresses and the multisig wallets. Until SetStore is called it lives in memory.
+var stateStore, _ = store.Open("")
+
+// SetStore persists the state of the controllers in db. It must be called before the
+// controllers are created.
+func SetStore(db *store.Store) {
+	stateStore = db
+}
@@ -0,0 +1,249 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/eabz/btcutil"
+	"github.com/eabz/btcutil/chaincfg"
+	"github.com/eabz/btcutil/txscript"
+	"github.com/grupokindynos/common/blockbook"
+	"github.com/grupokindynos/common/coin-factory/coins"
+	"github.com/grupokindynos/plutus/logger"
+	"github.com/grupokindynos/plutus/metrics"
+	"github.com/grupokindynos/plutus/models"
+	"github.com/martinboehm/btcd/chaincfg/chainhash"
+	"github.com/martinboehm/btcd/wire"
+)
+
+// spentOutput is an output of the wallet spent by an input of a payment.
+type spentOutput struct {
+	txid    string
+	vout    uint32
+	value   btcutil.Amount
+	address string
+	// index is the index of the address in the receive chain of the account.
+	index  uint32
+	script []byte
+}
+
+// payment is an unsigned transaction paying from the wallet of a coin.
+type payment struct {
+	tx     *wire.MsgTx
+	inputs []spentOutput
+	fee    btcutil.Amount
+	// feeRate is in satoshis per kB.
+	feeRate int64
+}
+
+// buildPayment spends every utxo of the wallet to pay amount to address.
+func buildPayment(coinConfig *coins.Coin, address string, amount float64, log *logger.Logger) (*payment, error) {
+	inputs, err := walletUtxos(coinConfig, log)
+	if err != nil {
+		return nil, err
+	}
+	return newPayment(coinConfig, inputs, address, amount, estimateFee, log)
+}
+
+// walletUtxos returns the outputs the BIP44 account of the coin can spend.
+func walletUtxos(coinConfig *coins.Coin, log *logger.Logger) ([]spentOutput, error) {
+	accPub, err := getAccPub(coinConfig)
+	if err != nil {
+		return nil, err
+	}
+	blockBookWrap := blockbook.NewBlockBookWrapper(coinConfig.Info.Blockbook)
+	start := time.Now()
+	utxos, err := blockBookWrap.GetUtxo(accPub.String(), false)
+	metrics.ObserveBlockbook(coinConfig.Info.Tag, "utxo", start, err)
+	if err != nil {
+		return nil, err
+	}
+	// To prevent address collision we need to de-register all networks and register just the network using
+	chaincfg.ResetParams()
+	_ = chaincfg.Register(coinConfig.NetParams)
+	var inputs []spentOutput
+	for _, utxo := range utxos {
+		intValue, err := strconv.ParseInt(utxo.Value, 10, 64)
+		if err != nil {
+			log.Error("walletUtxos: invalid utxo value", "value", utxo.Value, "err", err)
+			return nil, err
+		}
+		path := strings.Split(utxo.Path, "/")
+		if len(path) != 6 {
+			log.Error("walletUtxos: invalid utxo derivation path", "path", utxo.Path)
+			return nil, errors.New("invalid utxo derivation path")
+		}
+		pathParse, err := strconv.ParseInt(path[5], 10, 64)
+		if err != nil {
+			log.Error("walletUtxos: invalid utxo derivation path", "path", utxo.Path, "err", err)
+			return nil, err
+		}
+		addr, err := btcutil.DecodeAddress(utxo.Address, coinConfig.NetParams)
+		if err != nil {
+			log.Error("walletUtxos: invalid utxo address", "address", utxo.Address, "err", err)
+			return nil, err
+		}
+		subscript, err := txscript.PayToAddrScript(addr)
+		if err != nil {
+			log.Error("walletUtxos: unable to build the input script", "address", utxo.Address, "err", err)
+			return nil, err
+		}
+		inputs = append(inputs, spentOutput{
+			txid:    utxo.Txid,
+			vout:    uint32(utxo.Vout),
+			value:   btcutil.Amount(intValue),
+			address: utxo.Address,
+			index:   uint32(pathParse),
+			script:  subscript,
+		})
+	}
+	return inputs, nil
+}
+
+// newPayment spends every input to pay amount to address at the fee rate estimated by the
+// backend.
+func newPayment(coinConfig *coins.Coin, inputs []spentOutput, address string, amount float64, fee func(feeRate int64, inputs int, outputs int) btcutil.Amount, log *logger.Logger) (*payment, error) {
+	feeRate, err := getFeeRate(coinConfig)
+	if err != nil {
+		log.Error("newPayment: unable to get the fee rate", "coin", coinConfig.Info.Tag, "err", err)
+		return nil, err
+	}
+	return newPaymentAtRate(coinConfig, inputs, address, amount, feeRate, fee, log)
+}
+
+// newPaymentAtRate spends every input to pay amount to address. The change goes back to the
+// address of the first input, the fee is paid from the change or, when the change can't cover
+// it, from the amount. The inputs signal BIP125 replaceability on the coins accepting it.
+func newPaymentAtRate(coinConfig *coins.Coin, inputs []spentOutput, address string, amount float64, feeRate int64, fee func(feeRate int64, inputs int, outputs int) btcutil.Amount, log *logger.Logger) (*payment, error) {
+	value, err := btcutil.NewAmount(amount)
+	if err != nil {
+		return nil, err
+	}
+	if len(inputs) == 0 {
+		return nil, errors.New("no balance available")
+	}
+	p := &payment{tx: wire.NewMsgTx(txVersion(coinConfig)), inputs: inputs}
+	rbf := replaceable(coinConfig)
+	var availableAmount btcutil.Amount
+	// Add the inputs without signatures
+	for _, input := range inputs {
+		txidHash, err := chainhash.NewHashFromStr(input.txid)
+		if err != nil {
+			log.Error("newPayment: invalid utxo txid", "txid", input.txid, "err", err)
+			return nil, err
+		}
+		availableAmount += input.value
+		txIn := wire.NewTxIn(wire.NewOutPoint(txidHash, input.vout), nil, nil)
+		if rbf {
+			txIn.Sequence = maxRBFSequence
+		}
+		p.tx.AddTxIn(txIn)
+	}
+	// Retrieve information for outputs
+	payAddr, err := btcutil.DecodeAddress(address, coinConfig.NetParams)
+	if err != nil {
+		log.Error("newPayment: invalid destination address", "coin", coinConfig.Info.Tag, "address", address, "err", err)
+		return nil, err
+	}
+	pkScriptPay, err := txscript.PayToAddrScript(payAddr)
+	if err != nil {
+		log.Error("newPayment: unable to build the output script", "address", payAddr.String(), "err", err)
+		return nil, err
+	}
+	txOut := &wire.TxOut{
+		Value:    int64(value.ToUnit(btcutil.AmountSatoshi)),
+		PkScript: pkScriptPay,
+	}
+	p.feeRate = feeRate
+	p.fee = fee(feeRate, len(p.tx.TxIn), len(p.tx.TxOut))
+	if availableAmount-p.fee-value > 0 {
+		p.tx.AddTxOut(&wire.TxOut{
+			Value:    int64(((availableAmount - value) - p.fee).ToUnit(btcutil.AmountSatoshi)),
+			PkScript: inputs[0].script,
+		})
+	} else {
+		txOut.Value = int64((value - p.fee).ToUnit(btcutil.AmountSatoshi))
+	}
+	p.tx.AddTxOut(txOut)
+	return p, nil
+}
+
+func txVersion(coinConfig *coins.Coin) int32 {
+	if coinConfig.Info.Tag == "POLIS" || coinConfig.Info.Tag == "DASH" || coinConfig.Info.Tag == "GRS" {
+		return 2
+	}
+	return 1
+}
+
+// sign creates the signatures of every input.
+func (p *payment) sign(coinConfig *coins.Coin, log *logger.Logger) error {
+	for i, input := range p.inputs {
+		err := signInput(p.tx, i, input.script, coinConfig, input.index)
+		if err != nil {
+			log.Error("sign: unable to sign the input", "input", i, "index", input.index, "err", err)
+			return err
+		}
+	}
+	return nil
+}
+
+// broadcastTx sends a signed transaction to the network and returns its txid.
+func broadcastTx(coinConfig *coins.Coin, tx *wire.MsgTx, log *logger.Logger) (string, error) {
+	buf := bytes.NewBuffer([]byte{})
+	err := tx.BtcEncode(buf, 0, wire.WitnessEncoding)
+	if err != nil {
+		log.Error("broadcastTx: unable to serialize the transaction", "err", err)
+		return "", err
+	}
+	rawTx := hex.EncodeToString(buf.Bytes())
+	log.Debug("broadcastTx: raw transaction", "raw_tx", rawTx)
+	blockBookWrap := blockbook.NewBlockBookWrapper(coinConfig.Info.Blockbook)
+	start := time.Now()
+	txid, err := blockBookWrap.SendTx(rawTx)
+	metrics.ObserveBlockbook(coinConfig.Info.Tag, "send_tx", start, err)
+	return txid, err
+}
+
+// sendPayment builds, signs and broadcasts a payment and records it in the journal.
+func sendPayment(coinConfig *coins.Coin, address string, amount float64, origin sendOrigin, log *logger.Logger) (string, error) {
+	p, err := buildPayment(coinConfig, address, amount, log)
+	if err != nil {
+		return "", err
+	}
+	if err := p.sign(coinConfig, log); err != nil {
+		return "", err
+	}
+	txid, err := broadcastTx(coinConfig, p.tx, log)
+	if err != nil {
+		return "", err
+	}
+	metrics.FeePaid.WithLabelValues(coinConfig.Info.Tag).Add(p.fee.ToBTC())
+	journalSend(p.journalEntry(coinConfig, txid, address, amount), origin, log)
+	return txid, nil
+}
+
+// journalEntry describes the payment once broadcast as txid.
+func (p *payment) journalEntry(coinConfig *coins.Coin, txid string, address string, amount float64) models.SendJournalEntry {
+	entry := models.SendJournalEntry{
+		Coin:    coinConfig.Info.Tag,
+		Txid:    txid,
+		Address: address,
+		Amount:  amount,
+		Fee:     p.fee.ToBTC(),
+		FeeRate: p.feeRate,
+	}
+	for _, input := range p.inputs {
+		entry.Inputs = append(entry.Inputs, models.JournalInput{
+			Txid:    input.txid,
+			Vout:    input.vout,
+			Value:   int64(input.value),
+			Address: input.address,
+			Path:    input.index,
+		})
+	}
+	return entry
+}
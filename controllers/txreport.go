@@ -0,0 +1,454 @@
+package controllers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/eabz/btcutil"
+	"github.com/eabz/btcutil/txscript"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/grupokindynos/common/blockbook"
+	"github.com/grupokindynos/common/coin-factory/coins"
+	"github.com/grupokindynos/plutus/metrics"
+	"github.com/grupokindynos/plutus/models"
+	"github.com/grupokindynos/plutus/psbt"
+	"github.com/martinboehm/btcd/wire"
+)
+
+// maxRBFSequence is the highest sequence signalling BIP125 replaceability.
+const maxRBFSequence = 0xfffffffd
+
+// maxReportInputs caps the inputs of the reported transactions, as every spent output is
+// fetched from the backend.
+const maxReportInputs = 100
+
+var (
+	errMissingSignature = errors.New("the input is not signed")
+	errTooManyInputs    = fmt.Errorf("the transaction spends more than %d inputs", maxReportInputs)
+)
+
+// prevOutput is an output spent by a transaction being validated.
+type prevOutput struct {
+	out   *wire.TxOut
+	spent bool
+	err   error
+}
+
+type blockbookSpentTx struct {
+	Hex  string `json:"hex"`
+	Vout []struct {
+		N     uint32 `json:"n"`
+		Spent bool   `json:"spent"`
+	} `json:"vout"`
+}
+
+// getSpentOutputs fetches the outputs spent by the inputs of tx and whether another
+// transaction already spends them.
+func getSpentOutputs(coinConfig *coins.Coin, tx *wire.MsgTx) map[wire.OutPoint]*prevOutput {
+	outputs := make(map[wire.OutPoint]*prevOutput)
+	for _, in := range tx.TxIn {
+		outpoint := in.PreviousOutPoint
+		if _, ok := outputs[outpoint]; ok {
+			continue
+		}
+		var res blockbookSpentTx
+		start := time.Now()
+		err := getJSON(strings.TrimRight(coinConfig.Info.Blockbook, "/")+"/api/v2/tx/"+outpoint.Hash.String(), &res)
+		metrics.ObserveBlockbook(coinConfig.Info.Tag, "tx", start, err)
+		if err != nil {
+			outputs[outpoint] = &prevOutput{err: err}
+			continue
+		}
+		rawTx, err := hex.DecodeString(res.Hex)
+		if err != nil {
+			outputs[outpoint] = &prevOutput{err: err}
+			continue
+		}
+		prevTx := new(wire.MsgTx)
+		if err := prevTx.Deserialize(bytes.NewReader(rawTx)); err != nil || prevTx.TxHash() != outpoint.Hash {
+			outputs[outpoint] = &prevOutput{err: errors.New("the backend returned another transaction for " + outpoint.Hash.String())}
+			continue
+		}
+		if int(outpoint.Index) >= len(prevTx.TxOut) {
+			outputs[outpoint] = &prevOutput{err: errors.New("the spent output doesn't exist")}
+			continue
+		}
+		spent := &prevOutput{out: prevTx.TxOut[outpoint.Index]}
+		for _, vout := range res.Vout {
+			if vout.N == outpoint.Index {
+				spent.spent = vout.Spent
+			}
+		}
+		outputs[outpoint] = spent
+	}
+	return outputs
+}
+
+// ownScripts returns the output scripts of the known addresses of the coin.
+func ownScripts(coinConfig *coins.Coin, info AddrInfo) (map[string]bool, error) {
+	scripts := make(map[string]bool)
+	for _, addr := range info.AddrInfo {
+		address, err := btcutil.DecodeAddress(addr.Addr, coinConfig.NetParams)
+		if err != nil {
+			return nil, err
+		}
+		script, err := txscript.PayToAddrScript(address)
+		if err != nil {
+			return nil, err
+		}
+		scripts[string(script)] = true
+	}
+	return scripts, nil
+}
+
+// utxoTxReport validates a bitcoin-like transaction paying amount to the scripts of mine
+// against the outputs it spends.
+func utxoTxReport(coinConfig *coins.Coin, tx *wire.MsgTx, amount int64, mine map[string]bool, spent map[wire.OutPoint]*prevOutput) *models.TxReport {
+	size := tx.SerializeSize()
+	report := &models.TxReport{
+		Coin:            coinConfig.Info.Tag,
+		Txid:            tx.TxHash().String(),
+		Outputs:         []models.TxReportOutput{},
+		SignaturesValid: true,
+		InputsUnspent:   true,
+		Size:            size,
+		VSize:           (tx.SerializeSizeStripped()*3 + size + 3) / 4,
+	}
+	var outTotal int64
+	for i, out := range tx.TxOut {
+		output := models.TxReportOutput{
+			Index:   i,
+			Address: outputAddress(coinConfig, out.PkScript),
+			Amount:  out.Value,
+			Mine:    mine[string(out.PkScript)],
+		}
+		if output.Mine {
+			report.Paid += out.Value
+			if out.Value == amount {
+				report.PaysAmount = true
+			}
+		}
+		outTotal += out.Value
+		report.Outputs = append(report.Outputs, output)
+	}
+	var inTotal int64
+	known := true
+	for i, in := range tx.TxIn {
+		input := models.TxReportInput{
+			Txid:   in.PreviousOutPoint.Hash.String(),
+			Vout:   in.PreviousOutPoint.Index,
+			Signed: len(in.SignatureScript) > 0 || len(in.Witness) > 0,
+		}
+		if in.Sequence <= maxRBFSequence {
+			report.RBF = true
+		}
+		prev := spent[in.PreviousOutPoint]
+		switch {
+		case prev == nil:
+			input.Error = "the spent output is unknown"
+		case prev.err != nil:
+			input.Error = prev.err.Error()
+		default:
+			input.Address = outputAddress(coinConfig, prev.out.PkScript)
+			input.Amount = prev.out.Value
+			input.Spent = prev.spent
+			inTotal += prev.out.Value
+			if err := verifyInput(coinConfig, tx, i, prev.out); err != nil {
+				input.Error = err.Error()
+			} else {
+				input.SignatureValid = true
+			}
+		}
+		if prev == nil || prev.err != nil {
+			known = false
+		}
+		if !input.SignatureValid {
+			report.SignaturesValid = false
+		}
+		if input.Spent {
+			report.InputsUnspent = false
+		}
+		report.Inputs = append(report.Inputs, input)
+	}
+	if known {
+		report.Fee = inTotal - outTotal
+		report.FeeRate = float64(report.Fee) / float64(report.VSize)
+	}
+	report.Valid = report.PaysAmount && report.SignaturesValid && report.InputsUnspent
+	return report
+}
+
+func outputAddress(coinConfig *coins.Coin, script []byte) string {
+	_, addrs, _, err := txscript.ExtractPkScriptAddrs(script, coinConfig.NetParams)
+	if err != nil || len(addrs) != 1 {
+		return ""
+	}
+	return addrs[0].EncodeAddress()
+}
+
+// verifyInput checks that the input idx of tx satisfies the script of the output it spends.
+// Only the standard scripts signed with SIGHASH_ALL are supported: P2PKH, P2WPKH, P2SH
+// multisig, P2WSH multisig and their P2SH nested forms.
+func verifyInput(coinConfig *coins.Coin, tx *wire.MsgTx, idx int, prevOut *wire.TxOut) error {
+	in := tx.TxIn[idx]
+	pkScript := prevOut.PkScript
+	singleSha256 := coinConfig.Info.Tag == "GRS"
+	switch txscript.GetScriptClass(pkScript) {
+	case txscript.PubKeyHashTy:
+		pushes, err := txscript.PushedData(in.SignatureScript)
+		if err != nil {
+			return err
+		}
+		if len(pushes) != 2 {
+			return errMissingSignature
+		}
+		if !bytes.Equal(btcutil.Hash160(pushes[1]), pkScript[3:23]) {
+			return errors.New("the public key doesn't match the spent output")
+		}
+		digest, err := sigHash(tx, idx, pkScript, singleSha256)
+		if err != nil {
+			return err
+		}
+		return verifySignature(digest, psbt.PartialSig{PubKey: pushes[1], Signature: pushes[0]})
+	case txscript.WitnessV0PubKeyHashTy:
+		return verifyWitnessKeyHash(tx, idx, pkScript[2:], prevOut.Value, singleSha256)
+	case txscript.WitnessV0ScriptHashTy:
+		return verifyWitnessScriptHash(coinConfig, tx, idx, pkScript[2:], prevOut.Value)
+	case txscript.ScriptHashTy:
+		pushes, err := txscript.PushedData(in.SignatureScript)
+		if err != nil {
+			return err
+		}
+		if len(pushes) == 0 {
+			return errMissingSignature
+		}
+		redeemScript := pushes[len(pushes)-1]
+		if !bytes.Equal(btcutil.Hash160(redeemScript), pkScript[2:22]) {
+			return errors.New("the redeem script doesn't match the spent output")
+		}
+		switch txscript.GetScriptClass(redeemScript) {
+		case txscript.WitnessV0PubKeyHashTy:
+			return verifyWitnessKeyHash(tx, idx, redeemScript[2:], prevOut.Value, singleSha256)
+		case txscript.WitnessV0ScriptHashTy:
+			return verifyWitnessScriptHash(coinConfig, tx, idx, redeemScript[2:], prevOut.Value)
+		}
+		digest, err := sigHash(tx, idx, redeemScript, singleSha256)
+		if err != nil {
+			return err
+		}
+		return verifyMultisig(coinConfig, redeemScript, pushes[:len(pushes)-1], digest)
+	}
+	return errors.New("unsupported spent output script")
+}
+
+func verifyWitnessKeyHash(tx *wire.MsgTx, idx int, keyHash []byte, amount int64, singleSha256 bool) error {
+	witness := tx.TxIn[idx].Witness
+	if len(witness) != 2 {
+		return errMissingSignature
+	}
+	if !bytes.Equal(btcutil.Hash160(witness[1]), keyHash) {
+		return errors.New("the public key doesn't match the spent output")
+	}
+	scriptCode, err := txscript.NewScriptBuilder().
+		AddOp(txscript.OP_DUP).
+		AddOp(txscript.OP_HASH160).
+		AddData(keyHash).
+		AddOp(txscript.OP_EQUALVERIFY).
+		AddOp(txscript.OP_CHECKSIG).
+		Script()
+	if err != nil {
+		return err
+	}
+	digest, err := witnessSigHash(tx, idx, scriptCode, amount, singleSha256)
+	if err != nil {
+		return err
+	}
+	return verifySignature(digest, psbt.PartialSig{PubKey: witness[1], Signature: witness[0]})
+}
+
+func verifyWitnessScriptHash(coinConfig *coins.Coin, tx *wire.MsgTx, idx int, scriptHash []byte, amount int64) error {
+	witness := tx.TxIn[idx].Witness
+	if len(witness) == 0 {
+		return errMissingSignature
+	}
+	witnessScript := witness[len(witness)-1]
+	hash := sha256.Sum256(witnessScript)
+	if !bytes.Equal(hash[:], scriptHash) {
+		return errors.New("the witness script doesn't match the spent output")
+	}
+	digest, err := witnessSigHash(tx, idx, witnessScript, amount, coinConfig.Info.Tag == "GRS")
+	if err != nil {
+		return err
+	}
+	return verifyMultisig(coinConfig, witnessScript, witness[:len(witness)-1], digest)
+}
+
+// verifyMultisig checks that sigs hold the signatures required by the multisig script, in
+// the order of its keys like OP_CHECKMULTISIG. Empty elements, like the leading dummy, are
+// skipped.
+func verifyMultisig(coinConfig *coins.Coin, script []byte, sigs [][]byte, digest []byte) error {
+	if txscript.GetScriptClass(script) != txscript.MultiSigTy {
+		return errors.New("unsupported spent output script")
+	}
+	_, addrs, required, err := txscript.ExtractPkScriptAddrs(script, coinConfig.NetParams)
+	if err != nil {
+		return err
+	}
+	var valid, key int
+	for _, sig := range sigs {
+		if len(sig) == 0 {
+			continue
+		}
+		for key < len(addrs) {
+			pubKey := addrs[key].ScriptAddress()
+			key++
+			if verifySignature(digest, psbt.PartialSig{PubKey: pubKey, Signature: sig}) == nil {
+				valid++
+				break
+			}
+		}
+	}
+	if valid == 0 {
+		return errMissingSignature
+	}
+	if valid < required {
+		return fmt.Errorf("%d valid signatures of the %d required", valid, required)
+	}
+	return nil
+}
+
+func decodeEthTx(rawTx string) (*types.Transaction, error) {
+	rawTxBytes, err := hex.DecodeString(strings.TrimPrefix(rawTx, "0x"))
+	if err != nil {
+		return nil, err
+	}
+	var tx *types.Transaction
+	err = rlp.DecodeBytes(rawTxBytes, &tx)
+	if err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+// evmTxReport validates an ethereum or ERC20 transaction paying amount to address.
+// accountNonce returns the next nonce of the sender.
+func evmTxReport(coinConfig *coins.Coin, tx *types.Transaction, address string, amount int64, accountNonce func(from common.Address) (uint64, error)) (*models.TxReport, error) {
+	if tx.To() == nil {
+		return nil, errors.New("the transaction creates a contract")
+	}
+	rawTx, err := rlp.EncodeToBytes(tx)
+	if err != nil {
+		return nil, err
+	}
+	fee := new(big.Int).Mul(tx.GasPrice(), new(big.Int).SetUint64(tx.Gas()))
+	gasPrice, _ := new(big.Float).Quo(new(big.Float).SetInt(tx.GasPrice()), big.NewFloat(1e9)).Float64()
+	report := &models.TxReport{
+		Coin:  coinConfig.Info.Tag,
+		Txid:  tx.Hash().Hex(),
+		Fee:   fee.Int64(),
+		Size:  len(rawTx),
+		VSize: len(rawTx),
+		// any pending transaction can be replaced with another one using the same nonce
+		RBF:     true,
+		FeeRate: gasPrice,
+		EVM: &models.EVMTxReport{
+			To:       tx.To().Hex(),
+			Nonce:    tx.Nonce(),
+			GasLimit: tx.Gas(),
+			GasPrice: tx.GasPrice(),
+			Value:    tx.Value(),
+		},
+	}
+	if coinConfig.Info.Token && coinConfig.Info.Tag != "ETH" {
+		report.EVM.Contract = tx.To().Hex()
+		report.EVM.TokenContract = common.HexToAddress(coinConfig.Info.Contract).Hex()
+	}
+	recipient, value, ok, err := ethPayment(coinConfig, tx)
+	if err != nil {
+		return nil, err
+	}
+	report.Outputs = []models.TxReportOutput{}
+	if ok {
+		mine := bytes.Equal(recipient.Bytes(), common.HexToAddress(address).Bytes())
+		output := models.TxReportOutput{Address: recipient.Hex(), Mine: mine}
+		if value.IsInt64() {
+			output.Amount = value.Int64()
+		}
+		if mine {
+			report.Paid = output.Amount
+			report.PaysAmount = value.Cmp(big.NewInt(amount)) == 0
+		}
+		report.Outputs = append(report.Outputs, output)
+	}
+	var signer types.Signer = types.HomesteadSigner{}
+	if tx.Protected() {
+		signer = types.NewEIP155Signer(tx.ChainId())
+		report.EVM.ChainID = tx.ChainId()
+	}
+	from, err := types.Sender(signer, tx)
+	if err == nil {
+		report.SignaturesValid = true
+		report.EVM.From = from.Hex()
+		nonce, err := accountNonce(from)
+		if err != nil {
+			return nil, err
+		}
+		report.EVM.AccountNonce = nonce
+		report.InputsUnspent = tx.Nonce() >= nonce
+	}
+	report.Valid = report.PaysAmount && report.SignaturesValid && report.InputsUnspent
+	return report, nil
+}
+
+// ethAccountNonce returns the next nonce of the account from the ETH backend.
+func ethAccountNonce(from common.Address) (uint64, error) {
+	ethConfig, err := getCoin("ETH")
+	if err != nil {
+		return 0, err
+	}
+	blockBookWrap := blockbook.NewBlockBookWrapper(ethConfig.Info.Blockbook)
+	start := time.Now()
+	info, err := blockBookWrap.GetEthAddress(from.Hex())
+	metrics.ObserveBlockbook(ethConfig.Info.Tag, "eth_address", start, err)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(info.Nonce, 0, 64)
+}
+
+// rawTxReport builds the validation report of rawTx. The transactions of the UTXO coins
+// must pay the known addresses of info, the EVM ones address.
+func rawTxReport(coinConfig *coins.Coin, rawTx string, address string, amount int64, info AddrInfo) (*models.TxReport, error) {
+	if coinConfig.Info.Token || coinConfig.Info.Tag == "ETH" {
+		tx, err := decodeEthTx(rawTx)
+		if err != nil {
+			return nil, err
+		}
+		return evmTxReport(coinConfig, tx, address, amount, ethAccountNonce)
+	}
+	rawTxBytes, err := hex.DecodeString(rawTx)
+	if err != nil {
+		return nil, err
+	}
+	tx, err := btcutil.NewTxFromBytes(rawTxBytes)
+	if err != nil {
+		return nil, err
+	}
+	if len(tx.MsgTx().TxIn) > maxReportInputs {
+		return nil, errTooManyInputs
+	}
+	mine, err := ownScripts(coinConfig, info)
+	if err != nil {
+		return nil, err
+	}
+	return utxoTxReport(coinConfig, tx.MsgTx(), amount, mine, getSpentOutputs(coinConfig, tx.MsgTx())), nil
+}
@@ -0,0 +1,88 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/eabz/btcutil"
+	"github.com/eabz/btcutil/txscript"
+	"github.com/martinboehm/btcd/wire"
+)
+
+func TestUtxoTxReport(t *testing.T) {
+	defaultSigner := txSigner
+	defer SetSigner(defaultSigner)
+	for _, test := range testXpup {
+		_, privKey := useTestKey(t, test)
+		ourScript := testAddrScript(t, test)
+		keyHash := btcutil.Hash160(privKey.PubKey().SerializeCompressed())
+		witnessScript, _ := txscript.NewScriptBuilder().AddOp(txscript.OP_0).AddData(keyHash).Script()
+		otherScript, _ := txscript.NewScriptBuilder().AddOp(txscript.OP_RETURN).Script()
+		tx := testSpendingTx(testPrevHash(), 0, 1)
+		tx.AddTxOut(wire.NewTxOut(50000, ourScript))
+		tx.AddTxOut(wire.NewTxOut(10000, otherScript))
+		spent := map[wire.OutPoint]*prevOutput{
+			tx.TxIn[0].PreviousOutPoint: {out: wire.NewTxOut(40000, ourScript)},
+			tx.TxIn[1].PreviousOutPoint: {out: wire.NewTxOut(30000, witnessScript)},
+		}
+		if err := signInput(tx, 0, ourScript, test.coin, test.path); err != nil {
+			t.Fatal(err)
+		}
+		scriptCode, _ := txscript.NewScriptBuilder().AddOp(txscript.OP_DUP).AddOp(txscript.OP_HASH160).
+			AddData(keyHash).AddOp(txscript.OP_EQUALVERIFY).AddOp(txscript.OP_CHECKSIG).Script()
+		digest, err := witnessSigHash(tx, 1, scriptCode, 30000, test.coin.Info.Tag == "GRS")
+		if err != nil {
+			t.Fatal(err)
+		}
+		signature, err := privKey.Sign(digest)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tx.TxIn[1].Witness = wire.TxWitness{append(signature.Serialize(), byte(txscript.SigHashAll)), privKey.PubKey().SerializeCompressed()}
+		mine := map[string]bool{string(ourScript): true}
+
+		report := utxoTxReport(test.coin, tx, 50000, mine, spent)
+		if !report.Valid || report.Paid != 50000 || report.Fee != 10000 || report.RBF || !report.Outputs[0].Mine || report.Outputs[0].Address == "" {
+			t.Error("unexpected report for " + test.coin.Info.Tag)
+		}
+		if report.VSize >= report.Size || report.FeeRate != float64(10000)/float64(report.VSize) {
+			t.Error("the fee rate must use the virtual size for " + test.coin.Info.Tag)
+		}
+		if utxoTxReport(test.coin, tx, 10000, mine, spent).PaysAmount {
+			t.Error("the amount must be paid to one of our addresses for " + test.coin.Info.Tag)
+		}
+		spent[tx.TxIn[1].PreviousOutPoint].spent = true
+		report = utxoTxReport(test.coin, tx, 50000, mine, spent)
+		if report.Valid || report.InputsUnspent || !report.Inputs[1].Spent {
+			t.Error("a spent input must be reported for " + test.coin.Info.Tag)
+		}
+		spent[tx.TxIn[1].PreviousOutPoint].spent = false
+		tx.TxIn[1].Sequence = maxRBFSequence
+		report = utxoTxReport(test.coin, tx, 50000, mine, spent)
+		if report.SignaturesValid || report.Inputs[0].SignatureValid || report.Inputs[1].SignatureValid || !report.RBF {
+			t.Error("the signatures of a modified transaction must be invalid for " + test.coin.Info.Tag)
+		}
+		delete(spent, tx.TxIn[0].PreviousOutPoint)
+		report = utxoTxReport(test.coin, tx, 50000, mine, spent)
+		if report.Fee != 0 || report.Inputs[0].Error == "" {
+			t.Error("the fee must be unknown without the spent outputs for " + test.coin.Info.Tag)
+		}
+	}
+}
+
+func TestRawTxReportInputs(t *testing.T) {
+	vouts := make([]uint32, maxReportInputs+1)
+	for i := range vouts {
+		vouts[i] = uint32(i)
+	}
+	tx := testSpendingTx(testPrevHash(), vouts...)
+	tx.AddTxOut(wire.NewTxOut(50000, testAddrScript(t, testXpup[0])))
+	var buf bytes.Buffer
+	if err := tx.Serialize(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rawTxReport(testXpup[0].coin, hex.EncodeToString(buf.Bytes()), "", 50000, AddrInfo{}); err != errTooManyInputs {
+		t.Error("the spent outputs of a transaction with too many inputs must not be fetched")
+	}
+}
@@ -0,0 +1,133 @@
+package controllers
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"os"
+
+	"github.com/eabz/btcutil/hdkeychain"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/grupokindynos/common/coin-factory/coins"
+)
+
+// In watch-only mode (WATCH_ONLY=true) Plutus never touches a mnemonic. The read endpoints are
+// served from the account xpubs of the utxo coins (XPUB_<TAG>) and from the ethereum addresses
+// (ETH_ADDRESS, and ETHV2_ADDRESS for the services with their own account). Sends are rejected,
+// payments can still be prepared as PSBT and signed elsewhere. With a remote signer
+// (SIGNER=remote) the public keys are read the same way, only the signer process holds the
+// mnemonics.
+
+var errWatchOnly = errors.New("plutus runs in watch-only mode and can't sign transactions, prepare them with /v2/psbt/create and sign them elsewhere")
+
+func watchOnly() bool {
+	return os.Getenv("WATCH_ONLY") == "true"
+}
+
+// publicKeysOnly reports whether the keys are read from the configured public keys instead of
+// the mnemonics.
+func publicKeysOnly() bool {
+	return watchOnly() || os.Getenv("SIGNER") == "remote"
+}
+
+// getAccPub returns the BIP44 account public key of the coin.
+func getAccPub(coinConfig *coins.Coin) (*hdkeychain.ExtendedKey, error) {
+	if !publicKeysOnly() {
+		return getAccFromMnemonic(coinConfig, false)
+	}
+	xpub := os.Getenv("XPUB_" + coinConfig.Info.Tag)
+	if xpub == "" {
+		return nil, errors.New("the coin is not available")
+	}
+	acc, err := hdkeychain.NewKeyFromString(xpub)
+	if err != nil {
+		return nil, err
+	}
+	if acc.IsPrivate() {
+		return nil, errors.New("XPUB_" + coinConfig.Info.Tag + " must be an extended public key")
+	}
+	if acc.Depth() != 3 {
+		return nil, errors.New("XPUB_" + coinConfig.Info.Tag + " must be the key of a BIP44 account")
+	}
+	return acc, nil
+}
+
+// getMasterFingerprint returns the fingerprint of the master key of the coin, used in the key
+// origins of the PSBTs. Without the mnemonics it is read from XPUB_FINGERPRINT_<TAG>, 8 hex
+// characters as shown by the hardware wallets.
+func getMasterFingerprint(coinConfig *coins.Coin) (uint32, error) {
+	if !publicKeysOnly() {
+		if coinConfig.Mnemonic == "" {
+			return 0, errors.New("the coin is not available")
+		}
+		return keys.masterFingerprint(coinConfig)
+	}
+	name := "XPUB_FINGERPRINT_" + coinConfig.Info.Tag
+	fingerprint, err := hex.DecodeString(os.Getenv(name))
+	if err != nil || len(fingerprint) != 4 {
+		return 0, errors.New(name + " must hold the 4 bytes fingerprint of the master key")
+	}
+	return binary.LittleEndian.Uint32(fingerprint), nil
+}
+
+// ethAddress returns the address of the ethereum account tag, ETH or ETHV2.
+func ethAddress(tag string) (common.Address, error) {
+	if publicKeysOnly() {
+		address := os.Getenv(tag + "_ADDRESS")
+		if !common.IsHexAddress(address) {
+			return common.Address{}, errors.New("the coin is not available")
+		}
+		return common.HexToAddress(address), nil
+	}
+	ethMnemonic, err := ethMnemonic(tag)
+	if err != nil {
+		return common.Address{}, err
+	}
+	_, account, err := keys.ethAccount(ethMnemonic)
+	return account.Address, err
+}
+
+// tokenAddress returns the address receiving the ethereum coin or token on the V1 endpoints.
+// A token configured with its own mnemonic, or with <TAG>_ADDRESS without the mnemonics, is
+// received on its own account, the others on the ETH account.
+func tokenAddress(coinConfig *coins.Coin) (common.Address, error) {
+	if publicKeysOnly() {
+		if address := os.Getenv(coinConfig.Info.Tag + "_ADDRESS"); common.IsHexAddress(address) {
+			return common.HexToAddress(address), nil
+		}
+		return ethAddress("ETH")
+	}
+	if coinConfig.Mnemonic == "" {
+		return ethAddress("ETH")
+	}
+	_, account, err := keys.ethAccount(coinConfig.Mnemonic)
+	return account.Address, err
+}
+
+// ethMnemonic returns the mnemonic of the ethereum account tag, ETH or ETHV2.
+func ethMnemonic(tag string) (string, error) {
+	var ethMnemonic string
+	if tag == coinV2 {
+		ethMnemonic = mnemonic(coinV2)
+	} else {
+		ethConfig, err := getCoin("ETH")
+		if err != nil {
+			return "", err
+		}
+		ethMnemonic = ethConfig.Mnemonic
+	}
+	if ethMnemonic == "" {
+		return "", errors.New("the coin is not available")
+	}
+	return ethMnemonic, nil
+}
+
+// publicKeysAvailable reports whether the public keys of the coin are configured.
+func publicKeysAvailable(coin *coins.Coin) bool {
+	if coin.Info.Token || coin.Info.Tag == "ETH" {
+		_, err := ethAddress("ETH")
+		return err == nil
+	}
+	_, err := getAccPub(coin)
+	return err == nil
+}
@@ -0,0 +1,99 @@
+package controllers
+
+import (
+	"os"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	coinfactory "github.com/grupokindynos/common/coin-factory"
+)
+
+func TestWatchOnly(t *testing.T) {
+	os.Setenv("WATCH_ONLY", "true")
+	defer os.Unsetenv("WATCH_ONLY")
+	for _, test := range testXpup {
+		env := "XPUB_" + test.coin.Info.Tag
+		os.Setenv(env, test.xprv)
+		if _, err := getAccPub(test.coin); err == nil {
+			t.Error("a private key must be rejected for " + test.coin.Info.Tag)
+		}
+		os.Setenv(env, test.xpub)
+		acc, err := getAccPub(test.coin)
+		if err != nil {
+			t.Fatal(err)
+		}
+		addr, err := getPubKeyHashFromPath(acc, test.coin, test.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if addr != test.addr {
+			t.Error("address doesn't match for " + test.coin.Info.Tag + " expected: " + test.addr + " got: " + addr)
+		}
+		os.Unsetenv(env)
+	}
+	os.Setenv("ETH_ADDRESS", "0x931D387731bBbC988B312206c74F77D004D6B84b")
+	defer os.Unsetenv("ETH_ADDRESS")
+	address, err := ethAddress("ETH")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if address != common.HexToAddress("0x931d387731bbbc988b312206c74f77d004d6b84b") {
+		t.Error("unexpected ethereum address " + address.Hex())
+	}
+	if _, err := ethAddress(coinV2); err == nil {
+		t.Error("expected an error without ETHV2_ADDRESS")
+	}
+}
+
+func TestTokenAddress(t *testing.T) {
+	token := *coinfactory.Coins["ETH"]
+	token.Info.Tag = "USDT"
+	token.Info.Token = true
+	token.Mnemonic = testMnemonic
+	_, account, err := keys.ethAccount(testMnemonic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	address, err := tokenAddress(&token)
+	if err != nil || address != account.Address {
+		t.Error("a token with its own mnemonic must be received on its own account")
+	}
+	os.Setenv("WATCH_ONLY", "true")
+	defer os.Unsetenv("WATCH_ONLY")
+	os.Setenv("ETH_ADDRESS", "0x931D387731bBbC988B312206c74F77D004D6B84b")
+	defer os.Unsetenv("ETH_ADDRESS")
+	address, err = tokenAddress(&token)
+	if err != nil || address != common.HexToAddress("0x931D387731bBbC988B312206c74F77D004D6B84b") {
+		t.Error("a token without its own address must be received on the ETH account")
+	}
+	os.Setenv("USDT_ADDRESS", account.Address.Hex())
+	defer os.Unsetenv("USDT_ADDRESS")
+	address, err = tokenAddress(&token)
+	if err != nil || address != account.Address {
+		t.Error("a token with its own address must be received on it")
+	}
+}
+
+func TestRemoteSignerKeys(t *testing.T) {
+	os.Setenv("SIGNER", "remote")
+	defer os.Unsetenv("SIGNER")
+	if watchOnly() {
+		t.Error("the sends must be allowed with a remote signer")
+	}
+	for _, test := range testXpup {
+		test.coin.Mnemonic = testMnemonic
+		if _, err := getAccPub(test.coin); err == nil {
+			t.Error("the mnemonic must not be used with a remote signer for " + test.coin.Info.Tag)
+		}
+		env := "XPUB_" + test.coin.Info.Tag
+		os.Setenv(env, test.xpub)
+		acc, err := getAccPub(test.coin)
+		os.Unsetenv(env)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if addr, err := getPubKeyHashFromPath(acc, test.coin, test.path); err != nil || addr != test.addr {
+			t.Error("the address must be derived from the xpub for " + test.coin.Info.Tag)
+		}
+	}
+}
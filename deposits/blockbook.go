@@ -0,0 +1,141 @@
+package deposits
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+
+	coinfactory "github.com/grupokindynos/common/coin-factory"
+	"github.com/grupokindynos/common/coin-factory/coins"
+	"github.com/grupokindynos/plutus/metrics"
+)
+
+// Blockbook reads the payments from the blockbook of every coin.
+type Blockbook struct {
+	HTTPClient *http.Client
+}
+
+func NewBlockbook() *Blockbook {
+	return &Blockbook{HTTPClient: &http.Client{Timeout: 10 * time.Second}}
+}
+
+type addressTxs struct {
+	Transactions []blockbookTx `json:"transactions"`
+}
+
+type blockbookTx struct {
+	Txid          string `json:"txid"`
+	Confirmations int    `json:"confirmations"`
+	BlockTime     int64  `json:"blockTime"`
+	Vout          []struct {
+		Value     string   `json:"value"`
+		Addresses []string `json:"addresses"`
+	} `json:"vout"`
+	TokenTransfers []struct {
+		To       string `json:"to"`
+		Token    string `json:"token"`
+		Decimals int    `json:"decimals"`
+		Value    string `json:"value"`
+	} `json:"tokenTransfers"`
+	EthereumSpecific *struct {
+		// Status is 1 for a successful transaction, 0 for a failed one and -1 while pending.
+		Status int `json:"status"`
+	} `json:"ethereumSpecific"`
+}
+
+// Received returns the last transactions paying the address with the amount it received.
+// The ethereum addresses receive ether or, for the tokens, transfers of the token contract.
+func (b *Blockbook) Received(coin string, address string) ([]Payment, error) {
+	coinConfig, err := coinfactory.GetCoin(coin)
+	if err != nil {
+		return nil, err
+	}
+	url := strings.TrimRight(coinConfig.Info.Blockbook, "/") + "/api/v2/address/" + address + "?details=txs&pageSize=50"
+	if coinConfig.Info.Token {
+		url += "&contract=" + coinConfig.Info.Contract
+	}
+	start := time.Now()
+	res, err := b.HTTPClient.Get(url)
+	if err == nil && res.StatusCode != http.StatusOK {
+		res.Body.Close()
+		err = &StatusError{StatusCode: res.StatusCode}
+	}
+	metrics.ObserveBlockbook(coin, "address_txs", start, err)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	var txs addressTxs
+	if err := json.NewDecoder(res.Body).Decode(&txs); err != nil {
+		return nil, err
+	}
+	var payments []Payment
+	for _, tx := range txs.Transactions {
+		amount, err := received(coinConfig, tx, address)
+		if err != nil {
+			return nil, err
+		}
+		if amount <= 0 {
+			continue
+		}
+		payment := Payment{
+			Txid:          tx.Txid,
+			Amount:        amount,
+			Confirmations: tx.Confirmations,
+		}
+		if tx.BlockTime > 0 {
+			payment.Time = time.Unix(tx.BlockTime, 0).UTC()
+		}
+		payments = append(payments, payment)
+	}
+	return payments, nil
+}
+
+// received returns the amount tx pays to address, in coins.
+func received(coinConfig *coins.Coin, tx blockbookTx, address string) (float64, error) {
+	if tx.EthereumSpecific != nil && tx.EthereumSpecific.Status == 0 {
+		return 0, nil
+	}
+	total := new(big.Int)
+	decimals := 8
+	add := func(value string) error {
+		amount, ok := new(big.Int).SetString(value, 10)
+		if !ok {
+			return errors.New("invalid amount " + value + " in " + tx.Txid)
+		}
+		total.Add(total, amount)
+		return nil
+	}
+	switch {
+	case coinConfig.Info.Token:
+		for _, transfer := range tx.TokenTransfers {
+			if !strings.EqualFold(transfer.Token, coinConfig.Info.Contract) || !strings.EqualFold(transfer.To, address) {
+				continue
+			}
+			decimals = transfer.Decimals
+			if err := add(transfer.Value); err != nil {
+				return 0, err
+			}
+		}
+	default:
+		if coinConfig.Info.Tag == "ETH" {
+			decimals = 18
+		}
+		for _, out := range tx.Vout {
+			for _, outAddress := range out.Addresses {
+				if !strings.EqualFold(outAddress, address) {
+					continue
+				}
+				if err := add(out.Value); err != nil {
+					return 0, err
+				}
+			}
+		}
+	}
+	unit := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimals)), nil)
+	amount, _ := new(big.Rat).SetFrac(total, unit).Float64()
+	return amount, nil
+}
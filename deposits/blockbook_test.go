@@ -0,0 +1,45 @@
+package deposits
+
+import (
+	"encoding/json"
+	"testing"
+
+	coinfactory "github.com/grupokindynos/common/coin-factory"
+)
+
+const testEthTxs = `{"transactions": [
+	{"txid": "0x01", "confirmations": 2, "vout": [{"value": "1500000000000000000", "addresses": ["0x931D387731bBbC988B312206c74F77D004D6B84b"]}], "ethereumSpecific": {"status": 1}},
+	{"txid": "0x02", "vout": [{"value": "1000000000000000000", "addresses": ["0x931d387731bbbc988b312206c74f77d004d6b84b"]}], "ethereumSpecific": {"status": 0}},
+	{"txid": "0x03", "vout": [{"value": "0", "addresses": ["0xdAC17F958D2ee523a2206206994597C13D831ec7"]}], "ethereumSpecific": {"status": -1},
+		"tokenTransfers": [
+			{"to": "0x931d387731bbbc988b312206c74f77d004d6b84b", "token": "0xdac17f958d2ee523a2206206994597c13d831ec7", "decimals": 6, "value": "3100000"},
+			{"to": "0x931d387731bbbc988b312206c74f77d004d6b84b", "token": "0x0000000000000000000000000000000000000001", "decimals": 6, "value": "1000000"}
+		]}
+]}`
+
+func TestReceived(t *testing.T) {
+	var txs addressTxs
+	if err := json.Unmarshal([]byte(testEthTxs), &txs); err != nil {
+		t.Fatal(err)
+	}
+	address := "0x931D387731bBbC988B312206c74F77D004D6B84b"
+	eth := coinfactory.Coins["ETH"]
+	token := *eth
+	token.Info.Tag = "USDT"
+	token.Info.Token = true
+	token.Info.Contract = "0xdAC17F958D2ee523a2206206994597C13D831ec7"
+	expected := []struct {
+		eth   float64
+		token float64
+	}{{1.5, 0}, {0, 0}, {0, 3.1}}
+	for i, tx := range txs.Transactions {
+		amount, err := received(eth, tx, address)
+		if err != nil || amount != expected[i].eth {
+			t.Error("unexpected ether received by " + tx.Txid)
+		}
+		amount, err = received(&token, tx, address)
+		if err != nil || amount != expected[i].token {
+			t.Error("unexpected tokens received by " + tx.Txid)
+		}
+	}
+}
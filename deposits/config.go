@@ -0,0 +1,73 @@
+package deposits
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// Config describes how the deposits are watched and where they are notified.
+type Config struct {
+	// Schedule is a five field cron expression.
+	Schedule string `json:"schedule"`
+	// Confirmations lists the confirmation milestones notified after the detection.
+	Confirmations []int `json:"confirmations"`
+	// WatchHours is how long a new address is watched for deposits.
+	WatchHours int `json:"watch_hours"`
+	// Webhooks maps the services to their callback url, "default" is used for the addresses
+	// requested by other services.
+	Webhooks map[string]string `json:"webhooks"`
+	// MaxAttempts is the amount of deliveries of an event before giving up.
+	MaxAttempts int `json:"max_attempts"`
+}
+
+// DefaultConfig returns the rules used for the values missing from the file.
+func DefaultConfig() Config {
+	return Config{
+		Schedule:      "* * * * *",
+		Confirmations: []int{1, 6},
+		WatchHours:    7 * 24,
+		MaxAttempts:   10,
+	}
+}
+
+// LoadConfig reads the JSON file pointed by DEPOSITS_CONFIG on top of the defaults.
+// WEBHOOK_URL_<SERVICE> variables add or override the callback urls.
+func LoadConfig() (Config, error) {
+	config := DefaultConfig()
+	if path := os.Getenv("DEPOSITS_CONFIG"); path != "" {
+		file, err := ioutil.ReadFile(path)
+		if err != nil {
+			return Config{}, err
+		}
+		err = json.Unmarshal(file, &config)
+		if err != nil {
+			return Config{}, err
+		}
+	}
+	for _, variable := range os.Environ() {
+		parts := strings.SplitN(variable, "=", 2)
+		if !strings.HasPrefix(parts[0], "WEBHOOK_URL_") || parts[1] == "" {
+			continue
+		}
+		if config.Webhooks == nil {
+			config.Webhooks = make(map[string]string)
+		}
+		config.Webhooks[strings.ToLower(strings.TrimPrefix(parts[0], "WEBHOOK_URL_"))] = parts[1]
+	}
+	return config, nil
+}
+
+// Enabled reports whether any service wants to be notified.
+func (c Config) Enabled() bool {
+	return len(c.Webhooks) > 0
+}
+
+// Webhook returns the callback url of a service, empty when it is not notified.
+func (c Config) Webhook(service string) string {
+	if url, ok := c.Webhooks[strings.ToLower(service)]; ok {
+		return url
+	}
+	return c.Webhooks["default"]
+}
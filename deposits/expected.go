@@ -0,0 +1,154 @@
+package deposits
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	"github.com/grupokindynos/plutus/models"
+	"github.com/grupokindynos/plutus/store"
+)
+
+const expectedBucket = "expected_payments"
+
+// Expected payment statuses.
+const (
+	PaymentPending   = "pending"
+	PaymentExact     = "exact"
+	PaymentUnderpaid = "underpaid"
+	PaymentOverpaid  = "overpaid"
+	PaymentExpired   = "expired"
+)
+
+var (
+	ErrUnknownPayment = errors.New("no payment is expected on the address")
+	ErrPaymentExists  = errors.New("a payment is already expected on the address")
+)
+
+// Registry keeps the payments expected on the issued addresses and matches them against
+// the transactions received by the address.
+type Registry struct {
+	store   *store.Store
+	backend Backend
+	now     func() time.Time
+}
+
+func NewRegistry(db *store.Store, backend Backend) *Registry {
+	return &Registry{store: db, backend: backend, now: time.Now}
+}
+
+func expectedKey(coin string, address string) string {
+	return coin + ":" + address
+}
+
+// Expect registers a payment of amount on the address, valid for expiry.
+func (r *Registry) Expect(coin string, address string, amount float64, expiry time.Duration, reference string, service string) (*models.ExpectedPayment, error) {
+	if address == "" {
+		return nil, errors.New("missing address")
+	}
+	if amount <= 0 {
+		return nil, errors.New("the amount must be positive")
+	}
+	if expiry <= 0 {
+		return nil, errors.New("the expiry must be positive")
+	}
+	now := r.now().UTC()
+	payment := models.ExpectedPayment{
+		Coin:      coin,
+		Address:   address,
+		Amount:    amount,
+		Reference: reference,
+		Service:   service,
+		Status:    PaymentPending,
+		Txids:     []string{},
+		ExpiresAt: now.Add(expiry),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	var existing models.ExpectedPayment
+	err := r.store.Update(expectedBucket, expectedKey(coin, address), &existing, func(found bool) error {
+		if found {
+			return ErrPaymentExists
+		}
+		existing = payment
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &payment, nil
+}
+
+// Status matches the expected payment against the transactions of its address and returns
+// its updated state.
+func (r *Registry) Status(coin string, address string) (*models.ExpectedPayment, error) {
+	var payment models.ExpectedPayment
+	err := r.store.Get(expectedBucket, expectedKey(coin, address), &payment)
+	if err == store.ErrNotFound {
+		return nil, ErrUnknownPayment
+	}
+	if err != nil {
+		return nil, err
+	}
+	received, err := r.backend.Received(coin, address)
+	if err != nil {
+		return nil, err
+	}
+	match(&payment, received, r.now().UTC())
+	err = r.store.Put(expectedBucket, expectedKey(coin, address), payment)
+	if err != nil {
+		return nil, err
+	}
+	return &payment, nil
+}
+
+// match sums the payments made between the registration and the expiry and compares them,
+// in satoshis, with the expected amount. The unconfirmed payments are made now, they are
+// counted and stay matched when mined after the expiry, Confirmations reports the least
+// confirmed one.
+func match(payment *models.ExpectedPayment, received []Payment, now time.Time) {
+	matched := make(map[string]bool)
+	for _, txid := range payment.Txids {
+		matched[txid] = true
+	}
+	var total int64
+	payment.Txids = []string{}
+	payment.Confirmations = 0
+	for _, p := range received {
+		madeAt := p.Time
+		if madeAt.IsZero() {
+			madeAt = now
+		}
+		inTime := !madeAt.Before(payment.CreatedAt) && !madeAt.After(payment.ExpiresAt)
+		if !inTime && !matched[p.Txid] {
+			continue
+		}
+		if len(payment.Txids) == 0 || p.Confirmations < payment.Confirmations {
+			payment.Confirmations = p.Confirmations
+		}
+		payment.Txids = append(payment.Txids, p.Txid)
+		total += toSatoshis(p.Amount)
+	}
+	payment.Received = float64(total) / 1e8
+	expected := toSatoshis(payment.Amount)
+	switch {
+	case total == 0 && now.After(payment.ExpiresAt):
+		payment.Status = PaymentExpired
+	case total == 0:
+		payment.Status = PaymentPending
+	case total < expected:
+		payment.Status = PaymentUnderpaid
+	case total == expected:
+		payment.Status = PaymentExact
+	default:
+		payment.Status = PaymentOverpaid
+	}
+	if total >= expected && payment.PaidAt == nil {
+		payment.PaidAt = &now
+	}
+	payment.UpdatedAt = now
+}
+
+func toSatoshis(amount float64) int64 {
+	return int64(math.Round(amount * 1e8))
+}
@@ -0,0 +1,89 @@
+package deposits
+
+import (
+	"testing"
+	"time"
+
+	"github.com/grupokindynos/plutus/models"
+	"github.com/grupokindynos/plutus/store"
+)
+
+var matchTests = []struct {
+	received []Payment
+	late     bool
+	status   string
+	total    float64
+}{
+	{nil, false, PaymentPending, 0},
+	{nil, true, PaymentExpired, 0},
+	{[]Payment{{Txid: "a", Amount: 0.1}, {Txid: "b", Amount: 0.2}}, false, PaymentExact, 0.3},
+	{[]Payment{{Txid: "a", Amount: 0.1}}, true, PaymentUnderpaid, 0.1},
+	{[]Payment{{Txid: "a", Amount: 0.30000001}}, false, PaymentOverpaid, 0.30000001},
+}
+
+func TestMatch(t *testing.T) {
+	created := time.Date(2020, 3, 1, 12, 0, 0, 0, time.UTC)
+	for i, test := range matchTests {
+		payment := models.ExpectedPayment{Amount: 0.3, CreatedAt: created, ExpiresAt: created.Add(time.Hour)}
+		for j := range test.received {
+			test.received[j].Time = created.Add(time.Minute)
+		}
+		now := created.Add(time.Minute)
+		if test.late {
+			now = created.Add(2 * time.Hour)
+		}
+		match(&payment, test.received, now)
+		if payment.Status != test.status || payment.Received != test.total || len(payment.Txids) != len(test.received) {
+			t.Error("unexpected match", i, payment.Status, payment.Received)
+		}
+	}
+}
+
+func TestMatchWindow(t *testing.T) {
+	created := time.Date(2020, 3, 1, 12, 0, 0, 0, time.UTC)
+	payment := models.ExpectedPayment{Amount: 1, CreatedAt: created, ExpiresAt: created.Add(time.Hour)}
+	received := []Payment{
+		{Txid: "old", Amount: 1, Confirmations: 100, Time: created.Add(-24 * time.Hour)},
+		{Txid: "before", Amount: 1, Confirmations: 1, Time: created.Add(-time.Minute)},
+		{Txid: "pending", Amount: 0.5},
+	}
+	match(&payment, received, created.Add(time.Minute))
+	if payment.Status != PaymentUnderpaid || len(payment.Txids) != 1 || payment.Txids[0] != "pending" {
+		t.Fatal("payments made before the registration must not be matched")
+	}
+	received = []Payment{
+		received[0],
+		{Txid: "pending", Amount: 0.5, Confirmations: 1, Time: created.Add(3 * time.Hour)},
+		{Txid: "late", Amount: 0.5, Time: created.Add(3 * time.Hour)},
+	}
+	match(&payment, received, created.Add(3*time.Hour))
+	if payment.Status != PaymentUnderpaid || payment.Confirmations != 1 || payment.PaidAt != nil {
+		t.Error("a matched payment mined after the expiry must stay matched, new late payments must not")
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	db, _ := store.Open("")
+	backend := &testBackend{payments: map[string][]Payment{}}
+	registry := NewRegistry(db, backend)
+	if _, err := registry.Expect("BTC", "addr1", 0, time.Hour, "", ""); err == nil {
+		t.Error("expected an error for a zero amount")
+	}
+	if _, err := registry.Expect("BTC", "addr1", 0.5, time.Hour, "order-1", "ladon"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := registry.Expect("BTC", "addr1", 1, time.Hour, "order-2", "ladon"); err != ErrPaymentExists {
+		t.Error("a single payment can be expected on an address")
+	}
+	backend.payments["addr1"] = []Payment{{Txid: "tx1", Amount: 0.5, Time: time.Now()}}
+	payment, err := registry.Status("BTC", "addr1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if payment.Status != PaymentExact || payment.Reference != "order-1" || payment.PaidAt == nil {
+		t.Error("the payment must be matched")
+	}
+	if _, err := registry.Status("BTC", "addr2"); err != ErrUnknownPayment {
+		t.Error("expected an error for an unknown payment")
+	}
+}
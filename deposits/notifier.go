@@ -0,0 +1,68 @@
+package deposits
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/grupokindynos/common/tokens/mrt"
+	"github.com/grupokindynos/plutus/models"
+)
+
+// StatusError is returned when a webhook answers with a non 2xx status code.
+type StatusError struct {
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return "deposits: unexpected webhook status code " + strconv.Itoa(e.StatusCode)
+}
+
+// Notifier posts the events signed like the Plutus responses: the MRT token is the JSON
+// body and its signature is sent in the service header.
+type Notifier struct {
+	HTTPClient *http.Client
+	// Sign returns the signature header and the token of the payload.
+	Sign func(payload interface{}) (string, string, error)
+}
+
+func NewNotifier() *Notifier {
+	return &Notifier{
+		HTTPClient: &http.Client{Timeout: 10 * time.Second},
+		Sign:       signMRT,
+	}
+}
+
+// Send delivers a single event.
+func (n *Notifier) Send(url string, event models.DepositEvent) error {
+	header, token, err := n.Sign(event)
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(token)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("service", header)
+	res, err := n.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return &StatusError{StatusCode: res.StatusCode}
+	}
+	return nil
+}
+
+func signMRT(payload interface{}) (string, string, error) {
+	return mrt.CreateMRTToken("plutus", os.Getenv("MASTER_PASSWORD"), payload, os.Getenv("PLUTUS_PRIVATE_KEY"))
+}
@@ -0,0 +1,439 @@
+package deposits
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/grupokindynos/plutus/logger"
+	"github.com/grupokindynos/plutus/metrics"
+	"github.com/grupokindynos/plutus/models"
+	"github.com/grupokindynos/plutus/store"
+)
+
+const (
+	depositBucket  = "deposits"
+	deliveryBucket = "webhook_deliveries"
+)
+
+// Event types.
+const (
+	EventDetected  = "deposit.detected"
+	EventConfirmed = "deposit.confirmed"
+	// EventReorged is sent when a payment loses confirmations already notified, the milestones
+	// are notified again when reached.
+	EventReorged = "deposit.reorged"
+	// EventDropped is sent when a payment waiting for a milestone left the backend for dropTimeout.
+	EventDropped = "deposit.dropped"
+)
+
+// Delivery statuses.
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryFailed    = "failed"
+)
+
+const (
+	retryInitial = time.Minute
+	retryMax     = time.Hour
+)
+
+// dropTimeout is how long a payment can be missing from the backend before being dropped.
+const dropTimeout = time.Hour
+
+var ErrUnknownDelivery = errors.New("unknown webhook delivery")
+
+// Payment is a transaction paying a watched address.
+type Payment struct {
+	Txid          string
+	Amount        float64
+	Confirmations int
+	// Time is the block time, zero while unconfirmed.
+	Time time.Time
+}
+
+// Backend returns the payments received by an address.
+type Backend interface {
+	Received(coin string, address string) ([]Payment, error)
+}
+
+// deposit is the state of a payment to a watched address.
+type deposit struct {
+	Amount        float64 `json:"amount"`
+	Confirmations int     `json:"confirmations"`
+	// Notified lists the confirmation milestones already notified.
+	Notified []int     `json:"notified"`
+	SeenAt   time.Time `json:"seen_at"`
+	// Reorgs counts the times the payment was reorganized or dropped, it keeps the ids of the
+	// events notified again unique.
+	Reorgs       int        `json:"reorgs,omitempty"`
+	MissingSince *time.Time `json:"missing_since,omitempty"`
+	Dropped      bool       `json:"dropped,omitempty"`
+}
+
+// pending reports whether the deposit waits for one of the milestones.
+func (d *deposit) pending(milestones []int) bool {
+	if d.Dropped {
+		return false
+	}
+	for _, milestone := range milestones {
+		if !d.notified(milestone) {
+			return true
+		}
+	}
+	return false
+}
+
+// eventID returns the id of an event of the deposit key, suffixed by the reorg it follows.
+func (d *deposit) eventID(key string, name string) string {
+	if d.Reorgs == 0 {
+		return key + ":" + name
+	}
+	return key + ":" + name + ":" + strconv.Itoa(d.Reorgs)
+}
+
+func (d *deposit) notified(milestone int) bool {
+	for _, notified := range d.Notified {
+		if notified == milestone {
+			return true
+		}
+	}
+	return false
+}
+
+// Report is the outcome of a run of the watcher.
+type Report struct {
+	Addresses  int       `json:"addresses"`
+	Events     int       `json:"events"`
+	Delivered  int       `json:"delivered"`
+	Failed     int       `json:"failed"`
+	Errors     []string  `json:"errors,omitempty"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+}
+
+// Watcher detects the payments to the addresses issued by Plutus and notifies them, with
+// their confirmation milestones, to the webhooks of the services that requested them.
+// Events are kept in a delivery log and retried with an exponential backoff.
+type Watcher struct {
+	config    Config
+	store     *store.Store
+	addresses func() ([]models.AddressMeta, error)
+	backend   Backend
+	notifier  *Notifier
+	now       func() time.Time
+}
+
+// NewWatcher creates the watcher job, addresses returns the addresses issued by Plutus.
+func NewWatcher(config Config, db *store.Store, addresses func() ([]models.AddressMeta, error), backend Backend, notifier *Notifier) *Watcher {
+	return &Watcher{
+		config:    config,
+		store:     db,
+		addresses: addresses,
+		backend:   backend,
+		notifier:  notifier,
+		now:       time.Now,
+	}
+}
+
+// Run checks the watched addresses, queues the new events and delivers the pending ones.
+// It returns the resulting *Report.
+func (w *Watcher) Run() (interface{}, error) {
+	report := &Report{StartedAt: w.now()}
+	addresses, err := w.addresses()
+	if err != nil {
+		return nil, err
+	}
+	tracked := w.deposits()
+	for _, meta := range addresses {
+		deposits := tracked[meta.Coin+":"+meta.Address]
+		if !w.watched(meta, deposits) {
+			continue
+		}
+		report.Addresses++
+		payments, err := w.backend.Received(meta.Coin, meta.Address)
+		if err != nil {
+			logger.Warn("deposits: unable to check the address", "coin", meta.Coin, "address", meta.Address, "err", err)
+			report.Errors = append(report.Errors, meta.Coin+" "+meta.Address+": "+err.Error())
+			continue
+		}
+		received := make(map[string]bool)
+		for _, payment := range payments {
+			received[payment.Txid] = true
+			events, err := w.track(meta, payment)
+			if err != nil {
+				report.Errors = append(report.Errors, meta.Coin+" "+payment.Txid+": "+err.Error())
+				continue
+			}
+			w.enqueueAll(meta.Service, events, report)
+		}
+		for txid, d := range deposits {
+			if received[txid] || !d.pending(w.config.Confirmations) {
+				continue
+			}
+			events, err := w.missing(meta, txid)
+			if err != nil {
+				report.Errors = append(report.Errors, meta.Coin+" "+txid+": "+err.Error())
+				continue
+			}
+			w.enqueueAll(meta.Service, events, report)
+		}
+	}
+	w.deliver(report)
+	report.FinishedAt = w.now()
+	return report, nil
+}
+
+// deposits returns the tracked deposits by coin and address, then by txid.
+func (w *Watcher) deposits() map[string]map[string]deposit {
+	tracked := make(map[string]map[string]deposit)
+	for _, key := range w.store.Keys(depositBucket) {
+		sep := strings.LastIndex(key, ":")
+		if sep < 0 {
+			continue
+		}
+		var d deposit
+		if err := w.store.Get(depositBucket, key, &d); err != nil {
+			continue
+		}
+		address, txid := key[:sep], key[sep+1:]
+		if tracked[address] == nil {
+			tracked[address] = make(map[string]deposit)
+		}
+		tracked[address][txid] = d
+	}
+	return tracked
+}
+
+// watched reports whether the address was issued recently enough to be watched or still has
+// a deposit waiting for a confirmation milestone.
+func (w *Watcher) watched(meta models.AddressMeta, deposits map[string]deposit) bool {
+	issuedAt := meta.CreatedAt
+	if meta.LastIssuedAt != nil && meta.LastIssuedAt.After(issuedAt) {
+		issuedAt = *meta.LastIssuedAt
+	}
+	if w.now().Sub(issuedAt) < time.Duration(w.config.WatchHours)*time.Hour {
+		return true
+	}
+	for _, d := range deposits {
+		if d.pending(w.config.Confirmations) {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *Watcher) event(meta models.AddressMeta, txid string, d *deposit) models.DepositEvent {
+	return models.DepositEvent{
+		Coin:          meta.Coin,
+		Address:       meta.Address,
+		Txid:          txid,
+		Amount:        d.Amount,
+		Confirmations: d.Confirmations,
+		Service:       meta.Service,
+		Label:         meta.Label,
+		Reference:     meta.Reference,
+		CreatedAt:     w.now().UTC(),
+	}
+}
+
+// track updates the state of a payment and returns the events it triggers.
+func (w *Watcher) track(meta models.AddressMeta, payment Payment) ([]models.DepositEvent, error) {
+	key := meta.Coin + ":" + meta.Address + ":" + payment.Txid
+	var d deposit
+	var events []models.DepositEvent
+	err := w.store.Update(depositBucket, key, &d, func(found bool) error {
+		d.Amount = payment.Amount
+		d.MissingSince = nil
+		if d.Dropped {
+			// the payment was broadcast again
+			d.Dropped = false
+			found = false
+		}
+		if found {
+			var kept []int
+			for _, milestone := range d.Notified {
+				if milestone <= payment.Confirmations {
+					kept = append(kept, milestone)
+				}
+			}
+			if len(kept) < len(d.Notified) {
+				event := w.event(meta, payment.Txid, &d)
+				event.Confirmations = payment.Confirmations
+				event.ID = d.eventID(key, "reorged")
+				event.Type = EventReorged
+				events = append(events, event)
+				d.Notified = kept
+				d.Reorgs++
+			}
+		}
+		d.Confirmations = payment.Confirmations
+		event := w.event(meta, payment.Txid, &d)
+		if !found {
+			if d.SeenAt.IsZero() {
+				d.SeenAt = event.CreatedAt
+				metrics.Deposits.WithLabelValues(meta.Coin).Inc()
+			}
+			event.ID = d.eventID(key, "detected")
+			event.Type = EventDetected
+			events = append(events, event)
+		}
+		for _, milestone := range w.config.Confirmations {
+			if milestone <= 0 || payment.Confirmations < milestone || d.notified(milestone) {
+				continue
+			}
+			d.Notified = append(d.Notified, milestone)
+			event.ID = d.eventID(key, strconv.Itoa(milestone))
+			event.Type = EventConfirmed
+			events = append(events, event)
+		}
+		return nil
+	})
+	return events, err
+}
+
+// missing updates the state of a tracked payment the backend doesn't return anymore, it is
+// dropped once missing for dropTimeout.
+func (w *Watcher) missing(meta models.AddressMeta, txid string) ([]models.DepositEvent, error) {
+	key := meta.Coin + ":" + meta.Address + ":" + txid
+	var d deposit
+	var events []models.DepositEvent
+	err := w.store.Update(depositBucket, key, &d, func(found bool) error {
+		now := w.now().UTC()
+		if d.MissingSince == nil {
+			d.MissingSince = &now
+			return nil
+		}
+		if now.Sub(*d.MissingSince) < dropTimeout {
+			return nil
+		}
+		event := w.event(meta, txid, &d)
+		event.ID = d.eventID(key, "dropped")
+		event.Type = EventDropped
+		events = append(events, event)
+		logger.Warn("deposits: the payment was dropped", "coin", meta.Coin, "address", meta.Address, "txid", txid)
+		d.Dropped = true
+		d.Notified = nil
+		d.Reorgs++
+		return nil
+	})
+	return events, err
+}
+
+func (w *Watcher) enqueueAll(service string, events []models.DepositEvent, report *Report) {
+	for _, event := range events {
+		if err := w.enqueue(service, event); err != nil {
+			report.Errors = append(report.Errors, event.ID+": "+err.Error())
+			continue
+		}
+		report.Events++
+	}
+}
+
+// enqueue adds an event to the delivery log, if the service has a webhook.
+func (w *Watcher) enqueue(service string, event models.DepositEvent) error {
+	url := w.config.Webhook(service)
+	if url == "" {
+		return nil
+	}
+	return w.store.Put(deliveryBucket, event.ID, models.WebhookDelivery{
+		Event:       event,
+		URL:         url,
+		Status:      DeliveryPending,
+		NextAttempt: event.CreatedAt,
+		CreatedAt:   event.CreatedAt,
+	})
+}
+
+// deliver posts the pending events that are due.
+func (w *Watcher) deliver(report *Report) {
+	for _, id := range w.store.Keys(deliveryBucket) {
+		var delivery models.WebhookDelivery
+		if err := w.store.Get(deliveryBucket, id, &delivery); err != nil {
+			continue
+		}
+		now := w.now().UTC()
+		if delivery.Status != DeliveryPending || delivery.NextAttempt.After(now) {
+			continue
+		}
+		err := w.notifier.Send(delivery.URL, delivery.Event)
+		delivery.Attempts++
+		if err == nil {
+			delivery.Status = DeliveryDelivered
+			delivery.DeliveredAt = &now
+			delivery.LastError = ""
+			report.Delivered++
+			metrics.WebhookDeliveries.WithLabelValues(delivery.Event.Type, "success").Inc()
+		} else {
+			delivery.LastError = err.Error()
+			delivery.NextAttempt = now.Add(retryDelay(delivery.Attempts))
+			if delivery.Attempts >= w.config.MaxAttempts {
+				delivery.Status = DeliveryFailed
+				report.Failed++
+				logger.Error("deposits: giving up the webhook delivery", "id", id, "url", delivery.URL, "err", err)
+			}
+			metrics.WebhookDeliveries.WithLabelValues(delivery.Event.Type, "failure").Inc()
+		}
+		if err := w.store.Put(deliveryBucket, id, delivery); err != nil {
+			report.Errors = append(report.Errors, id+": "+err.Error())
+		}
+	}
+}
+
+func retryDelay(attempts int) time.Duration {
+	delay := retryInitial
+	for i := 1; i < attempts && delay < retryMax; i++ {
+		delay *= 2
+	}
+	if delay > retryMax {
+		delay = retryMax
+	}
+	return delay
+}
+
+// Deliveries returns the delivery log of the events of a service, newest first, optionally
+// filtered by status.
+func (w *Watcher) Deliveries(service string, status string) []models.WebhookDelivery {
+	var deliveries []models.WebhookDelivery
+	for _, id := range w.store.Keys(deliveryBucket) {
+		var delivery models.WebhookDelivery
+		if err := w.store.Get(deliveryBucket, id, &delivery); err != nil {
+			continue
+		}
+		if delivery.Event.Service != service || status != "" && delivery.Status != status {
+			continue
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	sort.SliceStable(deliveries, func(i, j int) bool {
+		return deliveries[i].CreatedAt.After(deliveries[j].CreatedAt)
+	})
+	return deliveries
+}
+
+// Retry schedules a delivery of an event of the service again on the next run, with a fresh
+// amount of attempts.
+func (w *Watcher) Retry(service string, id string) (*models.WebhookDelivery, error) {
+	var delivery models.WebhookDelivery
+	err := w.store.Update(deliveryBucket, id, &delivery, func(found bool) error {
+		if !found || delivery.Event.Service != service {
+			return ErrUnknownDelivery
+		}
+		delivery.Status = DeliveryPending
+		delivery.Attempts = 0
+		delivery.NextAttempt = w.now().UTC()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+// Config returns the configuration of the watcher.
+func (w *Watcher) Config() Config {
+	return w.config
+}
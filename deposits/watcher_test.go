@@ -0,0 +1,191 @@
+package deposits
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/grupokindynos/plutus/models"
+	"github.com/grupokindynos/plutus/store"
+)
+
+type testBackend struct {
+	payments map[string][]Payment
+}
+
+func (b *testBackend) Received(coin string, address string) ([]Payment, error) {
+	return b.payments[address], nil
+}
+
+type testWebhook struct {
+	*httptest.Server
+	mu     sync.Mutex
+	fail   bool
+	events []models.DepositEvent
+}
+
+func newTestWebhook() *testWebhook {
+	w := &testWebhook{}
+	w.Server = httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		if w.fail || r.Header.Get("service") != "signature" {
+			rw.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		var token string
+		var event models.DepositEvent
+		if err := json.NewDecoder(r.Body).Decode(&token); err != nil || json.Unmarshal([]byte(token), &event) != nil {
+			rw.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.events = append(w.events, event)
+	}))
+	return w
+}
+
+func testWatcher(t *testing.T, webhook *testWebhook, backend Backend, now *time.Time) *Watcher {
+	db, _ := store.Open("")
+	config := DefaultConfig()
+	config.MaxAttempts = 2
+	config.Webhooks = map[string]string{"ladon": webhook.URL}
+	issued := []models.AddressMeta{
+		{Coin: "BTC", Address: "addr1", Service: "ladon", Reference: "order-1", CreatedAt: *now},
+		{Coin: "BTC", Address: "addr2", Service: "tyche", CreatedAt: *now},
+	}
+	addresses := func() ([]models.AddressMeta, error) {
+		return issued, nil
+	}
+	notifier := NewNotifier()
+	notifier.Sign = func(payload interface{}) (string, string, error) {
+		token, err := json.Marshal(payload)
+		return "signature", string(token), err
+	}
+	w := NewWatcher(config, db, addresses, backend, notifier)
+	w.now = func() time.Time { return *now }
+	return w
+}
+
+func TestWatcherMilestones(t *testing.T) {
+	now := time.Date(2020, 3, 1, 12, 0, 0, 0, time.UTC)
+	webhook := newTestWebhook()
+	defer webhook.Close()
+	backend := &testBackend{payments: map[string][]Payment{
+		"addr1": {{Txid: "tx1", Amount: 0.5}},
+		"addr2": {{Txid: "tx2", Amount: 1}},
+	}}
+	w := testWatcher(t, webhook, backend, &now)
+	if _, err := w.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if len(webhook.events) != 1 || webhook.events[0].Type != EventDetected || webhook.events[0].Reference != "order-1" {
+		t.Fatal("only the detection of the deposit of the service with a webhook must be notified")
+	}
+	backend.payments["addr1"][0].Confirmations = 7
+	if _, err := w.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if len(webhook.events) != 3 || webhook.events[1].Type != EventConfirmed || webhook.events[2].ID != "BTC:addr1:tx1:6" {
+		t.Fatal("both confirmation milestones must be notified")
+	}
+	now = now.Add(30 * 24 * time.Hour)
+	if _, err := w.Run(); err != nil {
+		t.Fatal(err)
+	}
+	report, _ := w.Run()
+	if report.(*Report).Addresses != 1 || len(webhook.events) != 3 {
+		t.Error("old addresses must only be watched while a deposit waits for a milestone")
+	}
+}
+
+func TestWatcherRetries(t *testing.T) {
+	now := time.Date(2020, 3, 1, 12, 0, 0, 0, time.UTC)
+	webhook := newTestWebhook()
+	defer webhook.Close()
+	webhook.fail = true
+	backend := &testBackend{payments: map[string][]Payment{"addr1": {{Txid: "tx1", Amount: 0.5}}}}
+	w := testWatcher(t, webhook, backend, &now)
+	if _, err := w.Run(); err != nil {
+		t.Fatal(err)
+	}
+	deliveries := w.Deliveries("ladon", DeliveryPending)
+	if len(deliveries) != 1 || deliveries[0].Attempts != 1 || deliveries[0].LastError == "" {
+		t.Fatal("a failed delivery must stay pending")
+	}
+	if _, err := w.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if w.Deliveries("ladon", DeliveryPending)[0].Attempts != 1 {
+		t.Error("the delivery must wait for the backoff")
+	}
+	now = now.Add(retryInitial)
+	report, _ := w.Run()
+	if report.(*Report).Failed != 1 || len(w.Deliveries("ladon", DeliveryFailed)) != 1 {
+		t.Fatal("the delivery must fail after the last attempt")
+	}
+	webhook.fail = false
+	if _, err := w.Retry("ladon", "BTC:addr1:tx1:detected"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if len(w.Deliveries("ladon", DeliveryDelivered)) != 1 || len(webhook.events) != 1 {
+		t.Error("a retried delivery must be sent on the next run")
+	}
+	if _, err := w.Retry("ladon", "unknown"); err != ErrUnknownDelivery {
+		t.Error("expected an error for an unknown delivery")
+	}
+	if _, err := w.Retry("tyche", "BTC:addr1:tx1:detected"); err != ErrUnknownDelivery || len(w.Deliveries("tyche", "")) != 0 {
+		t.Error("the deliveries of another service must not be visible")
+	}
+}
+
+func TestWatcherReorgs(t *testing.T) {
+	now := time.Date(2020, 3, 1, 12, 0, 0, 0, time.UTC)
+	webhook := newTestWebhook()
+	defer webhook.Close()
+	backend := &testBackend{payments: map[string][]Payment{"addr1": {{Txid: "tx1", Amount: 0.5, Confirmations: 1}}}}
+	w := testWatcher(t, webhook, backend, &now)
+	if _, err := w.Run(); err != nil {
+		t.Fatal(err)
+	}
+	backend.payments["addr1"][0].Confirmations = 0
+	if _, err := w.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if len(webhook.events) != 3 || webhook.events[2].Type != EventReorged {
+		t.Fatal("a payment losing a notified milestone must be notified")
+	}
+	backend.payments["addr1"][0].Confirmations = 1
+	if _, err := w.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if len(webhook.events) != 4 || webhook.events[3].ID != "BTC:addr1:tx1:1:1" {
+		t.Fatal("the milestone must be notified again with a new id")
+	}
+	backend.payments["addr1"] = nil
+	if _, err := w.Run(); err != nil {
+		t.Fatal(err)
+	}
+	now = now.Add(dropTimeout)
+	if _, err := w.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if len(webhook.events) != 5 || webhook.events[4].Type != EventDropped {
+		t.Fatal("a payment missing from the backend must be dropped")
+	}
+	now = now.Add(30 * 24 * time.Hour)
+	if report, _ := w.Run(); report.(*Report).Addresses != 0 {
+		t.Error("a dropped payment must not be watched")
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	if retryDelay(1) != time.Minute || retryDelay(3) != 4*time.Minute || retryDelay(20) != retryMax {
+		t.Error("unexpected retry delays")
+	}
+}
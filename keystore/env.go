@@ -0,0 +1,78 @@
+package keystore
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+const mnemonicPrefix = "MNEMONIC_"
+
+const mnemonicPasswordEnv = "MNEMONIC_PASSWORD"
+
+// PassphraseFromEnv reads the passphrase from the file at KEYSTORE_PASSPHRASE_FILE or from
+// KEYSTORE_PASSPHRASE, and removes the variable from the environment.
+func PassphraseFromEnv() ([]byte, error) {
+	defer os.Unsetenv("KEYSTORE_PASSPHRASE")
+	if path := os.Getenv("KEYSTORE_PASSPHRASE_FILE"); path != "" {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		return []byte(strings.TrimRight(string(data), "\r\n")), nil
+	}
+	passphrase := os.Getenv("KEYSTORE_PASSPHRASE")
+	if passphrase == "" {
+		return nil, ErrEmptyPass
+	}
+	return []byte(passphrase), nil
+}
+
+// LoadFromEnv unlocks the keystore at KEYSTORE_PATH with the passphrase of the environment.
+// It returns nil when no keystore is configured.
+func LoadFromEnv() (*Secrets, error) {
+	path := os.Getenv("KEYSTORE_PATH")
+	if path == "" {
+		return nil, nil
+	}
+	passphrase, err := PassphraseFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		for i := range passphrase {
+			passphrase[i] = 0
+		}
+	}()
+	return Load(path, passphrase)
+}
+
+// SecretsFromEnv collects the MNEMONIC_<TAG> and MNEMONIC_PASSWORD variables.
+func SecretsFromEnv() *Secrets {
+	secrets := &Secrets{
+		Mnemonics:        make(map[string]string),
+		MnemonicPassword: os.Getenv(mnemonicPasswordEnv),
+	}
+	for _, env := range os.Environ() {
+		parts := strings.SplitN(env, "=", 2)
+		if len(parts) != 2 || parts[0] == mnemonicPasswordEnv || !strings.HasPrefix(parts[0], mnemonicPrefix) || parts[1] == "" {
+			continue
+		}
+		secrets.Mnemonics[strings.TrimPrefix(parts[0], mnemonicPrefix)] = parts[1]
+	}
+	return secrets
+}
+
+// ClearEnv removes the mnemonics and the mnemonic password from the environment and
+// returns the names of the variables it removed.
+func ClearEnv() []string {
+	var cleared []string
+	for _, env := range os.Environ() {
+		name := strings.SplitN(env, "=", 2)[0]
+		if strings.HasPrefix(name, mnemonicPrefix) {
+			os.Unsetenv(name)
+			cleared = append(cleared, name)
+		}
+	}
+	return cleared
+}
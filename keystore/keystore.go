@@ -0,0 +1,214 @@
+package keystore
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	version    = 1
+	cipherName = "aes-256-gcm"
+	kdfName    = "scrypt"
+	keyLength  = 32
+	saltLength = 32
+)
+
+// additionalData binds the ciphertext to the keystore format.
+var additionalData = []byte("plutus-keystore-v1")
+
+var (
+	ErrDecrypt     = errors.New("could not decrypt the keystore, wrong passphrase or corrupted file")
+	ErrUnsupported = errors.New("unsupported keystore format")
+	ErrEmptyPass   = errors.New("the keystore passphrase can't be empty")
+)
+
+// ScryptParams are the cost parameters of the key derivation.
+type ScryptParams struct {
+	N int `json:"n"`
+	R int `json:"r"`
+	P int `json:"p"`
+}
+
+var (
+	// StandardScrypt matches the cost used by geth for its keystores.
+	StandardScrypt = ScryptParams{N: 1 << 18, R: 8, P: 1}
+	// LightScrypt is cheap to derive, it must only be used on tests.
+	LightScrypt = ScryptParams{N: 1 << 12, R: 8, P: 6}
+)
+
+// Secrets is the content protected by the keystore.
+type Secrets struct {
+	// Mnemonics by coin tag, as the MNEMONIC_<TAG> environment variables.
+	Mnemonics        map[string]string `json:"mnemonics"`
+	MnemonicPassword string            `json:"mnemonic_password,omitempty"`
+}
+
+type kdfParams struct {
+	ScryptParams
+	DKLen int    `json:"dklen"`
+	Salt  string `json:"salt"`
+}
+
+type cryptoJSON struct {
+	Cipher     string    `json:"cipher"`
+	CipherText string    `json:"ciphertext"`
+	Nonce      string    `json:"nonce"`
+	KDF        string    `json:"kdf"`
+	KDFParams  kdfParams `json:"kdfparams"`
+}
+
+type keystoreJSON struct {
+	Version int        `json:"version"`
+	Crypto  cryptoJSON `json:"crypto"`
+}
+
+// Encrypt seals the secrets with a key derived from the passphrase.
+func Encrypt(secrets *Secrets, passphrase []byte, params ScryptParams) ([]byte, error) {
+	if len(passphrase) == 0 {
+		return nil, ErrEmptyPass
+	}
+	plaintext, err := json.Marshal(secrets)
+	if err != nil {
+		return nil, err
+	}
+	defer zero(plaintext)
+	salt := make([]byte, saltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	key, err := scrypt.Key(passphrase, salt, params.N, params.R, params.P, keyLength)
+	if err != nil {
+		return nil, err
+	}
+	defer zero(key)
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	ciphertext := gcm.Seal(nil, nonce, plaintext, additionalData)
+	return json.MarshalIndent(keystoreJSON{
+		Version: version,
+		Crypto: cryptoJSON{
+			Cipher:     cipherName,
+			CipherText: hex.EncodeToString(ciphertext),
+			Nonce:      hex.EncodeToString(nonce),
+			KDF:        kdfName,
+			KDFParams: kdfParams{
+				ScryptParams: params,
+				DKLen:        keyLength,
+				Salt:         hex.EncodeToString(salt),
+			},
+		},
+	}, "", "  ")
+}
+
+// Decrypt opens a keystore sealed by Encrypt.
+func Decrypt(data []byte, passphrase []byte) (*Secrets, error) {
+	var ks keystoreJSON
+	if err := json.Unmarshal(data, &ks); err != nil {
+		return nil, err
+	}
+	if ks.Version != version || ks.Crypto.Cipher != cipherName || ks.Crypto.KDF != kdfName || ks.Crypto.KDFParams.DKLen != keyLength {
+		return nil, ErrUnsupported
+	}
+	salt, err := hex.DecodeString(ks.Crypto.KDFParams.Salt)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := hex.DecodeString(ks.Crypto.Nonce)
+	if err != nil {
+		return nil, err
+	}
+	ciphertext, err := hex.DecodeString(ks.Crypto.CipherText)
+	if err != nil {
+		return nil, err
+	}
+	params := ks.Crypto.KDFParams
+	key, err := scrypt.Key(passphrase, salt, params.N, params.R, params.P, keyLength)
+	if err != nil {
+		return nil, err
+	}
+	defer zero(key)
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(nonce) != gcm.NonceSize() {
+		return nil, ErrUnsupported
+	}
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, additionalData)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	defer zero(plaintext)
+	var secrets Secrets
+	if err := json.Unmarshal(plaintext, &secrets); err != nil {
+		return nil, err
+	}
+	return &secrets, nil
+}
+
+// Load reads and decrypts the keystore at path.
+func Load(path string, passphrase []byte) (*Secrets, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Decrypt(data, passphrase)
+}
+
+// Save encrypts the secrets and writes them to path, readable by the owner only.
+func Save(path string, secrets *Secrets, passphrase []byte, params ScryptParams) error {
+	data, err := Encrypt(secrets, passphrase, params)
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := tmp.Chmod(0600); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func zero(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
@@ -0,0 +1,81 @@
+package keystore
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var testSecrets = &Secrets{
+	Mnemonics: map[string]string{
+		"BTC": "maximum potato bitter govern rebuild elegant nest boring note caution wedding exercise near chimney narrow",
+	},
+	MnemonicPassword: "extra",
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	data, err := Encrypt(testSecrets, []byte("passphrase"), LightScrypt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "potato") {
+		t.Error("the keystore contains the plaintext mnemonic")
+	}
+	secrets, err := Decrypt(data, []byte("passphrase"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secrets.Mnemonics["BTC"] != testSecrets.Mnemonics["BTC"] || secrets.MnemonicPassword != "extra" {
+		t.Error("decrypted secrets don't match")
+	}
+	if _, err := Decrypt(data, []byte("wrong")); err != ErrDecrypt {
+		t.Error("expected a decryption error with a wrong passphrase")
+	}
+	if _, err := Encrypt(testSecrets, nil, LightScrypt); err != ErrEmptyPass {
+		t.Error("expected an error with an empty passphrase")
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "keystore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "keystore.json")
+	if err := Save(path, testSecrets, []byte("passphrase"), LightScrypt); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Error("the keystore must only be readable by the owner")
+	}
+	secrets, err := Load(path, []byte("passphrase"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secrets.Mnemonics["BTC"] != testSecrets.Mnemonics["BTC"] {
+		t.Error("loaded secrets don't match")
+	}
+}
+
+func TestEnv(t *testing.T) {
+	os.Setenv("MNEMONIC_TEST", "one two three")
+	os.Setenv("MNEMONIC_PASSWORD", "extra")
+	secrets := SecretsFromEnv()
+	if secrets.Mnemonics["TEST"] != "one two three" || secrets.MnemonicPassword != "extra" {
+		t.Error("unexpected secrets from the environment")
+	}
+	if _, ok := secrets.Mnemonics["PASSWORD"]; ok {
+		t.Error("the mnemonic password must not be imported as a mnemonic")
+	}
+	ClearEnv()
+	if os.Getenv("MNEMONIC_TEST") != "" || os.Getenv("MNEMONIC_PASSWORD") != "" {
+		t.Error("the mnemonics must be removed from the environment")
+	}
+}
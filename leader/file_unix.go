@@ -0,0 +1,62 @@
+//go:build !windows
+// +build !windows
+
+package leader
+
+import (
+	"os"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// FileLease is a Lease backed by an exclusive flock on a file. Every instance must see
+// the same file (a shared volume), the lock is released by the kernel if the process dies
+// so the ttl is not needed to recover from crashes.
+type FileLease struct {
+	Path string
+
+	mu     sync.Mutex
+	file   *os.File
+	holder string
+}
+
+func (l *FileLease) Acquire(holder string, ttl time.Duration) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file != nil {
+		return l.holder == holder, nil
+	}
+	file, err := os.OpenFile(l.Path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return false, err
+	}
+	err = syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if err == syscall.EWOULDBLOCK {
+		file.Close()
+		return false, nil
+	}
+	if err != nil {
+		file.Close()
+		return false, err
+	}
+	// The holder is written for operators, the lock itself is what matters.
+	_ = file.Truncate(0)
+	_, _ = file.WriteAt([]byte(holder+"\n"), 0)
+	l.file = file
+	l.holder = holder
+	return true, nil
+}
+
+func (l *FileLease) Release(holder string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil || l.holder != holder {
+		return nil
+	}
+	err := syscall.Flock(int(l.file.Fd()), syscall.LOCK_UN)
+	l.file.Close()
+	l.file = nil
+	l.holder = ""
+	return err
+}
@@ -0,0 +1,19 @@
+package leader
+
+import (
+	"errors"
+	"time"
+)
+
+// FileLease is not supported on windows, use a RowLease instead.
+type FileLease struct {
+	Path string
+}
+
+func (l *FileLease) Acquire(holder string, ttl time.Duration) (bool, error) {
+	return false, errors.New("file leases are not supported on windows")
+}
+
+func (l *FileLease) Release(holder string) error {
+	return nil
+}
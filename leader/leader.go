@@ -0,0 +1,105 @@
+package leader
+
+import (
+	"sync"
+	"time"
+
+	"github.com/grupokindynos/plutus/logger"
+)
+
+// Lease is an exclusive lock held by a single instance for a limited time.
+type Lease interface {
+	// Acquire takes the lease for holder, or renews it if holder already owns it,
+	// and reports whether holder is the owner afterwards.
+	Acquire(holder string, ttl time.Duration) (bool, error)
+	// Release gives up the lease if it is owned by holder.
+	Release(holder string) error
+}
+
+// Elector keeps trying to acquire a lease and reports whether this instance is the leader.
+// Leadership is dropped as soon as a renewal fails or the lease would have expired
+// without being renewed, so two instances never consider themselves leaders at once.
+type Elector struct {
+	lease Lease
+	id    string
+	ttl   time.Duration
+
+	mu      sync.Mutex
+	leader  bool
+	renewed time.Time
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+func NewElector(lease Lease, id string, ttl time.Duration) *Elector {
+	return &Elector{
+		lease: lease,
+		id:    id,
+		ttl:   ttl,
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+}
+
+// Start runs the election loop in the background, renewing the lease three times per ttl.
+func (e *Elector) Start() {
+	e.tick()
+	go func() {
+		defer close(e.done)
+		ticker := time.NewTicker(e.ttl / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-e.stop:
+				return
+			case <-ticker.C:
+				e.tick()
+			}
+		}
+	}()
+}
+
+// Stop ends the election loop and releases the lease so another instance can take over.
+func (e *Elector) Stop() {
+	close(e.stop)
+	<-e.done
+	e.mu.Lock()
+	e.leader = false
+	e.mu.Unlock()
+	err := e.lease.Release(e.id)
+	if err != nil {
+		logger.Error("leader: unable to release the lease", "err", err)
+	}
+}
+
+// IsLeader reports whether this instance currently holds a valid lease.
+func (e *Elector) IsLeader() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.leader && time.Since(e.renewed) < e.ttl
+}
+
+// ID is the identifier this instance uses as lease holder.
+func (e *Elector) ID() string {
+	return e.id
+}
+
+func (e *Elector) tick() {
+	now := time.Now()
+	acquired, err := e.lease.Acquire(e.id, e.ttl)
+	if err != nil {
+		logger.Error("leader: unable to acquire the lease", "err", err)
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if acquired && !e.leader {
+		logger.Info("leader: this instance is now the leader", "id", e.id)
+	}
+	if !acquired && e.leader {
+		logger.Warn("leader: this instance lost the leadership", "id", e.id)
+	}
+	e.leader = acquired
+	if acquired {
+		e.renewed = now
+	}
+}
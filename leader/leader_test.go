@@ -0,0 +1,74 @@
+package leader
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestMemoryStoreLease(t *testing.T) {
+	store := NewMemoryStore()
+	first := NewElector(&RowLease{Name: "jobs", Store: store}, "first", time.Second)
+	second := NewElector(&RowLease{Name: "jobs", Store: store}, "second", time.Second)
+	first.Start()
+	second.Start()
+	if !first.IsLeader() || second.IsLeader() {
+		t.Fatal("only the first elector should be the leader")
+	}
+	first.Stop()
+	if first.IsLeader() {
+		t.Error("a stopped elector can't be the leader")
+	}
+	second.tick()
+	if !second.IsLeader() {
+		t.Error("the second elector should take over after the release")
+	}
+	second.Stop()
+}
+
+func TestExpiredLeaseIsTakenOver(t *testing.T) {
+	store := NewMemoryStore()
+	now := time.Now()
+	ok, _ := store.Claim("jobs", Record{Holder: "dead", Expires: now.Add(-time.Second)}, now.Add(-time.Minute))
+	if !ok {
+		t.Fatal("unable to claim an empty lease")
+	}
+	ok, _ = store.Claim("jobs", Record{Holder: "alive", Expires: now.Add(time.Minute)}, now)
+	if !ok {
+		t.Error("an expired lease should be claimable")
+	}
+	ok, _ = store.Claim("jobs", Record{Holder: "other", Expires: now.Add(time.Minute)}, now)
+	if ok {
+		t.Error("a valid lease must not be claimable by another holder")
+	}
+}
+
+func TestFileLease(t *testing.T) {
+	dir, err := ioutil.TempDir("", "plutus-lease")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "jobs.lock")
+	first := &FileLease{Path: path}
+	second := &FileLease{Path: path}
+	ok, err := first.Acquire("first", time.Second)
+	if err != nil || !ok {
+		t.Fatal("first lease should be acquired", err)
+	}
+	ok, err = second.Acquire("second", time.Second)
+	if err != nil || ok {
+		t.Fatal("second lease must not be acquired while the first one is held", err)
+	}
+	err = first.Release("first")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ok, err = second.Acquire("second", time.Second)
+	if err != nil || !ok {
+		t.Fatal("second lease should be acquired after the release", err)
+	}
+	_ = second.Release("second")
+}
@@ -0,0 +1,74 @@
+package leader
+
+import (
+	"sync"
+	"time"
+)
+
+// Record is the content of a lease row.
+type Record struct {
+	Holder  string
+	Expires time.Time
+}
+
+// Store persists lease rows. It is modelled after a database table with a single row per
+// lease name, Claim maps to
+//
+//	UPDATE leases SET holder = $holder, expires = $expires
+//	WHERE name = $name AND (holder = $holder OR expires < $now)
+//
+// (plus an INSERT when the row does not exist yet) and Drop to
+//
+//	DELETE FROM leases WHERE name = $name AND holder = $holder
+type Store interface {
+	Claim(name string, record Record, now time.Time) (bool, error)
+	Drop(name string, holder string) error
+}
+
+// RowLease is a Lease backed by a row of a Store.
+type RowLease struct {
+	Name  string
+	Store Store
+}
+
+func (l *RowLease) Acquire(holder string, ttl time.Duration) (bool, error) {
+	now := time.Now()
+	return l.Store.Claim(l.Name, Record{Holder: holder, Expires: now.Add(ttl)}, now)
+}
+
+func (l *RowLease) Release(holder string) error {
+	return l.Store.Drop(l.Name, holder)
+}
+
+// MemoryStore is an in-process Store. It only coordinates electors living in the same
+// process and stands in for the database table on single instance deployments and tests.
+type MemoryStore struct {
+	mu   sync.Mutex
+	rows map[string]Record
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		rows: make(map[string]Record),
+	}
+}
+
+func (s *MemoryStore) Claim(name string, record Record, now time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	current, ok := s.rows[name]
+	if ok && current.Holder != record.Holder && current.Expires.After(now) {
+		return false, nil
+	}
+	s.rows[name] = record
+	return true, nil
+}
+
+func (s *MemoryStore) Drop(name string, holder string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if current, ok := s.rows[name]; ok && current.Holder == holder {
+		delete(s.rows, name)
+	}
+	return nil
+}
@@ -0,0 +1,181 @@
+package logger
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Level is the severity of a log entry.
+type Level int
+
+const (
+	DebugLevel Level = iota
+	InfoLevel
+	WarnLevel
+	ErrorLevel
+)
+
+var levelNames = []string{"debug", "info", "warn", "error"}
+
+func (l Level) String() string {
+	if l < DebugLevel || l > ErrorLevel {
+		return "unknown"
+	}
+	return levelNames[l]
+}
+
+// ParseLevel parses the level names used by LOG_LEVEL.
+func ParseLevel(name string) (Level, error) {
+	for i, levelName := range levelNames {
+		if strings.EqualFold(name, levelName) {
+			return Level(i), nil
+		}
+	}
+	return InfoLevel, errors.New("unknown log level " + name)
+}
+
+// Logger writes one JSON object per entry. Every value goes through the redaction layer
+// before being written, so keys, WIFs and mnemonics never reach the output.
+type Logger struct {
+	mu     *sync.Mutex
+	out    io.Writer
+	level  Level
+	fields []interface{}
+}
+
+func New(out io.Writer, level Level) *Logger {
+	return &Logger{
+		mu:    new(sync.Mutex),
+		out:   out,
+		level: level,
+	}
+}
+
+var std = New(os.Stderr, levelFromEnv())
+
+func levelFromEnv() Level {
+	level, err := ParseLevel(os.Getenv("LOG_LEVEL"))
+	if err != nil {
+		return InfoLevel
+	}
+	return level
+}
+
+// Default returns the logger used by the package level functions.
+func Default() *Logger {
+	return std
+}
+
+// With returns a logger that adds the key value pairs to every entry.
+func (l *Logger) With(keyvals ...interface{}) *Logger {
+	fields := make([]interface{}, 0, len(l.fields)+len(keyvals))
+	fields = append(fields, l.fields...)
+	fields = append(fields, keyvals...)
+	return &Logger{
+		mu:     l.mu,
+		out:    l.out,
+		level:  l.level,
+		fields: fields,
+	}
+}
+
+// WithRequestID returns a logger that tags every entry with the request id.
+func (l *Logger) WithRequestID(id string) *Logger {
+	if id == "" {
+		return l
+	}
+	return l.With("request_id", id)
+}
+
+func (l *Logger) Debug(msg string, keyvals ...interface{}) {
+	l.log(DebugLevel, msg, keyvals)
+}
+
+func (l *Logger) Info(msg string, keyvals ...interface{}) {
+	l.log(InfoLevel, msg, keyvals)
+}
+
+func (l *Logger) Warn(msg string, keyvals ...interface{}) {
+	l.log(WarnLevel, msg, keyvals)
+}
+
+func (l *Logger) Error(msg string, keyvals ...interface{}) {
+	l.log(ErrorLevel, msg, keyvals)
+}
+
+func (l *Logger) log(level Level, msg string, keyvals []interface{}) {
+	if level < l.level {
+		return
+	}
+	var b strings.Builder
+	b.WriteString(`{"time":`)
+	writeJSON(&b, time.Now().UTC().Format(time.RFC3339Nano))
+	b.WriteString(`,"level":`)
+	writeJSON(&b, level.String())
+	b.WriteString(`,"msg":`)
+	writeJSON(&b, redactString(msg))
+	all := append(append([]interface{}{}, l.fields...), keyvals...)
+	for i := 0; i < len(all); i += 2 {
+		key, ok := all[i].(string)
+		if !ok {
+			key = "!badkey"
+		}
+		var value interface{} = "!missing"
+		if i+1 < len(all) {
+			value = all[i+1]
+		}
+		b.WriteString(",")
+		writeJSON(&b, key)
+		b.WriteString(":")
+		writeJSON(&b, redactField(key, value))
+	}
+	b.WriteString("}\n")
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, _ = io.WriteString(l.out, b.String())
+}
+
+func writeJSON(b *strings.Builder, v interface{}) {
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		encoded, _ = json.Marshal(redacted)
+	}
+	b.Write(encoded)
+}
+
+func Debug(msg string, keyvals ...interface{}) {
+	std.log(DebugLevel, msg, keyvals)
+}
+
+func Info(msg string, keyvals ...interface{}) {
+	std.log(InfoLevel, msg, keyvals)
+}
+
+func Warn(msg string, keyvals ...interface{}) {
+	std.log(WarnLevel, msg, keyvals)
+}
+
+func Error(msg string, keyvals ...interface{}) {
+	std.log(ErrorLevel, msg, keyvals)
+}
+
+// With returns a child of the default logger.
+func With(keyvals ...interface{}) *Logger {
+	return std.With(keyvals...)
+}
+
+// NewRequestID returns a random id to correlate the entries of a request.
+func NewRequestID() string {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return strings.Replace(time.Now().UTC().Format("20060102150405.000000000"), ".", "", 1)
+	}
+	return hex.EncodeToString(id)
+}
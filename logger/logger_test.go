@@ -0,0 +1,104 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+type ExtendedKey struct {
+	key string
+}
+
+func (k *ExtendedKey) String() string {
+	return k.key
+}
+
+const (
+	testXprv     = "xprv9s21ZrQH143K3QTDL4LXw2F7HEK3wJUD2nW2nRk4stbPy6cq3jPPqjiChkVvvNKmPGJxWUtg6LnF5kejMRNNU3TGtRBeJgk33yuGBxrMPHi"
+	testWIF      = "5HueCGU8rMjxEXxiPuD5BDku4MkFqeZyd4dZ1jvhTVqvbTLvyTJ"
+	testMnemonic = "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"
+)
+
+func TestRedaction(t *testing.T) {
+	var out bytes.Buffer
+	log := New(&out, DebugLevel).WithRequestID("abc")
+	AddSecret("hunter2hunter2")
+	log.Error("sendToAddress: signing failed",
+		"acc", &ExtendedKey{key: testXprv},
+		"key_string", "key "+testXprv,
+		"wif", testWIF,
+		"wif_value", "imported "+testWIF,
+		"err", errors.New("bad mnemonic "+testMnemonic),
+		"raw", []byte{1, 2, 3},
+		"privKey", "anything",
+		"password", "hunter2hunter2",
+		"note", "password is hunter2hunter2",
+		"txid", "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
+		"amount", 1.5,
+	)
+	line := out.String()
+	for _, secret := range []string{testXprv, testWIF, "abandon abandon", "hunter2", "anything"} {
+		if strings.Contains(line, secret) {
+			t.Error("secret leaked: " + secret)
+		}
+	}
+	var entry map[string]interface{}
+	if err := json.Unmarshal([]byte(line), &entry); err != nil {
+		t.Fatal(err)
+	}
+	if entry["level"] != "error" || entry["request_id"] != "abc" || entry["msg"] != "sendToAddress: signing failed" {
+		t.Error("unexpected entry " + line)
+	}
+	if entry["txid"] != "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855" || entry["amount"] != 1.5 {
+		t.Error("non secret values must be kept " + line)
+	}
+}
+
+type testAccount struct {
+	Coin   string
+	Keys   map[string]string
+	Config struct {
+		Passphrase string
+		Accounts   []interface{} `json:"accounts"`
+	}
+	seed string
+}
+
+func TestNestedRedaction(t *testing.T) {
+	var out bytes.Buffer
+	log := New(&out, DebugLevel)
+	account := testAccount{Coin: "BTC", Keys: map[string]string{"xprv": "anything", "xpub": "key " + testXprv}, seed: "hidden seed"}
+	account.Config.Passphrase = "hunter3hunter3"
+	account.Config.Accounts = []interface{}{testMnemonic, &ExtendedKey{key: testXprv}, 7}
+	log.Info("loadAccount: loaded", "account", &account, "accounts", []testAccount{account})
+	line := out.String()
+	for _, secret := range []string{testXprv, "abandon abandon", "hunter3", "anything", "hidden seed"} {
+		if strings.Contains(line, secret) {
+			t.Error("nested secret leaked: " + secret)
+		}
+	}
+	var entry map[string]interface{}
+	if err := json.Unmarshal([]byte(line), &entry); err != nil {
+		t.Fatal(err)
+	}
+	logged, ok := entry["account"].(map[string]interface{})
+	if !ok || logged["Coin"] != "BTC" {
+		t.Error("nested non secret values must be kept " + line)
+	}
+}
+
+func TestLevel(t *testing.T) {
+	var out bytes.Buffer
+	log := New(&out, WarnLevel)
+	log.Info("skipped")
+	log.Warn("written")
+	if strings.Contains(out.String(), "skipped") || !strings.Contains(out.String(), "written") {
+		t.Error("unexpected output " + out.String())
+	}
+	if _, err := ParseLevel("verbose"); err == nil {
+		t.Error("expected an error for an unknown level")
+	}
+}
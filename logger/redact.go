@@ -0,0 +1,180 @@
+package logger
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+const redacted = "[REDACTED]"
+
+// minSecretLength avoids blanking common substrings when a short secret is registered.
+const minSecretLength = 6
+
+var (
+	// Extended keys (xprv, xpub and their altcoin versions) are 111 base58 characters.
+	extendedKeyPattern = regexp.MustCompile(`[1-9A-HJ-NP-Za-km-z]{100,120}`)
+	// WIF private keys are 51 or 52 base58 characters.
+	wifPattern = regexp.MustCompile(`\b[1-9A-HJ-NP-Za-km-z]{50,53}\b`)
+	// BIP39 mnemonics are 12 to 24 lowercase words.
+	mnemonicPattern = regexp.MustCompile(`\b(?:[a-z]{3,8}\s+){11,23}[a-z]{3,8}\b`)
+)
+
+// Raw keys are redacted by type since a hex private key can't be told apart from a txid.
+var secretTypeNames = []string{"PrivateKey", "ExtendedKey", "WIF", "Mnemonic", "Seed", "Wallet"}
+
+var secretKeyNames = []string{"mnemonic", "password", "passphrase", "privkey", "private_key", "privatekey", "seed", "secret", "xprv", "wif"}
+
+var secrets struct {
+	sync.RWMutex
+	values []string
+}
+
+// AddSecret makes the logger replace every occurrence of value, e.g. the configured
+// mnemonics and passwords.
+func AddSecret(value string) {
+	value = strings.TrimSpace(value)
+	if len(value) < minSecretLength {
+		return
+	}
+	secrets.Lock()
+	defer secrets.Unlock()
+	secrets.values = append(secrets.values, value)
+}
+
+// AddSecretsFromEnv registers the values of the environment variables holding mnemonics,
+// passwords or keys.
+func AddSecretsFromEnv() {
+	for _, env := range os.Environ() {
+		parts := strings.SplitN(env, "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		name := strings.ToUpper(parts[0])
+		if strings.Contains(name, "MNEMONIC") || strings.Contains(name, "PASSWORD") ||
+			strings.Contains(name, "PASSPHRASE") || strings.Contains(name, "PRIVATE_KEY") || strings.Contains(name, "SECRET") {
+			AddSecret(parts[1])
+		}
+	}
+}
+
+func redactString(s string) string {
+	secrets.RLock()
+	for _, secret := range secrets.values {
+		s = strings.Replace(s, secret, redacted, -1)
+	}
+	secrets.RUnlock()
+	s = mnemonicPattern.ReplaceAllString(s, redacted)
+	s = extendedKeyPattern.ReplaceAllString(s, redacted)
+	return wifPattern.ReplaceAllString(s, redacted)
+}
+
+// maxRedactDepth bounds the walk of nested values, a deeper or cyclic value is dropped.
+const maxRedactDepth = 8
+
+func redactField(key string, value interface{}) interface{} {
+	return redactFieldAt(key, value, 0)
+}
+
+func redactFieldAt(key string, value interface{}, depth int) interface{} {
+	lowerKey := strings.ToLower(key)
+	for _, name := range secretKeyNames {
+		if strings.Contains(lowerKey, name) {
+			return redacted
+		}
+	}
+	return redactValueAt(value, depth)
+}
+
+func redactValue(value interface{}) interface{} {
+	return redactValueAt(value, 0)
+}
+
+func redactValueAt(value interface{}, depth int) interface{} {
+	if value == nil {
+		return nil
+	}
+	if isSecretType(reflect.TypeOf(value)) {
+		return redacted
+	}
+	switch v := value.(type) {
+	case []byte:
+		return redacted
+	case string:
+		return redactString(v)
+	case error:
+		return redactString(v.Error())
+	case bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return v
+	case time.Time:
+		return v.UTC().Format(time.RFC3339Nano)
+	case fmt.Stringer:
+		return redactString(v.String())
+	}
+	return redactNested(reflect.ValueOf(value), depth)
+}
+
+// redactNested walks structs, maps and slices so the secrets they hold are redacted by field
+// name and type like the logged fields. Unexported struct fields are dropped.
+func redactNested(v reflect.Value, depth int) interface{} {
+	if depth >= maxRedactDepth {
+		return redacted
+	}
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return redactValueAt(v.Elem().Interface(), depth+1)
+	case reflect.Struct:
+		fields := make(map[string]interface{})
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if field.PkgPath != "" {
+				continue
+			}
+			name := field.Name
+			if tag := strings.Split(field.Tag.Get("json"), ",")[0]; tag == "-" {
+				continue
+			} else if tag != "" {
+				name = tag
+			}
+			fields[name] = redactFieldAt(name, v.Field(i).Interface(), depth+1)
+		}
+		return fields
+	case reflect.Map:
+		entries := make(map[string]interface{}, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			key := redactString(fmt.Sprint(iter.Key().Interface()))
+			entries[key] = redactFieldAt(key, iter.Value().Interface(), depth+1)
+		}
+		return entries
+	case reflect.Slice, reflect.Array:
+		items := make([]interface{}, v.Len())
+		for i := range items {
+			items[i] = redactValueAt(v.Index(i).Interface(), depth+1)
+		}
+		return items
+	case reflect.Chan, reflect.Func, reflect.UnsafePointer:
+		return v.Type().String()
+	}
+	return redactString(fmt.Sprintf("%+v", v.Interface()))
+}
+
+func isSecretType(t reflect.Type) bool {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+		t = t.Elem()
+	}
+	for _, name := range secretTypeNames {
+		if strings.Contains(t.Name(), name) {
+			return true
+		}
+	}
+	return false
+}
@@ -1,64 +1,142 @@
 package main
 
 import (
-	"encoding/json"
+	"context"
+	"crypto/subtle"
 	"errors"
-	"fmt"
-	"io/ioutil"
-	"log"
 	"net/http"
 	"os"
-	"reflect"
-	"sync"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
 	"time"
 
-	"github.com/grupokindynos/adrestia-go/models"
-
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	coinfactory "github.com/grupokindynos/common/coin-factory"
-	"github.com/grupokindynos/common/plutus"
 	"github.com/grupokindynos/common/responses"
 	"github.com/grupokindynos/common/tokens/mrt"
 	"github.com/grupokindynos/common/tokens/mvt"
+	"github.com/grupokindynos/plutus/adrestia"
 	"github.com/grupokindynos/plutus/controllers"
+	"github.com/grupokindynos/plutus/deposits"
+	"github.com/grupokindynos/plutus/keystore"
+	"github.com/grupokindynos/plutus/leader"
+	"github.com/grupokindynos/plutus/logger"
+	"github.com/grupokindynos/plutus/metrics"
+	"github.com/grupokindynos/plutus/multisig"
+	"github.com/grupokindynos/plutus/scheduler"
+	"github.com/grupokindynos/plutus/signer"
+	"github.com/grupokindynos/plutus/store"
+	"github.com/grupokindynos/plutus/sweep"
 	_ "github.com/heroku/x/hmetrics/onload"
 	"github.com/joho/godotenv"
 )
 
 func init() {
 	_ = godotenv.Load()
+	logger.AddSecretsFromEnv()
+	unlockKeystore()
+	connectSigner()
+}
+
+// unlockKeystore loads the mnemonics from the encrypted keystore at KEYSTORE_PATH, when
+// configured, and removes the plaintext mnemonics from the process environment. With a remote
+// signer the mnemonics are only loaded by the signer process.
+func unlockKeystore() {
+	if os.Getenv("SIGNER") == "remote" {
+		if cleared := keystore.ClearEnv(); len(cleared) > 0 {
+			logger.Warn("main: ignoring the mnemonics of the environment, they are held by the signer process", "variables", strings.Join(cleared, ","))
+		}
+		return
+	}
+	secrets, err := keystore.LoadFromEnv()
+	if err != nil {
+		panic(err)
+	}
+	if secrets == nil {
+		return
+	}
+	for _, mnemonic := range secrets.Mnemonics {
+		logger.AddSecret(mnemonic)
+	}
+	logger.AddSecret(secrets.MnemonicPassword)
+	controllers.SetSecrets(secrets.Mnemonics, secrets.MnemonicPassword)
+	if cleared := keystore.ClearEnv(); len(cleared) > 0 {
+		logger.Warn("main: ignoring the mnemonics of the environment, the keystore is used instead", "variables", strings.Join(cleared, ","))
+	}
+	logger.Info("main: keystore unlocked", "coins", len(secrets.Mnemonics))
 }
 
-type CurrentTime struct {
-	Hour   int
-	Day    int
-	Minute int
-	Second int
+// connectSigner signs through the signer process listening on SIGNER_SOCKET when SIGNER is
+// set to remote, instead of signing in process.
+func connectSigner() {
+	if os.Getenv("SIGNER") != "remote" {
+		return
+	}
+	socket := os.Getenv("SIGNER_SOCKET")
+	if socket == "" {
+		panic("SIGNER_SOCKET is required with a remote signer")
+	}
+	remote, err := signer.Dial("unix", socket)
+	if err != nil {
+		panic(err)
+	}
+	controllers.SetSigner(remote)
+	onShutdown(func() { _ = remote.Close() })
+	logger.Info("main: signing through the signer process", "socket", socket)
 }
 
-var currTime CurrentTime
+// shutdownTimeout is how long the in flight requests have to finish on shutdown.
+const shutdownTimeout = 30 * time.Second
+
+// shutdownHooks are run in reverse order once the server stopped serving requests.
+var shutdownHooks []func()
+
+func onShutdown(hook func()) {
+	shutdownHooks = append(shutdownHooks, hook)
+}
 
 func main() {
 	port := os.Getenv("PORT")
 	if port == "" {
 		port = "8080"
 	}
-
-	currTime = CurrentTime{
-		Hour:   time.Now().Hour(),
-		Day:    time.Now().Day(),
-		Minute: time.Now().Minute(),
-		Second: time.Now().Second(),
-	}
 	App := GetApp()
-	_ = App.Run(":" + port)
-
+	srv := &http.Server{
+		Addr:    ":" + port,
+		Handler: App,
+	}
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- srv.ListenAndServe()
+	}()
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	select {
+	case err := <-serveErr:
+		logger.Error("main: the server stopped", "err", err)
+	case sig := <-quit:
+		logger.Info("main: shutting down", "signal", sig.String())
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		err := srv.Shutdown(ctx)
+		cancel()
+		if err != nil {
+			logger.Error("main: unable to drain the requests", "err", err)
+		}
+	}
+	for i := len(shutdownHooks) - 1; i >= 0; i-- {
+		shutdownHooks[i]()
+	}
+	controllers.WipeKeys()
 }
 
 func GetApp() *gin.Engine {
-	App := gin.Default()
+	App := gin.New()
+	App.Use(requestLogMiddleware, gin.Recovery())
 	App.Use(cors.Default())
+	App.Use(metricsMiddleware)
 	ApplyRoutes(App)
 	return App
 }
@@ -69,31 +147,158 @@ func ApplyRoutes(r *gin.Engine) {
 	api := r.Group("/", gin.BasicAuth(gin.Accounts{
 		authUser: authPassword,
 	}))
+	db, err := store.Open(os.Getenv("STORE_PATH"))
+	if err != nil {
+		panic(err)
+	}
+	multisigConfig, err := multisig.LoadConfig()
+	if err != nil {
+		panic(err)
+	}
+	// the issued multisig addresses and the pending spends only live in the store, an in
+	// memory store would issue the same addresses again after a restart and strand their funds
+	if len(multisigConfig.Coins) > 0 && os.Getenv("STORE_PATH") == "" {
+		panic("MULTISIG_CONFIG requires STORE_PATH")
+	}
+	controllers.SetStore(db)
+	err = controllers.SetMultisig(multisigConfig)
+	if err != nil {
+		panic(err)
+	}
+	ctrl := controllers.NewPlutusController()
+	depositsCtrl := &controllers.DepositsController{
+		Watcher:  newWatcher(db),
+		Registry: deposits.NewRegistry(db, deposits.NewBlockbook()),
+	}
+	jobsCtrl := &controllers.JobsController{Scheduler: startJobs(ctrl, db, depositsCtrl.Watcher)}
 	{
-		ctrl := controllers.NewPlutusController()
-		go timer(ctrl)
 		api.GET("/balance/:coin", func(context *gin.Context) { VerifyRequest(context, ctrl.GetBalance) })
 		api.GET("/address/:coin", func(context *gin.Context) { VerifyRequest(context, ctrl.GetAddress) })
 		api.POST("/validate/addr", func(context *gin.Context) { VerifyRequest(context, ctrl.ValidateAddress) })
 		api.POST("/validate/tx", func(context *gin.Context) { VerifyRequest(context, ctrl.ValidateRawTx) })
+		api.POST("/validate/tx/report", func(context *gin.Context) { VerifyRequest(context, ctrl.ValidateRawTxReport) })
 		api.POST("/send/address", func(context *gin.Context) { VerifyRequest(context, ctrl.SendToAddress) })
+		api.POST("/rescan/:coin", adminOnly, func(context *gin.Context) { VerifyRequest(context, ctrl.Rescan) })
+		api.GET("/metrics", gin.WrapH(metrics.Handler()))
 	}
+	ctrlV2 := controllers.NewPlutusControllerV2()
 	apiV2 := r.Group("/v2/", gin.BasicAuth(gin.Accounts{
 		authUser: authPassword,
 	}))
 	{
-		ctrlV2 := controllers.NewPlutusControllerV2()
 		apiV2.GET("/balance/:coin", func(context *gin.Context) { VerifyRequestV2(context, ctrlV2.GetBalanceV2) })
 		apiV2.GET("/address/:coin", func(context *gin.Context) { VerifyRequestV2(context, ctrlV2.GetAddressV2) })
+		apiV2.GET("/address/:coin/:addr", func(context *gin.Context) { VerifyRequestV2(context, ctrlV2.GetAddressInfoV2) })
 		apiV2.POST("/validate/addr", func(context *gin.Context) { VerifyRequestV2(context, ctrlV2.ValidateAddressV2) })
 		apiV2.POST("/validate/tx", func(context *gin.Context) { VerifyRequestV2(context, ctrlV2.ValidateRawTxV2) })
+		apiV2.POST("/validate/tx/report", func(context *gin.Context) { VerifyRequestV2(context, ctrlV2.ValidateRawTxReportV2) })
 		apiV2.POST("/send/address", func(context *gin.Context) { VerifyRequestV2(context, ctrlV2.SendToAddressV2) })
+		apiV2.POST("/send/bump", func(context *gin.Context) { VerifyRequestV2(context, ctrlV2.BumpFeeV2) })
+		apiV2.POST("/send/accelerate", func(context *gin.Context) { VerifyRequestV2(context, ctrlV2.AccelerateV2) })
+		apiV2.POST("/rescan/:coin", adminOnly, func(context *gin.Context) { VerifyRequestV2(context, ctrlV2.RescanV2) })
+		apiV2.GET("/history/:coin", func(context *gin.Context) { VerifyRequestV2(context, ctrlV2.GetHistoryV2) })
+		apiV2.POST("/psbt/create", func(context *gin.Context) { VerifyRequestV2(context, ctrlV2.CreatePSBTV2) })
+		apiV2.POST("/psbt/sign", func(context *gin.Context) { VerifyRequestV2(context, ctrlV2.SignPSBTV2) })
+		apiV2.POST("/psbt/finalize", func(context *gin.Context) { VerifyRequestV2(context, ctrlV2.FinalizePSBTV2) })
+		apiV2.POST("/multisig/create", func(context *gin.Context) { VerifyRequestV2(context, ctrlV2.CreateMultisigTxV2) })
+		apiV2.POST("/multisig/sign", func(context *gin.Context) { VerifyRequestV2(context, ctrlV2.SignMultisigTxV2) })
+		apiV2.GET("/multisig/:txid", func(context *gin.Context) { VerifyRequestV2(context, ctrlV2.GetMultisigTxV2) })
+		applyJobRoutes(apiV2, jobsCtrl)
+		apiV2.GET("/deposits/webhooks", func(context *gin.Context) { VerifyRequestV2(context, depositsCtrl.GetDeliveries) })
+		apiV2.POST("/deposits/webhooks/:id/retry", func(context *gin.Context) { VerifyRequestV2(context, depositsCtrl.RetryDelivery) })
+		apiV2.POST("/payments/:coin", func(context *gin.Context) { VerifyRequestV2(context, depositsCtrl.ExpectPayment) })
+		apiV2.GET("/payments/:coin/:addr", func(context *gin.Context) { VerifyRequestV2(context, depositsCtrl.GetExpectedPayment) })
 	}
+	r.GET("/health", func(context *gin.Context) {
+		context.JSON(http.StatusOK, gin.H{"status": "ok"})
+	})
+	r.GET("/ready", func(context *gin.Context) {
+		controllers.ServeReadiness(context.Writer, controllers.Readiness(ctrl, ctrlV2))
+	})
 	r.NoRoute(func(c *gin.Context) {
 		c.String(http.StatusNotFound, "Not Found")
 	})
 }
 
+// applyJobRoutes serves the jobs of the scheduler. Pausing, resuming and running them, the
+// sweep moves funds to the cold wallets, is restricted to the operators.
+func applyJobRoutes(api *gin.RouterGroup, jobsCtrl *controllers.JobsController) {
+	api.GET("/jobs", func(context *gin.Context) { VerifyRequestV2(context, jobsCtrl.GetJobs) })
+	api.GET("/jobs/:job", func(context *gin.Context) { VerifyRequestV2(context, jobsCtrl.GetJob) })
+	api.GET("/jobs/:job/last", func(context *gin.Context) { VerifyRequestV2(context, jobsCtrl.GetLastRun) })
+	api.POST("/jobs/:job/pause", adminOnly, func(context *gin.Context) { VerifyRequestV2(context, jobsCtrl.PauseJob) })
+	api.POST("/jobs/:job/resume", adminOnly, func(context *gin.Context) { VerifyRequestV2(context, jobsCtrl.ResumeJob) })
+	api.POST("/jobs/:job/run", adminOnly, func(context *gin.Context) { VerifyRequestV2(context, jobsCtrl.RunJob) })
+}
+
+const adminTokenHeader = "X-Admin-Token"
+
+// adminOnly restricts a route to the operators, who send PLUTUS_ADMIN_TOKEN in the
+// X-Admin-Token header. The route is disabled while no token is configured.
+func adminOnly(c *gin.Context) {
+	token := os.Getenv("PLUTUS_ADMIN_TOKEN")
+	if token == "" {
+		c.AbortWithStatus(http.StatusForbidden)
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(c.GetHeader(adminTokenHeader)), []byte(token)) != 1 {
+		c.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+	c.Next()
+}
+
+const requestIDHeader = "X-Request-ID"
+
+// requestLogMiddleware tags the request with an id, taken from the X-Request-ID header when
+// the caller sends a sane one, and writes the access log entry.
+func requestLogMiddleware(c *gin.Context) {
+	start := time.Now()
+	requestID := c.GetHeader(requestIDHeader)
+	if !validRequestID(requestID) {
+		requestID = logger.NewRequestID()
+	}
+	c.Set("request_id", requestID)
+	c.Header(requestIDHeader, requestID)
+	c.Next()
+	logger.Default().WithRequestID(requestID).Info("request",
+		"method", c.Request.Method,
+		"path", c.Request.URL.Path,
+		"status", c.Writer.Status(),
+		"latency_ms", time.Since(start).Milliseconds(),
+		"client_ip", c.ClientIP(),
+	)
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 64 {
+		return false
+	}
+	for _, r := range id {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+// metricsMiddleware records the count and latency of every request. Only the configured
+// coins are used as label so the series can't be inflated with arbitrary urls.
+func metricsMiddleware(c *gin.Context) {
+	start := time.Now()
+	c.Next()
+	route := c.FullPath()
+	if route == "" {
+		route = "unmatched"
+	}
+	coin := strings.ToUpper(c.Param("coin"))
+	if _, ok := coinfactory.Coins[coin]; !ok && coin != "" {
+		coin = "unknown"
+	}
+	metrics.Requests.WithLabelValues(c.Request.Method, route, coin, strconv.Itoa(c.Writer.Status())).Inc()
+	metrics.RequestDuration.WithLabelValues(c.Request.Method, route, coin).Observe(time.Since(start).Seconds())
+}
+
 func VerifyRequest(c *gin.Context, method func(params controllers.Params) (interface{}, error)) {
 	payload, err := mvt.VerifyRequest(c)
 	if err != nil {
@@ -101,9 +306,10 @@ func VerifyRequest(c *gin.Context, method func(params controllers.Params) (inter
 		return
 	}
 	params := controllers.Params{
-		Coin: c.Param("coin"),
-		Txid: c.Param("txid"),
-		Body: payload,
+		Coin:      c.Param("coin"),
+		Txid:      c.Param("txid"),
+		Body:      payload,
+		RequestID: c.GetString("request_id"),
 	}
 	response, err := method(params)
 	if err != nil {
@@ -127,10 +333,21 @@ func VerifyRequestV2(c *gin.Context, method func(params controllers.ParamsV2) (i
 	}
 	variables := c.Request.URL.Query()
 	params := controllers.ParamsV2{
-		Coin:    c.Param("coin"),
-		Txid:    c.Param("txid"),
-		Body:    payload,
-		Service: variables.Get("source"),
+		Coin:      c.Param("coin"),
+		Txid:      c.Param("txid"),
+		Address:   c.Param("addr"),
+		Body:      payload,
+		Service:   variables.Get("source"),
+		Job:       c.Param("job"),
+		RequestID: c.GetString("request_id"),
+		Label:     variables.Get("label"),
+		Reference: variables.Get("reference"),
+		ID:        c.Param("id"),
+		Status:    variables.Get("status"),
+		Page:      variables.Get("page"),
+		Limit:     variables.Get("limit"),
+		From:      variables.Get("from"),
+		To:        variables.Get("to"),
 	}
 	response, err := method(params)
 	if err != nil {
@@ -146,145 +363,100 @@ func VerifyRequestV2(c *gin.Context, method func(params controllers.ParamsV2) (i
 	return
 }
 
-func timer(ctrl *controllers.Controller) {
-	for {
-		time.Sleep(1 * time.Second)
-		currTime = CurrentTime{
-			Hour:   time.Now().Hour(),
-			Day:    time.Now().Day(),
-			Minute: time.Now().Minute(),
-			Second: time.Now().Second(),
-		}
-		if currTime.Second == 0 {
-			var wg sync.WaitGroup
-			wg.Add(1)
-			runCrons(&wg, ctrl)
-			wg.Wait()
-		}
+// newWatcher creates the deposit watcher, nil when no webhook is configured.
+func newWatcher(db *store.Store) *deposits.Watcher {
+	config, err := deposits.LoadConfig()
+	if err != nil {
+		panic(err)
 	}
+	if !config.Enabled() {
+		return nil
+	}
+	return deposits.NewWatcher(config, db, controllers.IssuedAddresses, deposits.NewBlockbook(), deposits.NewNotifier())
 }
 
-func runCrons(mainWg *sync.WaitGroup, ctrl *controllers.Controller) {
-	defer func() {
-		mainWg.Done()
-	}()
-	var wg sync.WaitGroup
-	wg.Add(1)
-	go runCronMinutes(180, runSend, &wg, ctrl) // 180 minutes
-	wg.Wait()
-}
-
-func runCronMinutes(schedule int, function func(ctrl *controllers.Controller), wg *sync.WaitGroup, ctrl *controllers.Controller) {
-	go func() {
-		defer func() {
-			wg.Done()
-		}()
-		remainder := currTime.Minute % schedule
-		if remainder == 0 {
-			function(ctrl)
-		}
-		return
-	}()
-}
-
-var floatType = reflect.TypeOf(float64(0))
-
-func runSend(ctrl *controllers.Controller) {
-	fmt.Println("Running send script")
-	for _, coin := range coinfactory.Coins {
-		if coin.Info.StableCoin || coin.Info.Tag == "DASH" {
-			continue
-		}
-		params := controllers.Params{
-			Coin: coin.Info.Tag,
-		}
-		balance, err := ctrl.GetBalance(params)
+func startJobs(ctrl *controllers.Controller, db *store.Store, watcher *deposits.Watcher) *scheduler.Scheduler {
+	sweepConfig, err := sweep.LoadConfig()
+	if err != nil {
+		panic(err)
+	}
+	sweepJob := sweep.NewJob(ctrl, sweepConfig, adrestia.NewClientFromEnv().GetDepositAddress, sweep.NewColdWallet(db))
+	jobs := scheduler.NewScheduler()
+	err = jobs.Register("sweep", sweepConfig.Schedule, sweepJob.Run)
+	if err != nil {
+		panic(err)
+	}
+	if watcher != nil {
+		err = jobs.Register("deposits", watcher.Config().Schedule, watcher.Run)
 		if err != nil {
-			continue
-		}
-		plutusBalance, ok := balance.(plutus.Balance)
-		if !ok {
-			continue
-		}
-		send := checkSend(plutusBalance.Confirmed, coin.Info.Tag)
-		if send {
-			address, err := getDepositAddress(coin.Info.Tag)
-			if address == "" || err != nil {
-				continue
-			}
-			sendInfo := plutus.SendAddressBodyReq{
-				Amount:  plutusBalance.Confirmed,
-				Address: address,
-				Coin:    coin.Info.Tag,
-			}
-			rawData, err := json.Marshal(sendInfo)
-			if err != nil {
-				continue
-			}
-			newParams := controllers.Params{
-				Coin: coin.Info.Tag,
-				Body: rawData,
-			}
-			txId, err := ctrl.SendToAddress(newParams)
-			log.Println("sent ", sendInfo.Amount, " ", sendInfo.Coin, " to ", sendInfo.Address)
-			fmt.Println(txId, err)
+			panic(err)
 		}
 	}
+	if elector := newElector(); elector != nil {
+		elector.Start()
+		onShutdown(elector.Stop)
+		jobs.SetLeader(elector.IsLeader)
+	} else if count := replicas(); count > 1 {
+		// without a shared lease the jobs would run on every instance, on Heroku only web.1
+		// keeps them
+		logger.Error("main: the jobs don't run on this instance, set LEADER_LEASE=file to coordinate several replicas", "replicas", count)
+		jobs.SetLeader(func() bool { return false })
+	}
+	jobs.Start()
+	onShutdown(jobs.Stop)
+	return jobs
 }
 
-func checkSend(balance float64, coin string) bool {
-	if coin != "BTC" && coin != "LTC" && balance > 1 {
-		return true
+// newElector configures the leader election used to run the jobs on a single instance.
+// LEADER_LEASE selects the lease: "file" (flock on LEADER_LEASE_FILE, which must be shared
+// by all the instances) or "memory" (single instance, refused on Heroku and when LEADER_REPLICAS
+// tells there are more). Without it the jobs only run when there is a single instance.
+func newElector() *leader.Elector {
+	var lease leader.Lease
+	switch os.Getenv("LEADER_LEASE") {
+	case "":
+		return nil
+	case "file":
+		lease = &leader.FileLease{Path: os.Getenv("LEADER_LEASE_FILE")}
+	case "memory":
+		if replicas() > 1 || os.Getenv("DYNO") != "" {
+			panic("the memory lease only coordinates a single instance, use LEADER_LEASE=file with several replicas")
+		}
+		lease = &leader.RowLease{Name: "jobs", Store: leader.NewMemoryStore()}
+	default:
+		panic(errors.New("unknown LEADER_LEASE " + os.Getenv("LEADER_LEASE")))
 	}
-	if coin == "BTC" && balance > 0.001 {
-		return true
+	id := os.Getenv("LEADER_ID")
+	if id == "" {
+		hostname, _ := os.Hostname()
+		id = hostname + "-" + strconv.Itoa(os.Getpid())
 	}
-	if coin == "LTC" && balance > 0.1 {
-		return true
+	ttl := 30 * time.Second
+	if leaseTTL := os.Getenv("LEADER_LEASE_TTL"); leaseTTL != "" {
+		var err error
+		ttl, err = time.ParseDuration(leaseTTL)
+		if err != nil {
+			panic(err)
+		}
 	}
-	return false
+	return leader.NewElector(lease, id, ttl)
 }
 
-func getDepositAddress(coin string) (string, error) {
-	addr, err := getAddress(coin, "http://adrestia.polispay.com")
-	return addr.ExchangeAddress.Address, err
-}
-
-func getAddress(coin string, adrestiaUrl string) (address models.AddressResponse, err error) {
-	url := adrestiaUrl + "/address/" + coin
-	req, err := mvt.CreateMVTToken("GET", url, "plutus", os.Getenv("MASTER_PASSWORD"), nil, os.Getenv("HESTIA_AUTH_USERNAME"), os.Getenv("HESTIA_AUTH_PASSWORD"), os.Getenv("PLUTUS_PRIVATE_KEY"))
-	if err != nil {
-		return
-	}
-	client := http.Client{
-		Timeout: 5 * time.Second,
-	}
-	res, err := client.Do(req)
-	if err != nil {
-		return
-	}
-	defer res.Body.Close()
-	tokenResponse, err := ioutil.ReadAll(res.Body)
-	if err != nil {
-		return
-	}
-	var tokenString string
-	err = json.Unmarshal(tokenResponse, &tokenString)
-	if err != nil {
-		return
-	}
-	headerSignature := res.Header.Get("service")
-	if headerSignature == "" {
-		err = errors.New("no header signature")
-		return
-	}
-	valid, payload := mrt.VerifyMRTToken(headerSignature, tokenString, os.Getenv("ADRESTIA_PUBLIC_KEY"), os.Getenv("MASTER_PASSWORD"))
-	if !valid {
-		return
+// replicas returns the amount of instances deployed, from LEADER_REPLICAS. On Heroku a dyno
+// numbered above 1 (DYNO=web.2) proves there are several.
+func replicas() int {
+	count := 1
+	if env := os.Getenv("LEADER_REPLICAS"); env != "" {
+		var err error
+		count, err = strconv.Atoi(env)
+		if err != nil || count < 1 {
+			panic(errors.New("LEADER_REPLICAS must be a positive number"))
+		}
 	}
-	err = json.Unmarshal(payload, &address)
-	if err != nil {
-		return
+	if dyno := strings.SplitN(os.Getenv("DYNO"), ".", 2); len(dyno) == 2 {
+		if number, err := strconv.Atoi(dyno[1]); err == nil && number > count {
+			count = number
+		}
 	}
-	return
+	return count
 }
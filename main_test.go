@@ -0,0 +1,32 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grupokindynos/plutus/controllers"
+)
+
+func TestJobRoutesAdminOnly(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	applyJobRoutes(r.Group("/v2/"), &controllers.JobsController{})
+	os.Setenv("PLUTUS_ADMIN_TOKEN", "operator")
+	defer os.Unsetenv("PLUTUS_ADMIN_TOKEN")
+	for _, action := range []string{"pause", "resume", "run"} {
+		for _, token := range []string{"", "service"} {
+			req := httptest.NewRequest("POST", "/v2/jobs/sweep/"+action, nil)
+			if token != "" {
+				req.Header.Set(adminTokenHeader, token)
+			}
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+			if rec.Code != http.StatusUnauthorized {
+				t.Error("the " + action + " of a job must be refused without the admin token")
+			}
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package metrics
+
+import (
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// DefaultBuckets are the latency buckets, in seconds, used by the histograms.
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+var (
+	Requests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "plutus_http_requests_total",
+		Help: "HTTP requests handled, by route, coin and status code.",
+	}, []string{"method", "route", "coin", "status"})
+	RequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "plutus_http_request_duration_seconds",
+		Help:    "HTTP request latencies, by route and coin.",
+		Buckets: DefaultBuckets,
+	}, []string{"method", "route", "coin"})
+	Sends = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "plutus_sends_total",
+		Help: "Outgoing transactions, by coin, result and error class.",
+	}, []string{"coin", "result", "error_class"})
+	FeePaid = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "plutus_fee_paid_total",
+		Help: "Fees paid by the outgoing transactions in coin units. Token fees are paid and counted in ETH as the gas price times the gas limit.",
+	}, []string{"coin"})
+	HotBalance = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "plutus_hot_balance",
+		Help: "Last known hot wallet balance in coin units.",
+	}, []string{"coin", "state"})
+	BlockbookDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "plutus_blockbook_request_duration_seconds",
+		Help:    "Blockbook call latencies, by coin, call and result.",
+		Buckets: DefaultBuckets,
+	}, []string{"coin", "call", "result"})
+	SweepOutcomes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "plutus_sweep_coins_total",
+		Help: "Sweep job outcomes, by coin and status.",
+	}, []string{"coin", "status"})
+	Deposits = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "plutus_deposits_total",
+		Help: "Deposits detected on the issued addresses, by coin.",
+	}, []string{"coin"})
+	WebhookDeliveries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "plutus_webhook_deliveries_total",
+		Help: "Webhook delivery attempts, by event type and result.",
+	}, []string{"type", "result"})
+)
+
+// Registry holds the wallet metrics served on /metrics, with the go runtime and process
+// metrics.
+var Registry = prometheus.NewRegistry()
+
+func init() {
+	Registry.MustRegister(
+		prometheus.NewGoCollector(),
+		prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}),
+		Requests,
+		RequestDuration,
+		Sends,
+		FeePaid,
+		HotBalance,
+		BlockbookDuration,
+		SweepOutcomes,
+		Deposits,
+		WebhookDeliveries,
+	)
+}
+
+// Error classes used by the send metrics.
+const (
+	ClassNone              = ""
+	ClassInsufficientFunds = "insufficient_funds"
+	ClassUnavailable       = "unavailable"
+	ClassBackend           = "backend"
+	ClassInvalidRequest    = "invalid_request"
+	ClassSigning           = "signing"
+	ClassOther             = "other"
+)
+
+// Handler serves the Registry in the Prometheus exposition format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
+
+// ObserveSend records the outcome of an outgoing transaction.
+func ObserveSend(coin string, err error) {
+	if err != nil {
+		Sends.WithLabelValues(coin, "failure", ErrorClass(err)).Inc()
+		return
+	}
+	Sends.WithLabelValues(coin, "success", ClassNone).Inc()
+}
+
+// ObserveBlockbook records the latency of a blockbook call started at start.
+func ObserveBlockbook(coin string, call string, start time.Time, err error) {
+	result := "success"
+	if err != nil {
+		result = "failure"
+	}
+	BlockbookDuration.WithLabelValues(coin, call, result).Observe(time.Since(start).Seconds())
+}
+
+// ErrorClass groups the errors of the wallet operations in a small set of classes so they
+// can be used as a metric label.
+func ErrorClass(err error) string {
+	if err == nil {
+		return ClassNone
+	}
+	if _, ok := err.(net.Error); ok {
+		return ClassBackend
+	}
+	msg := strings.ToLower(err.Error())
+	switch {
+	case strings.Contains(msg, "temporarily unavailable"):
+		return ClassUnavailable
+	case strings.Contains(msg, "not enough"), strings.Contains(msg, "no balance"),
+		strings.Contains(msg, "insufficient"), strings.Contains(msg, "no eth"), strings.Contains(msg, "no token"):
+		return ClassInsufficientFunds
+	case strings.Contains(msg, "sign"):
+		return ClassSigning
+	case strings.Contains(msg, "decode"), strings.Contains(msg, "invalid"), strings.Contains(msg, "unmarshal"),
+		strings.Contains(msg, "not found"), strings.Contains(msg, "missing"):
+		return ClassInvalidRequest
+	case strings.Contains(msg, "timeout"), strings.Contains(msg, "connection"), strings.Contains(msg, "status code"),
+		strings.Contains(msg, "eof"), strings.Contains(msg, "blockbook"):
+		return ClassBackend
+	}
+	return ClassOther
+}
@@ -0,0 +1,61 @@
+package metrics
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestExposition(t *testing.T) {
+	ObserveSend("BTC", nil)
+	ObserveSend("BTC", errors.New("no balance available"))
+	ObserveBlockbook("BTC", "xpub", time.Now(), nil)
+	HotBalance.WithLabelValues("LTC", "confirmed").Set(1.5)
+	if value := testutil.ToFloat64(Sends.WithLabelValues("BTC", "failure", ClassInsufficientFunds)); value != 1 {
+		t.Error("the failed send must be counted with its class")
+	}
+
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body, _ := ioutil.ReadAll(rec.Body)
+	expected := []string{
+		"# TYPE plutus_sends_total counter",
+		`plutus_sends_total{coin="BTC",error_class="",result="success"} 1`,
+		"# TYPE plutus_hot_balance gauge",
+		`plutus_hot_balance{coin="LTC",state="confirmed"} 1.5`,
+		"# TYPE plutus_blockbook_request_duration_seconds histogram",
+		`plutus_blockbook_request_duration_seconds_count{call="xpub",coin="BTC",result="success"} 1`,
+		"# TYPE go_goroutines gauge",
+	}
+	for _, line := range expected {
+		if !strings.Contains(string(body), line+"\n") {
+			t.Error("missing line " + line)
+		}
+	}
+}
+
+func TestErrorClass(t *testing.T) {
+	var classes = []struct {
+		err   error
+		class string
+	}{
+		{nil, ClassNone},
+		{errors.New("no balance available"), ClassInsufficientFunds},
+		{errors.New("not enough token available"), ClassInsufficientFunds},
+		{errors.New("the coin BTC is temporarily unavailable: EOF"), ClassUnavailable},
+		{errors.New("failed to sign transaction"), ClassSigning},
+		{errors.New("decoded address is of unknown format"), ClassInvalidRequest},
+		{errors.New("unexpected EOF"), ClassBackend},
+		{errors.New("nonce failed"), ClassOther},
+	}
+	for _, c := range classes {
+		if class := ErrorClass(c.err); class != c.class {
+			t.Error("wrong class " + class + " for " + c.class)
+		}
+	}
+}
@@ -1,5 +1,10 @@
 package models
 
+import (
+	"math/big"
+	"time"
+)
+
 type BodyReq struct {
 	Payload string `bson:"payload" json:"payload"`
 }
@@ -23,4 +28,300 @@ type ResponseTxid struct {
 type AddrInfo struct {
 	Addr string
 	Path int
+	// Chain is 0 for the receive addresses and 1 for the change ones.
+	Chain int
+	// Multisig is set for the addresses of the multisig wallet, Path is their index.
+	Multisig bool
+}
+
+type CoinHealth struct {
+	Coin              string     `json:"coin"`
+	Ready             bool       `json:"ready"`
+	Reachable         bool       `json:"reachable"`
+	LatencyMs         int64      `json:"latency_ms"`
+	LastSync          *time.Time `json:"last_sync,omitempty"`
+	MnemonicAvailable bool       `json:"mnemonic_available"`
+	// PublicKeyAvailable is only reported in watch-only mode.
+	PublicKeyAvailable bool   `json:"public_key_available,omitempty"`
+	Error              string `json:"error,omitempty"`
+}
+
+type ServiceHealth struct {
+	Reachable bool   `json:"reachable"`
+	LatencyMs int64  `json:"latency_ms"`
+	Error     string `json:"error,omitempty"`
+}
+
+type Readiness struct {
+	Ready bool `json:"ready"`
+	// Status is ready, degraded when some coins are not ready or unavailable.
+	Status    string        `json:"status"`
+	CheckedAt time.Time     `json:"checked_at"`
+	WatchOnly bool          `json:"watch_only"`
+	Coins     []CoinHealth  `json:"coins"`
+	GasOracle ServiceHealth `json:"gas_oracle"`
+}
+
+type PSBTBodyReq struct {
+	Coin string `json:"coin"`
+	PSBT string `json:"psbt"`
+	// Broadcast sends the transaction once every input is finalized.
+	Broadcast bool `json:"broadcast"`
+}
+
+type PSBTResponse struct {
+	PSBT     string  `json:"psbt"`
+	Fee      float64 `json:"fee"`
+	Signed   int     `json:"signed"`
+	Complete bool    `json:"complete"`
+	RawTx    string  `json:"raw_tx,omitempty"`
+	Txid     string  `json:"txid,omitempty"`
+}
+
+type MultisigBodyReq struct {
+	Coin string `json:"coin"`
+	// PSBT holds the signatures of a cosigner for a spend created by Plutus.
+	PSBT string `json:"psbt"`
+	// Broadcast sends the transaction once the threshold is met.
+	Broadcast bool `json:"broadcast"`
+}
+
+// MultisigTx tracks a multisig spend until it gathers the signatures it needs. ID is the
+// hash of the unsigned transaction, Txid is only known once the transaction is complete.
+type MultisigTx struct {
+	ID       string  `json:"id"`
+	Coin     string  `json:"coin"`
+	Address  string  `json:"address"`
+	Amount   float64 `json:"amount"`
+	Fee      float64 `json:"fee"`
+	PSBT     string  `json:"psbt"`
+	Required int     `json:"required"`
+	// Signatures is the amount of signatures of every input.
+	Signatures []int     `json:"signatures"`
+	Complete   bool      `json:"complete"`
+	RawTx      string    `json:"raw_tx,omitempty"`
+	Txid       string    `json:"txid,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// AddressMeta records who requested an address and why.
+type AddressMeta struct {
+	Coin    string `json:"coin"`
+	Address string `json:"address"`
+	Path    int    `json:"path"`
+	// Multisig tells whether the address belongs to the multisig wallet of the coin.
+	Multisig  bool      `json:"multisig,omitempty"`
+	Service   string    `json:"service,omitempty"`
+	Label     string    `json:"label,omitempty"`
+	Reference string    `json:"reference,omitempty"`
+	RequestID string    `json:"request_id,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	// LastIssuedAt is set on the ethereum account addresses, issued again on every request.
+	LastIssuedAt *time.Time `json:"last_issued_at,omitempty"`
+}
+
+type RescanResponse struct {
+	Coin string `json:"coin"`
+	Gap  int    `json:"gap"`
+	// External and Internal are the amount of known addresses of every chain.
+	External         int `json:"external"`
+	Internal         int `json:"internal"`
+	Multisig         int `json:"multisig"`
+	LastUsed         int `json:"last_used"`
+	LastUsedInternal int `json:"last_used_internal"`
+}
+
+// DepositEvent is posted to the webhook of the service that requested the address. ID is
+// the same on every delivery of the event so the receivers can ignore duplicates.
+type DepositEvent struct {
+	ID            string    `json:"id"`
+	Type          string    `json:"type"`
+	Coin          string    `json:"coin"`
+	Address       string    `json:"address"`
+	Txid          string    `json:"txid"`
+	Amount        float64   `json:"amount"`
+	Confirmations int       `json:"confirmations"`
+	Service       string    `json:"service,omitempty"`
+	Label         string    `json:"label,omitempty"`
+	Reference     string    `json:"reference,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+type WebhookDelivery struct {
+	Event       DepositEvent `json:"event"`
+	URL         string       `json:"url"`
+	Status      string       `json:"status"`
+	Attempts    int          `json:"attempts"`
+	LastError   string       `json:"last_error,omitempty"`
+	NextAttempt time.Time    `json:"next_attempt"`
+	DeliveredAt *time.Time   `json:"delivered_at,omitempty"`
+	CreatedAt   time.Time    `json:"created_at"`
+}
+
+// ExpectedPaymentReq registers a payment expected on an issued address.
+type ExpectedPaymentReq struct {
+	Address string  `json:"address"`
+	Amount  float64 `json:"amount"`
+	// Expiry is the amount of seconds the payment is expected for.
+	Expiry    int64  `json:"expiry"`
+	Reference string `json:"reference"`
+}
+
+// ExpectedPayment is an invoice matched against the transactions paying its address.
+// Received sums the payments seen between the registration and the expiry.
+type ExpectedPayment struct {
+	Coin          string     `json:"coin"`
+	Address       string     `json:"address"`
+	Amount        float64    `json:"amount"`
+	Reference     string     `json:"reference,omitempty"`
+	Service       string     `json:"service,omitempty"`
+	Status        string     `json:"status"`
+	Received      float64    `json:"received"`
+	Txids         []string   `json:"txids"`
+	Confirmations int        `json:"confirmations"`
+	ExpiresAt     time.Time  `json:"expires_at"`
+	PaidAt        *time.Time `json:"paid_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// TxReport details a raw transaction sent to Plutus for validation. Amounts are in
+// satoshis, or in wei and token units for the EVM transactions.
+type TxReport struct {
+	Coin string `json:"coin"`
+	Txid string `json:"txid"`
+	// Valid is set when a single output pays the amount to Plutus, every signature is
+	// valid and no input is spent.
+	Valid bool `json:"valid"`
+	// PaysAmount is set when a single output pays the amount to Plutus.
+	PaysAmount bool             `json:"pays_amount"`
+	Paid       int64            `json:"paid"`
+	Outputs    []TxReportOutput `json:"outputs"`
+	Inputs     []TxReportInput  `json:"inputs,omitempty"`
+	// SignaturesValid is set when every input is signed with a valid signature.
+	SignaturesValid bool `json:"signatures_valid"`
+	// InputsUnspent is set when no input is spent, or the nonce is unused for EVM.
+	InputsUnspent bool `json:"inputs_unspent"`
+	// Fee is unknown (zero) when an output spent by the transaction can't be found. For
+	// EVM it is the maximum fee, the gas limit times the gas price.
+	Fee int64 `json:"fee"`
+	// FeeRate is in satoshis per virtual byte, or the gas price in gwei for EVM.
+	FeeRate float64 `json:"fee_rate"`
+	Size    int     `json:"size"`
+	VSize   int     `json:"vsize"`
+	// RBF is set when the transaction signals BIP125 replaceability.
+	RBF bool         `json:"rbf"`
+	EVM *EVMTxReport `json:"evm,omitempty"`
+}
+
+type TxReportOutput struct {
+	Index   int    `json:"index"`
+	Address string `json:"address"`
+	Amount  int64  `json:"amount"`
+	Mine    bool   `json:"mine"`
+}
+
+type TxReportInput struct {
+	Txid    string `json:"txid"`
+	Vout    uint32 `json:"vout"`
+	Address string `json:"address,omitempty"`
+	Amount  int64  `json:"amount"`
+	Signed  bool   `json:"signed"`
+	// SignatureValid is set when the signatures satisfy the script of the spent output.
+	SignatureValid bool   `json:"signature_valid"`
+	Spent          bool   `json:"spent"`
+	Error          string `json:"error,omitempty"`
+}
+
+type EVMTxReport struct {
+	From     string `json:"from,omitempty"`
+	To       string `json:"to"`
+	Contract string `json:"contract,omitempty"`
+	// TokenContract is the configured contract of the token, the transaction pays nothing
+	// when it calls another one.
+	TokenContract string `json:"token_contract,omitempty"`
+	Nonce         uint64 `json:"nonce"`
+	// AccountNonce is the next nonce of the sender, the transaction can't be mined below it.
+	AccountNonce uint64   `json:"account_nonce"`
+	GasLimit     uint64   `json:"gas_limit"`
+	GasPrice     *big.Int `json:"gas_price"`
+	Value        *big.Int `json:"value"`
+	ChainID      *big.Int `json:"chain_id,omitempty"`
+}
+
+// SendJournalEntry records a transaction sent by Plutus and who requested it.
+type SendJournalEntry struct {
+	Coin    string  `json:"coin"`
+	Txid    string  `json:"txid"`
+	Address string  `json:"address"`
+	Amount  float64 `json:"amount"`
+	Fee     float64 `json:"fee"`
+	// FeeRate is in satoshis per kB, for the UTXO coins.
+	FeeRate   int64          `json:"fee_rate,omitempty"`
+	Inputs    []JournalInput `json:"inputs,omitempty"`
+	Service   string         `json:"service,omitempty"`
+	RequestID string         `json:"request_id,omitempty"`
+	// Replaces and ReplacedBy link the sends replaced to bump their fee.
+	Replaces   string `json:"replaces,omitempty"`
+	ReplacedBy string `json:"replaced_by,omitempty"`
+	// Accelerates is the parent transaction of a child paying for it.
+	Accelerates string    `json:"accelerates,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// BumpFeeReq asks to replace a send with a higher fee rate, in satoshis per kB. The rate
+// estimated by the backend is used when FeeRate is zero.
+type BumpFeeReq struct {
+	Coin    string `json:"coin"`
+	Txid    string `json:"txid"`
+	FeeRate int64  `json:"fee_rate"`
+}
+
+// AccelerateReq asks to spend our outputs of an unconfirmed transaction with a child bringing
+// both to FeeRate, in satoshis per kB. The rate estimated by the backend is used when FeeRate
+// is zero.
+type AccelerateReq struct {
+	Coin    string `json:"coin"`
+	Txid    string `json:"txid"`
+	FeeRate int64  `json:"fee_rate"`
+}
+
+// JournalInput is an output of the wallet spent by a send, Path is the index of its
+// address in the receive chain.
+type JournalInput struct {
+	Txid    string `json:"txid"`
+	Vout    uint32 `json:"vout"`
+	Value   int64  `json:"value"`
+	Address string `json:"address"`
+	Path    uint32 `json:"path"`
+}
+
+// HistoryTx is a transaction moving coins in or out of the wallet. Amount is the net
+// amount received by the wallet, negative when sending.
+type HistoryTx struct {
+	Txid          string    `json:"txid"`
+	Time          time.Time `json:"time"`
+	BlockHeight   int       `json:"block_height"`
+	Confirmations int       `json:"confirmations"`
+	// Direction is "in", "out" or "self" for the transactions between our own addresses.
+	Direction string   `json:"direction"`
+	Amount    float64  `json:"amount"`
+	Fee       float64  `json:"fee"`
+	Addresses []string `json:"addresses"`
+	// Send is the journal entry of the transactions sent by Plutus.
+	Send *SendJournalEntry `json:"send,omitempty"`
+	// Unseen is set for the sends of the journal unknown to the backend.
+	Unseen bool `json:"unseen,omitempty"`
+}
+
+type History struct {
+	Coin  string `json:"coin"`
+	Page  int    `json:"page"`
+	Limit int    `json:"limit"`
+	// Total and TotalPages count the transactions of the backend, Total includes the sends
+	// it doesn't know yet.
+	Total        int         `json:"total"`
+	TotalPages   int         `json:"total_pages"`
+	Transactions []HistoryTx `json:"transactions"`
 }
@@ -0,0 +1,137 @@
+package multisig
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Script types of the multisig wallets.
+const (
+	P2SH  = "p2sh"
+	P2WSH = "p2wsh"
+)
+
+// MaxKeys is the largest amount of keys of a standard multisig redeem script.
+const MaxKeys = 15
+
+const hardenedKeyStart = 0x80000000
+
+// Cosigner is a key of a multisig wallet held outside Plutus.
+type Cosigner struct {
+	// Xpub is the account public key of the cosigner, the wallet uses its receive chain.
+	Xpub string `json:"xpub"`
+	// Fingerprint (8 hex characters) and Path are the origin of Xpub, written to the PSBTs
+	// so the cosigner wallets recognize their keys.
+	Fingerprint string `json:"fingerprint"`
+	Path        string `json:"path"`
+}
+
+// Wallet is an m-of-n wallet made of the key of Plutus and the keys of the cosigners.
+type Wallet struct {
+	// Type is P2SH or P2WSH.
+	Type string `json:"type"`
+	// Required is the amount of signatures a spend needs.
+	Required  int        `json:"required"`
+	Cosigners []Cosigner `json:"cosigners"`
+}
+
+// Keys returns the total amount of keys of the wallet, Plutus included.
+func (w Wallet) Keys() int {
+	return len(w.Cosigners) + 1
+}
+
+// Config lists the multisig wallets by coin tag.
+type Config struct {
+	Coins map[string]Wallet `json:"coins"`
+}
+
+// LoadConfig reads the JSON file pointed by MULTISIG_CONFIG. Without it no coin uses a
+// multisig wallet.
+func LoadConfig() (Config, error) {
+	var config Config
+	path := os.Getenv("MULTISIG_CONFIG")
+	if path == "" {
+		return config, nil
+	}
+	file, err := ioutil.ReadFile(path)
+	if err != nil {
+		return Config{}, err
+	}
+	err = json.Unmarshal(file, &config)
+	if err != nil {
+		return Config{}, err
+	}
+	return config, config.Validate()
+}
+
+// Wallet returns the multisig wallet of a coin, if configured.
+func (c Config) Wallet(tag string) (Wallet, bool) {
+	wallet, ok := c.Coins[tag]
+	return wallet, ok
+}
+
+// Validate checks the thresholds and the key origins of every wallet.
+func (c Config) Validate() error {
+	for tag, wallet := range c.Coins {
+		if wallet.Type != P2SH && wallet.Type != P2WSH {
+			return errors.New("multisig wallet of " + tag + ": unknown type " + wallet.Type)
+		}
+		if len(wallet.Cosigners) == 0 || wallet.Keys() > MaxKeys {
+			return errors.New("multisig wallet of " + tag + ": between 1 and " + strconv.Itoa(MaxKeys-1) + " cosigners are required")
+		}
+		if wallet.Required < 1 || wallet.Required > wallet.Keys() {
+			return errors.New("multisig wallet of " + tag + ": the required signatures must be between 1 and " + strconv.Itoa(wallet.Keys()))
+		}
+		for _, cosigner := range wallet.Cosigners {
+			if cosigner.Xpub == "" {
+				return errors.New("multisig wallet of " + tag + ": a cosigner is missing its xpub")
+			}
+			if _, err := ParseFingerprint(cosigner.Fingerprint); err != nil {
+				return errors.New("multisig wallet of " + tag + ": " + err.Error())
+			}
+			if _, err := ParsePath(cosigner.Path); err != nil {
+				return errors.New("multisig wallet of " + tag + ": " + err.Error())
+			}
+		}
+	}
+	return nil
+}
+
+// ParseFingerprint decodes a master key fingerprint as shown by the wallets, the way it is
+// stored in the PSBTs.
+func ParseFingerprint(fingerprint string) (uint32, error) {
+	decoded, err := hex.DecodeString(fingerprint)
+	if err != nil || len(decoded) != 4 {
+		return 0, errors.New("invalid fingerprint " + fingerprint)
+	}
+	return binary.LittleEndian.Uint32(decoded), nil
+}
+
+// ParsePath decodes a derivation path like m/48'/0'/0'/2'. Hardened indexes are marked with
+// ' or h.
+func ParsePath(path string) ([]uint32, error) {
+	parts := strings.Split(path, "/")
+	if parts[0] != "m" {
+		return nil, errors.New("invalid derivation path " + path)
+	}
+	indexes := make([]uint32, 0, len(parts)-1)
+	for _, part := range parts[1:] {
+		var hardened uint32
+		if strings.HasSuffix(part, "'") || strings.HasSuffix(part, "h") {
+			hardened = hardenedKeyStart
+			part = part[:len(part)-1]
+		}
+		index, err := strconv.ParseUint(part, 10, 31)
+		if err != nil {
+			return nil, errors.New("invalid derivation path " + path)
+		}
+		indexes = append(indexes, uint32(index)+hardened)
+	}
+	return indexes, nil
+}
@@ -0,0 +1,55 @@
+package multisig
+
+import (
+	"testing"
+)
+
+var testCosigner = Cosigner{Xpub: "xpub", Fingerprint: "d34db33f", Path: "m/48'/0'/0'/2'"}
+
+var testWallets = []struct {
+	name   string
+	wallet Wallet
+	valid  bool
+}{
+	{"2 of 2", Wallet{Type: P2SH, Required: 2, Cosigners: []Cosigner{testCosigner}}, true},
+	{"2 of 3 segwit", Wallet{Type: P2WSH, Required: 2, Cosigners: []Cosigner{testCosigner, testCosigner}}, true},
+	{"unknown type", Wallet{Type: "p2tr", Required: 1, Cosigners: []Cosigner{testCosigner}}, false},
+	{"without cosigners", Wallet{Type: P2SH, Required: 1}, false},
+	{"threshold above the keys", Wallet{Type: P2SH, Required: 3, Cosigners: []Cosigner{testCosigner}}, false},
+	{"no threshold", Wallet{Type: P2SH, Cosigners: []Cosigner{testCosigner}}, false},
+	{"invalid fingerprint", Wallet{Type: P2SH, Required: 1, Cosigners: []Cosigner{{Xpub: "xpub", Fingerprint: "d34d", Path: "m/0"}}}, false},
+	{"invalid path", Wallet{Type: P2SH, Required: 1, Cosigners: []Cosigner{{Xpub: "xpub", Fingerprint: "d34db33f", Path: "48'/0'"}}}, false},
+}
+
+func TestValidate(t *testing.T) {
+	for _, test := range testWallets {
+		err := Config{Coins: map[string]Wallet{"BTC": test.wallet}}.Validate()
+		if (err == nil) != test.valid {
+			t.Error("unexpected validation result for " + test.name)
+		}
+	}
+}
+
+func TestParsePath(t *testing.T) {
+	path, err := ParsePath("m/48'/1h/0/2'")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []uint32{hardenedKeyStart + 48, hardenedKeyStart + 1, 0, hardenedKeyStart + 2}
+	if len(path) != len(expected) {
+		t.Fatal("unexpected path length")
+	}
+	for i := range path {
+		if path[i] != expected[i] {
+			t.Error("unexpected index in the path")
+		}
+	}
+	for _, invalid := range []string{"", "m/", "m/a", "m/2147483648", "0/1"} {
+		if _, err := ParsePath(invalid); err == nil {
+			t.Error("expected an error for the path " + invalid)
+		}
+	}
+	if _, err := ParsePath("m"); err != nil {
+		t.Error("the master key path must be accepted")
+	}
+}
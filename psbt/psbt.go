@@ -0,0 +1,634 @@
+// Package psbt implements the partially signed transaction format of BIP174, used to hand
+// the transactions built by Plutus to reviewers, co-signers and hardware wallets.
+package psbt
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"sort"
+
+	"github.com/martinboehm/btcd/wire"
+)
+
+const (
+	globalUnsignedTx = 0x00
+	globalVersion    = 0xfb
+
+	inputNonWitnessUtxo     = 0x00
+	inputWitnessUtxo        = 0x01
+	inputPartialSig         = 0x02
+	inputSighashType        = 0x03
+	inputRedeemScript       = 0x04
+	inputWitnessScript      = 0x05
+	inputBip32Derivation    = 0x06
+	inputFinalScriptSig     = 0x07
+	inputFinalScriptWitness = 0x08
+
+	outputRedeemScript    = 0x00
+	outputWitnessScript   = 0x01
+	outputBip32Derivation = 0x02
+)
+
+var magic = []byte{0x70, 0x73, 0x62, 0x74, 0xff}
+
+// maxPairSize bounds a single key or value, a full previous transaction included.
+const maxPairSize = 4000000
+
+var (
+	ErrInvalidMagic  = errors.New("psbt: invalid magic bytes")
+	ErrInvalidKey    = errors.New("psbt: invalid key")
+	ErrInvalidValue  = errors.New("psbt: invalid value")
+	ErrDuplicateKey  = errors.New("psbt: duplicate key")
+	ErrMissingTx     = errors.New("psbt: the unsigned transaction is missing")
+	ErrSignedTx      = errors.New("psbt: the unsigned transaction has signatures")
+	ErrUnsupported   = errors.New("psbt: unsupported version")
+	ErrMissingUtxo   = errors.New("psbt: the output spent by the input is missing")
+	ErrUtxoMismatch  = errors.New("psbt: the previous transaction doesn't match the input")
+	ErrIndexRange    = errors.New("psbt: input index out of range")
+	ErrNegativeFee   = errors.New("psbt: the outputs spend more than the inputs")
+	ErrNotFinalized  = errors.New("psbt: not every input is finalized")
+	ErrInvalidPubKey = errors.New("psbt: invalid public key")
+)
+
+// Unknown is a key-value pair this package doesn't interpret, it is kept as is.
+type Unknown struct {
+	Key   []byte
+	Value []byte
+}
+
+// Bip32Derivation tells which key of an HD wallet a public key comes from. Fingerprint
+// holds the first 4 bytes of the hash160 of the master public key, in wire order.
+type Bip32Derivation struct {
+	PubKey      []byte
+	Fingerprint uint32
+	Path        []uint32
+}
+
+type PartialSig struct {
+	PubKey []byte
+	// Signature is the DER signature followed by the sighash type.
+	Signature []byte
+}
+
+type Input struct {
+	NonWitnessUtxo     *wire.MsgTx
+	WitnessUtxo        *wire.TxOut
+	PartialSigs        []PartialSig
+	SighashType        uint32
+	RedeemScript       []byte
+	WitnessScript      []byte
+	Bip32Derivation    []Bip32Derivation
+	FinalScriptSig     []byte
+	FinalScriptWitness [][]byte
+	Unknowns           []Unknown
+}
+
+type Output struct {
+	RedeemScript    []byte
+	WitnessScript   []byte
+	Bip32Derivation []Bip32Derivation
+	Unknowns        []Unknown
+}
+
+type Packet struct {
+	UnsignedTx *wire.MsgTx
+	Inputs     []Input
+	Outputs    []Output
+	Unknowns   []Unknown
+}
+
+// New returns a packet for a transaction without signatures.
+func New(tx *wire.MsgTx) (*Packet, error) {
+	for _, in := range tx.TxIn {
+		if len(in.SignatureScript) > 0 || len(in.Witness) > 0 {
+			return nil, ErrSignedTx
+		}
+	}
+	return &Packet{
+		UnsignedTx: tx,
+		Inputs:     make([]Input, len(tx.TxIn)),
+		Outputs:    make([]Output, len(tx.TxOut)),
+	}, nil
+}
+
+// Decode parses a base64 encoded packet.
+func Decode(s string) (*Packet, error) {
+	b, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(bytes.NewReader(b))
+}
+
+// Encode returns the base64 encoding of the packet.
+func (p *Packet) Encode() (string, error) {
+	var buf bytes.Buffer
+	if err := p.Serialize(&buf); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+// Parse reads a binary packet.
+func Parse(r io.Reader) (*Packet, error) {
+	var m [5]byte
+	if _, err := io.ReadFull(r, m[:]); err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(m[:], magic) {
+		return nil, ErrInvalidMagic
+	}
+	p := &Packet{}
+	seen := make(map[string]bool)
+	for {
+		key, value, err := readPair(r)
+		if err != nil {
+			return nil, err
+		}
+		if key == nil {
+			break
+		}
+		if seen[string(key)] {
+			return nil, ErrDuplicateKey
+		}
+		seen[string(key)] = true
+		switch key[0] {
+		case globalUnsignedTx:
+			if len(key) != 1 {
+				return nil, ErrInvalidKey
+			}
+			tx := new(wire.MsgTx)
+			if err := tx.DeserializeNoWitness(bytes.NewReader(value)); err != nil {
+				return nil, err
+			}
+			p.UnsignedTx = tx
+		case globalVersion:
+			if len(key) != 1 || len(value) != 4 {
+				return nil, ErrInvalidValue
+			}
+			if binary.LittleEndian.Uint32(value) != 0 {
+				return nil, ErrUnsupported
+			}
+		default:
+			p.Unknowns = append(p.Unknowns, Unknown{Key: key, Value: value})
+		}
+	}
+	if p.UnsignedTx == nil {
+		return nil, ErrMissingTx
+	}
+	unsigned, err := New(p.UnsignedTx)
+	if err != nil {
+		return nil, err
+	}
+	p.Inputs, p.Outputs = unsigned.Inputs, unsigned.Outputs
+	for i := range p.Inputs {
+		if err := p.Inputs[i].parse(r); err != nil {
+			return nil, err
+		}
+	}
+	for i := range p.Outputs {
+		if err := p.Outputs[i].parse(r); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+func (in *Input) parse(r io.Reader) error {
+	seen := make(map[string]bool)
+	for {
+		key, value, err := readPair(r)
+		if err != nil {
+			return err
+		}
+		if key == nil {
+			return nil
+		}
+		if seen[string(key)] {
+			return ErrDuplicateKey
+		}
+		seen[string(key)] = true
+		switch key[0] {
+		case inputNonWitnessUtxo:
+			if len(key) != 1 {
+				return ErrInvalidKey
+			}
+			tx := new(wire.MsgTx)
+			if err := tx.Deserialize(bytes.NewReader(value)); err != nil {
+				return err
+			}
+			in.NonWitnessUtxo = tx
+		case inputWitnessUtxo:
+			if len(key) != 1 {
+				return ErrInvalidKey
+			}
+			out, err := readTxOut(value)
+			if err != nil {
+				return err
+			}
+			in.WitnessUtxo = out
+		case inputPartialSig:
+			if !validPubKey(key[1:]) {
+				return ErrInvalidPubKey
+			}
+			in.PartialSigs = append(in.PartialSigs, PartialSig{PubKey: key[1:], Signature: value})
+		case inputSighashType:
+			if len(key) != 1 || len(value) != 4 {
+				return ErrInvalidValue
+			}
+			in.SighashType = binary.LittleEndian.Uint32(value)
+		case inputRedeemScript:
+			if len(key) != 1 {
+				return ErrInvalidKey
+			}
+			in.RedeemScript = value
+		case inputWitnessScript:
+			if len(key) != 1 {
+				return ErrInvalidKey
+			}
+			in.WitnessScript = value
+		case inputBip32Derivation:
+			derivation, err := readDerivation(key[1:], value)
+			if err != nil {
+				return err
+			}
+			in.Bip32Derivation = append(in.Bip32Derivation, derivation)
+		case inputFinalScriptSig:
+			if len(key) != 1 {
+				return ErrInvalidKey
+			}
+			in.FinalScriptSig = value
+		case inputFinalScriptWitness:
+			if len(key) != 1 {
+				return ErrInvalidKey
+			}
+			witness, err := readWitness(value)
+			if err != nil {
+				return err
+			}
+			in.FinalScriptWitness = witness
+		default:
+			in.Unknowns = append(in.Unknowns, Unknown{Key: key, Value: value})
+		}
+	}
+}
+
+func (out *Output) parse(r io.Reader) error {
+	seen := make(map[string]bool)
+	for {
+		key, value, err := readPair(r)
+		if err != nil {
+			return err
+		}
+		if key == nil {
+			return nil
+		}
+		if seen[string(key)] {
+			return ErrDuplicateKey
+		}
+		seen[string(key)] = true
+		switch key[0] {
+		case outputRedeemScript:
+			if len(key) != 1 {
+				return ErrInvalidKey
+			}
+			out.RedeemScript = value
+		case outputWitnessScript:
+			if len(key) != 1 {
+				return ErrInvalidKey
+			}
+			out.WitnessScript = value
+		case outputBip32Derivation:
+			derivation, err := readDerivation(key[1:], value)
+			if err != nil {
+				return err
+			}
+			out.Bip32Derivation = append(out.Bip32Derivation, derivation)
+		default:
+			out.Unknowns = append(out.Unknowns, Unknown{Key: key, Value: value})
+		}
+	}
+}
+
+// Serialize writes the binary packet.
+func (p *Packet) Serialize(w io.Writer) error {
+	if p.UnsignedTx == nil {
+		return ErrMissingTx
+	}
+	if len(p.Inputs) != len(p.UnsignedTx.TxIn) || len(p.Outputs) != len(p.UnsignedTx.TxOut) {
+		return ErrInvalidValue
+	}
+	var buf bytes.Buffer
+	buf.Write(magic)
+	var tx bytes.Buffer
+	if err := p.UnsignedTx.SerializeNoWitness(&tx); err != nil {
+		return err
+	}
+	writePair(&buf, []byte{globalUnsignedTx}, tx.Bytes())
+	writeUnknowns(&buf, p.Unknowns)
+	buf.WriteByte(0)
+	for _, in := range p.Inputs {
+		if err := in.serialize(&buf); err != nil {
+			return err
+		}
+	}
+	for _, out := range p.Outputs {
+		out.serialize(&buf)
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+func (in *Input) serialize(buf *bytes.Buffer) error {
+	if in.NonWitnessUtxo != nil {
+		var tx bytes.Buffer
+		if err := in.NonWitnessUtxo.Serialize(&tx); err != nil {
+			return err
+		}
+		writePair(buf, []byte{inputNonWitnessUtxo}, tx.Bytes())
+	}
+	if in.WitnessUtxo != nil {
+		writePair(buf, []byte{inputWitnessUtxo}, txOutBytes(in.WitnessUtxo))
+	}
+	if !in.finalized() {
+		sigs := append([]PartialSig(nil), in.PartialSigs...)
+		sort.Slice(sigs, func(i, j int) bool { return bytes.Compare(sigs[i].PubKey, sigs[j].PubKey) < 0 })
+		for _, sig := range sigs {
+			writePair(buf, append([]byte{inputPartialSig}, sig.PubKey...), sig.Signature)
+		}
+		if in.SighashType != 0 {
+			var value [4]byte
+			binary.LittleEndian.PutUint32(value[:], in.SighashType)
+			writePair(buf, []byte{inputSighashType}, value[:])
+		}
+		if in.RedeemScript != nil {
+			writePair(buf, []byte{inputRedeemScript}, in.RedeemScript)
+		}
+		if in.WitnessScript != nil {
+			writePair(buf, []byte{inputWitnessScript}, in.WitnessScript)
+		}
+		writeDerivations(buf, inputBip32Derivation, in.Bip32Derivation)
+	}
+	if len(in.FinalScriptSig) > 0 {
+		writePair(buf, []byte{inputFinalScriptSig}, in.FinalScriptSig)
+	}
+	if len(in.FinalScriptWitness) > 0 {
+		writePair(buf, []byte{inputFinalScriptWitness}, witnessBytes(in.FinalScriptWitness))
+	}
+	writeUnknowns(buf, in.Unknowns)
+	buf.WriteByte(0)
+	return nil
+}
+
+func (out *Output) serialize(buf *bytes.Buffer) {
+	if out.RedeemScript != nil {
+		writePair(buf, []byte{outputRedeemScript}, out.RedeemScript)
+	}
+	if out.WitnessScript != nil {
+		writePair(buf, []byte{outputWitnessScript}, out.WitnessScript)
+	}
+	writeDerivations(buf, outputBip32Derivation, out.Bip32Derivation)
+	writeUnknowns(buf, out.Unknowns)
+	buf.WriteByte(0)
+}
+
+// SpentOutput returns the output spent by the input i, checking the previous transaction
+// against the outpoint when it is available.
+func (p *Packet) SpentOutput(i int) (*wire.TxOut, error) {
+	if i < 0 || i >= len(p.Inputs) {
+		return nil, ErrIndexRange
+	}
+	in := p.Inputs[i]
+	prevOut := p.UnsignedTx.TxIn[i].PreviousOutPoint
+	if in.NonWitnessUtxo != nil {
+		if in.NonWitnessUtxo.TxHash() != prevOut.Hash || int(prevOut.Index) >= len(in.NonWitnessUtxo.TxOut) {
+			return nil, ErrUtxoMismatch
+		}
+		return in.NonWitnessUtxo.TxOut[prevOut.Index], nil
+	}
+	if in.WitnessUtxo != nil {
+		return in.WitnessUtxo, nil
+	}
+	return nil, ErrMissingUtxo
+}
+
+// Fee returns the fee paid by the transaction, in satoshis.
+func (p *Packet) Fee() (int64, error) {
+	var fee int64
+	for i := range p.Inputs {
+		out, err := p.SpentOutput(i)
+		if err != nil {
+			return 0, err
+		}
+		fee += out.Value
+	}
+	for _, out := range p.UnsignedTx.TxOut {
+		fee -= out.Value
+	}
+	if fee < 0 {
+		return 0, ErrNegativeFee
+	}
+	return fee, nil
+}
+
+// AddPartialSig stores the signature of pubKey for the input i, replacing a previous one.
+func (p *Packet) AddPartialSig(i int, pubKey []byte, signature []byte) error {
+	if i < 0 || i >= len(p.Inputs) {
+		return ErrIndexRange
+	}
+	if !validPubKey(pubKey) {
+		return ErrInvalidPubKey
+	}
+	in := &p.Inputs[i]
+	for j := range in.PartialSigs {
+		if bytes.Equal(in.PartialSigs[j].PubKey, pubKey) {
+			in.PartialSigs[j].Signature = signature
+			return nil
+		}
+	}
+	in.PartialSigs = append(in.PartialSigs, PartialSig{PubKey: pubKey, Signature: signature})
+	return nil
+}
+
+// Finalized reports whether the input i has its final scripts.
+func (p *Packet) Finalized(i int) bool {
+	return p.Inputs[i].finalized()
+}
+
+func (in *Input) finalized() bool {
+	return len(in.FinalScriptSig) > 0 || len(in.FinalScriptWitness) > 0
+}
+
+// SetFinal sets the final scripts of the input i and drops the fields only needed to sign.
+func (p *Packet) SetFinal(i int, scriptSig []byte, witness [][]byte) error {
+	if i < 0 || i >= len(p.Inputs) {
+		return ErrIndexRange
+	}
+	in := &p.Inputs[i]
+	in.FinalScriptSig = scriptSig
+	in.FinalScriptWitness = witness
+	in.PartialSigs = nil
+	in.SighashType = 0
+	in.RedeemScript = nil
+	in.WitnessScript = nil
+	in.Bip32Derivation = nil
+	return nil
+}
+
+// Complete reports whether every input is finalized.
+func (p *Packet) Complete() bool {
+	for i := range p.Inputs {
+		if !p.Finalized(i) {
+			return false
+		}
+	}
+	return true
+}
+
+// Extract returns the network transaction of a complete packet.
+func (p *Packet) Extract() (*wire.MsgTx, error) {
+	if !p.Complete() {
+		return nil, ErrNotFinalized
+	}
+	tx := p.UnsignedTx.Copy()
+	for i, in := range p.Inputs {
+		tx.TxIn[i].SignatureScript = in.FinalScriptSig
+		tx.TxIn[i].Witness = in.FinalScriptWitness
+	}
+	return tx, nil
+}
+
+func readPair(r io.Reader) ([]byte, []byte, error) {
+	key, err := readBytes(r)
+	if err != nil || len(key) == 0 {
+		return nil, nil, err
+	}
+	value, err := readBytes(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	return key, value, nil
+}
+
+func readBytes(r io.Reader) ([]byte, error) {
+	n, err := wire.ReadVarInt(r, 0)
+	if err != nil {
+		return nil, err
+	}
+	if n > maxPairSize {
+		return nil, ErrInvalidValue
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+func writePair(buf *bytes.Buffer, key []byte, value []byte) {
+	_ = wire.WriteVarBytes(buf, 0, key)
+	_ = wire.WriteVarBytes(buf, 0, value)
+}
+
+func writeUnknowns(buf *bytes.Buffer, unknowns []Unknown) {
+	for _, unknown := range unknowns {
+		writePair(buf, unknown.Key, unknown.Value)
+	}
+}
+
+func readTxOut(value []byte) (*wire.TxOut, error) {
+	if len(value) < 9 {
+		return nil, ErrInvalidValue
+	}
+	r := bytes.NewReader(value[8:])
+	script, err := readBytes(r)
+	if err != nil {
+		return nil, err
+	}
+	if r.Len() != 0 {
+		return nil, ErrInvalidValue
+	}
+	return wire.NewTxOut(int64(binary.LittleEndian.Uint64(value[:8])), script), nil
+}
+
+func txOutBytes(out *wire.TxOut) []byte {
+	var buf bytes.Buffer
+	var value [8]byte
+	binary.LittleEndian.PutUint64(value[:], uint64(out.Value))
+	buf.Write(value[:])
+	_ = wire.WriteVarBytes(&buf, 0, out.PkScript)
+	return buf.Bytes()
+}
+
+func readWitness(value []byte) ([][]byte, error) {
+	r := bytes.NewReader(value)
+	n, err := wire.ReadVarInt(r, 0)
+	if err != nil {
+		return nil, err
+	}
+	if n > uint64(len(value)) {
+		return nil, ErrInvalidValue
+	}
+	witness := make([][]byte, n)
+	for i := range witness {
+		if witness[i], err = readBytes(r); err != nil {
+			return nil, err
+		}
+	}
+	if r.Len() != 0 {
+		return nil, ErrInvalidValue
+	}
+	return witness, nil
+}
+
+func witnessBytes(witness [][]byte) []byte {
+	var buf bytes.Buffer
+	_ = wire.WriteVarInt(&buf, 0, uint64(len(witness)))
+	for _, item := range witness {
+		_ = wire.WriteVarBytes(&buf, 0, item)
+	}
+	return buf.Bytes()
+}
+
+func readDerivation(pubKey []byte, value []byte) (Bip32Derivation, error) {
+	if !validPubKey(pubKey) {
+		return Bip32Derivation{}, ErrInvalidPubKey
+	}
+	if len(value) < 4 || len(value)%4 != 0 {
+		return Bip32Derivation{}, ErrInvalidValue
+	}
+	derivation := Bip32Derivation{
+		PubKey:      pubKey,
+		Fingerprint: binary.LittleEndian.Uint32(value[:4]),
+	}
+	for i := 4; i < len(value); i += 4 {
+		derivation.Path = append(derivation.Path, binary.LittleEndian.Uint32(value[i:i+4]))
+	}
+	return derivation, nil
+}
+
+func writeDerivations(buf *bytes.Buffer, keyType byte, derivations []Bip32Derivation) {
+	sorted := append([]Bip32Derivation(nil), derivations...)
+	sort.Slice(sorted, func(i, j int) bool { return bytes.Compare(sorted[i].PubKey, sorted[j].PubKey) < 0 })
+	for _, derivation := range sorted {
+		value := make([]byte, 4+4*len(derivation.Path))
+		binary.LittleEndian.PutUint32(value[:4], derivation.Fingerprint)
+		for i, index := range derivation.Path {
+			binary.LittleEndian.PutUint32(value[4+4*i:], index)
+		}
+		writePair(buf, append([]byte{keyType}, derivation.PubKey...), value)
+	}
+}
+
+func validPubKey(pubKey []byte) bool {
+	switch len(pubKey) {
+	case 33:
+		return pubKey[0] == 0x02 || pubKey[0] == 0x03
+	case 65:
+		return pubKey[0] == 0x04
+	}
+	return false
+}
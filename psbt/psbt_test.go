@@ -0,0 +1,167 @@
+package psbt
+
+import (
+	"bytes"
+	"encoding/base64"
+	"testing"
+
+	"github.com/martinboehm/btcd/chaincfg/chainhash"
+	"github.com/martinboehm/btcd/wire"
+)
+
+var testPubKey = append([]byte{0x02}, bytes.Repeat([]byte{0x11}, 32)...)
+
+var testScript = []byte{0x76, 0xa9, 0x14, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f, 0x10, 0x11, 0x12, 0x13, 0x14, 0x88, 0xac}
+
+func testPacket(t *testing.T) (*Packet, *wire.MsgTx) {
+	prevHash, _ := chainhash.NewHashFromStr("e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855")
+	prevTx := wire.NewMsgTx(1)
+	prevTx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(prevHash, 0), []byte{0x51}, nil))
+	prevTx.AddTxOut(wire.NewTxOut(50000, testScript))
+	prevTx.AddTxOut(wire.NewTxOut(70000, testScript))
+	prevTxHash := prevTx.TxHash()
+
+	tx := wire.NewMsgTx(2)
+	tx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&prevTxHash, 1), nil, nil))
+	tx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(prevHash, 3), nil, nil))
+	tx.AddTxOut(wire.NewTxOut(100000, testScript))
+	p, err := New(tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Inputs[0].NonWitnessUtxo = prevTx
+	p.Inputs[0].SighashType = 1
+	p.Inputs[0].Bip32Derivation = []Bip32Derivation{{PubKey: testPubKey, Fingerprint: 0xdeadbeef, Path: []uint32{0x8000002c, 0x80000000, 0x80000000, 0, 7}}}
+	p.Inputs[1].WitnessUtxo = wire.NewTxOut(40000, testScript)
+	p.Inputs[1].RedeemScript = []byte{0x51}
+	p.Inputs[1].Unknowns = []Unknown{{Key: []byte{0xfc, 0x01}, Value: []byte{0x02}}}
+	p.Outputs[0].Bip32Derivation = []Bip32Derivation{{PubKey: testPubKey, Fingerprint: 1, Path: []uint32{1, 2}}}
+	return p, prevTx
+}
+
+func TestRoundTrip(t *testing.T) {
+	p, _ := testPacket(t)
+	if err := p.AddPartialSig(0, testPubKey, []byte{0x30, 0x01}); err != nil {
+		t.Fatal(err)
+	}
+	encoded, err := p.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := Decode(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reencoded, err := decoded.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if encoded != reencoded {
+		t.Error("the packet changed after a round trip")
+	}
+	in := decoded.Inputs[0]
+	if in.NonWitnessUtxo == nil || in.SighashType != 1 || len(in.PartialSigs) != 1 || len(in.Bip32Derivation) != 1 {
+		t.Error("the fields of the first input were not decoded")
+	}
+	if in.Bip32Derivation[0].Fingerprint != 0xdeadbeef || len(in.Bip32Derivation[0].Path) != 5 || in.Bip32Derivation[0].Path[4] != 7 {
+		t.Error("the derivation path was not decoded")
+	}
+	if decoded.Inputs[1].WitnessUtxo.Value != 40000 || !bytes.Equal(decoded.Inputs[1].WitnessUtxo.PkScript, testScript) {
+		t.Error("the witness utxo was not decoded")
+	}
+	if len(decoded.Inputs[1].Unknowns) != 1 || len(decoded.Outputs[0].Bip32Derivation) != 1 {
+		t.Error("the unknown pairs and the output derivations must be kept")
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	p, _ := testPacket(t)
+	var valid bytes.Buffer
+	if err := p.Serialize(&valid); err != nil {
+		t.Fatal(err)
+	}
+	var tx bytes.Buffer
+	_ = p.UnsignedTx.SerializeNoWitness(&tx)
+	var duplicate bytes.Buffer
+	duplicate.Write(magic)
+	writePair(&duplicate, []byte{globalUnsignedTx}, tx.Bytes())
+	writePair(&duplicate, []byte{globalUnsignedTx}, tx.Bytes())
+	duplicate.WriteByte(0)
+
+	signed := p.UnsignedTx.Copy()
+	signed.TxIn[0].SignatureScript = []byte{0x51}
+	var signedTx bytes.Buffer
+	_ = signed.SerializeNoWitness(&signedTx)
+	var signedPacket bytes.Buffer
+	signedPacket.Write(magic)
+	writePair(&signedPacket, []byte{globalUnsignedTx}, signedTx.Bytes())
+	signedPacket.WriteByte(0)
+
+	var invalidPubKey bytes.Buffer
+	invalidPubKey.Write(magic)
+	writePair(&invalidPubKey, []byte{globalUnsignedTx}, tx.Bytes())
+	invalidPubKey.WriteByte(0)
+	writePair(&invalidPubKey, []byte{inputPartialSig, 0x02, 0x01}, []byte{0x30})
+
+	tests := []struct {
+		name string
+		data []byte
+		err  error
+	}{
+		{"raw transaction", tx.Bytes(), ErrInvalidMagic},
+		{"missing transaction", append(append([]byte(nil), magic...), 0), ErrMissingTx},
+		{"duplicate key", duplicate.Bytes(), ErrDuplicateKey},
+		{"signed transaction", signedPacket.Bytes(), ErrSignedTx},
+		{"invalid public key", invalidPubKey.Bytes(), ErrInvalidPubKey},
+		{"truncated", valid.Bytes()[:valid.Len()-3], nil},
+	}
+	for _, test := range tests {
+		_, err := Decode(base64.StdEncoding.EncodeToString(test.data))
+		if err == nil || test.err != nil && err != test.err {
+			t.Error("unexpected error for " + test.name)
+		}
+	}
+}
+
+func TestFeeAndExtract(t *testing.T) {
+	p, prevTx := testPacket(t)
+	fee, err := p.Fee()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fee != 10000 {
+		t.Error("the fee must be the inputs minus the outputs")
+	}
+	if _, err := p.Extract(); err != ErrNotFinalized {
+		t.Error("a packet without final scripts can't be extracted")
+	}
+	if err := p.SetFinal(0, []byte{0x01, 0x02}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.SetFinal(1, nil, [][]byte{{0x03}}); err != nil {
+		t.Fatal(err)
+	}
+	if p.Inputs[0].Bip32Derivation != nil || p.Inputs[0].SighashType != 0 {
+		t.Error("the signing fields must be dropped once finalized")
+	}
+	tx, err := p.Extract()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(tx.TxIn[0].SignatureScript, []byte{0x01, 0x02}) || len(tx.TxIn[1].Witness) != 1 {
+		t.Error("the final scripts were not set on the transaction")
+	}
+	if len(p.UnsignedTx.TxIn[0].SignatureScript) != 0 {
+		t.Error("the unsigned transaction must not be modified")
+	}
+
+	prevTx.TxOut[1].Value = 1
+	p.Inputs[0].NonWitnessUtxo = prevTx
+	if _, err := p.SpentOutput(0); err != ErrUtxoMismatch {
+		t.Error("a previous transaction with another hash must be rejected")
+	}
+	p.Inputs[1].WitnessUtxo = nil
+	if _, err := p.SpentOutput(1); err != ErrMissingUtxo {
+		t.Error("expected an error without the spent output")
+	}
+}
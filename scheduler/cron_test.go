@@ -0,0 +1,59 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+)
+
+type testCron struct {
+	spec string
+	from string
+	next string
+}
+
+var testCrons = []testCron{
+	{spec: "0 * * * *", from: "2020-06-10 10:00", next: "2020-06-10 11:00"},
+	{spec: "0 * * * *", from: "2020-06-10 10:59", next: "2020-06-10 11:00"},
+	{spec: "*/15 * * * *", from: "2020-06-10 10:16", next: "2020-06-10 10:30"},
+	{spec: "30 */3 * * *", from: "2020-06-10 10:16", next: "2020-06-10 12:30"},
+	{spec: "0 0 * * *", from: "2020-12-31 23:00", next: "2021-01-01 00:00"},
+	{spec: "0 9-17/4 * * 1-5", from: "2020-06-12 17:00", next: "2020-06-15 09:00"},
+	{spec: "0 0 29 2 *", from: "2020-03-01 00:00", next: "2024-02-29 00:00"},
+	{spec: "0 0 1,15 * *", from: "2020-06-02 00:00", next: "2020-06-15 00:00"},
+	{spec: "0 0 * * 7", from: "2020-06-12 00:00", next: "2020-06-14 00:00"},
+	{spec: "0 0 * * 6-7", from: "2020-06-13 01:00", next: "2020-06-14 00:00"},
+}
+
+func TestCronNext(t *testing.T) {
+	for _, test := range testCrons {
+		schedule, err := ParseCron(test.spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		from, _ := time.Parse("2006-01-02 15:04", test.from)
+		expected, _ := time.Parse("2006-01-02 15:04", test.next)
+		next := schedule.Next(from)
+		if !next.Equal(expected) {
+			t.Error("wrong activation for " + test.spec + " expected: " + expected.String() + " got: " + next.String())
+		}
+	}
+}
+
+func TestCronInvalid(t *testing.T) {
+	for _, spec := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "*/0 * * * *", "5-1 * * * *", "a * * * *", "* * * * 8"} {
+		_, err := ParseCron(spec)
+		if err == nil {
+			t.Error("expected error for cron expression " + spec)
+		}
+	}
+}
+
+func TestCronNeverMatches(t *testing.T) {
+	schedule, err := ParseCron("0 0 31 2 *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !schedule.Next(time.Now()).IsZero() {
+		t.Error("expected zero time for an impossible schedule")
+	}
+}
@@ -0,0 +1,295 @@
+package scheduler
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/grupokindynos/plutus/logger"
+)
+
+var (
+	ErrJobNotFound = errors.New("job not found")
+	ErrJobRunning  = errors.New("job is already running")
+	ErrNoRuns      = errors.New("job has not run yet")
+	ErrNotLeader   = errors.New("this instance is not the leader, jobs run on the leader only")
+)
+
+// Func is the work performed by a job, the result is kept as part of the last run.
+type Func func() (interface{}, error)
+
+// Run describes a single execution of a job.
+type Run struct {
+	StartedAt  time.Time   `json:"started_at"`
+	FinishedAt time.Time   `json:"finished_at"`
+	Manual     bool        `json:"manual"`
+	Result     interface{} `json:"result,omitempty"`
+	Error      string      `json:"error,omitempty"`
+}
+
+// Status is the public view of a registered job.
+type Status struct {
+	Name     string    `json:"name"`
+	Schedule string    `json:"schedule"`
+	Paused   bool      `json:"paused"`
+	Running  bool      `json:"running"`
+	Leader   bool      `json:"leader"`
+	NextRun  time.Time `json:"next_run"`
+	LastRun  *Run      `json:"last_run,omitempty"`
+}
+
+type job struct {
+	name     string
+	spec     string
+	schedule *Schedule
+	run      Func
+	trigger  chan struct{}
+
+	mu      sync.Mutex
+	paused  bool
+	running bool
+	nextRun time.Time
+	lastRun *Run
+}
+
+// Scheduler runs registered jobs at the times described by their cron expressions.
+// A job never overlaps with itself, if a run takes longer than its period the
+// missed activations are skipped.
+type Scheduler struct {
+	mu      sync.Mutex
+	jobs    map[string]*job
+	stop    chan struct{}
+	wg      sync.WaitGroup
+	started bool
+	leader  func() bool
+}
+
+func NewScheduler() *Scheduler {
+	return &Scheduler{
+		jobs: make(map[string]*job),
+		stop: make(chan struct{}),
+	}
+}
+
+// Register adds a job to the scheduler. Jobs must be registered before Start is called.
+func (s *Scheduler) Register(name string, spec string, run Func) error {
+	schedule, err := ParseCron(spec)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.started {
+		return errors.New("scheduler already started")
+	}
+	if _, ok := s.jobs[name]; ok {
+		return errors.New("job already registered: " + name)
+	}
+	s.jobs[name] = &job{
+		name:     name,
+		spec:     spec,
+		schedule: schedule,
+		run:      run,
+		trigger:  make(chan struct{}, 1),
+	}
+	return nil
+}
+
+// SetLeader restricts the runs to the instances for which isLeader returns true.
+// Without it every instance runs the jobs.
+func (s *Scheduler) SetLeader(isLeader func() bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.leader = isLeader
+}
+
+// Start launches one goroutine per registered job.
+func (s *Scheduler) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.started {
+		return
+	}
+	s.started = true
+	for _, j := range s.jobs {
+		s.wg.Add(1)
+		go s.loop(j)
+	}
+}
+
+// Stop prevents new runs and waits for the running ones to finish.
+func (s *Scheduler) Stop() {
+	close(s.stop)
+	s.wg.Wait()
+}
+
+// Jobs returns the status of every registered job sorted by name.
+func (s *Scheduler) Jobs() []Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	statuses := make([]Status, 0, len(s.jobs))
+	for _, j := range s.jobs {
+		statuses = append(statuses, j.status(s.isLeaderLocked()))
+	}
+	sort.Slice(statuses, func(i, k int) bool {
+		return statuses[i].Name < statuses[k].Name
+	})
+	return statuses
+}
+
+// Job returns the status of a single job.
+func (s *Scheduler) Job(name string) (Status, error) {
+	j, err := s.get(name)
+	if err != nil {
+		return Status{}, err
+	}
+	return j.status(s.isLeader()), nil
+}
+
+// LastRun returns the outcome of the latest execution of a job.
+func (s *Scheduler) LastRun(name string) (Run, error) {
+	j, err := s.get(name)
+	if err != nil {
+		return Run{}, err
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.lastRun == nil {
+		return Run{}, ErrNoRuns
+	}
+	return *j.lastRun, nil
+}
+
+// Pause skips the scheduled activations of a job until it is resumed.
+// Manual runs are still allowed.
+func (s *Scheduler) Pause(name string) error {
+	return s.setPaused(name, true)
+}
+
+// Resume re-enables the scheduled activations of a paused job.
+func (s *Scheduler) Resume(name string) error {
+	return s.setPaused(name, false)
+}
+
+// Trigger requests an immediate run of a job. The run happens in the background.
+func (s *Scheduler) Trigger(name string) error {
+	j, err := s.get(name)
+	if err != nil {
+		return err
+	}
+	if !s.isLeader() {
+		return ErrNotLeader
+	}
+	j.mu.Lock()
+	running := j.running
+	j.mu.Unlock()
+	if running {
+		return ErrJobRunning
+	}
+	select {
+	case j.trigger <- struct{}{}:
+		return nil
+	default:
+		return ErrJobRunning
+	}
+}
+
+func (s *Scheduler) setPaused(name string, paused bool) error {
+	j, err := s.get(name)
+	if err != nil {
+		return err
+	}
+	j.mu.Lock()
+	j.paused = paused
+	j.mu.Unlock()
+	return nil
+}
+
+func (s *Scheduler) isLeader() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.isLeaderLocked()
+}
+
+func (s *Scheduler) isLeaderLocked() bool {
+	return s.leader == nil || s.leader()
+}
+
+func (s *Scheduler) get(name string) (*job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j, ok := s.jobs[name]
+	if !ok {
+		return nil, ErrJobNotFound
+	}
+	return j, nil
+}
+
+func (s *Scheduler) loop(j *job) {
+	defer s.wg.Done()
+	for {
+		next := j.schedule.Next(time.Now())
+		if next.IsZero() {
+			logger.Warn("scheduler: the job will never run", "job", j.name, "schedule", j.spec)
+		}
+		j.mu.Lock()
+		j.nextRun = next
+		j.mu.Unlock()
+		var fire <-chan time.Time
+		timer := time.NewTimer(time.Until(next))
+		if !next.IsZero() {
+			fire = timer.C
+		}
+		select {
+		case <-s.stop:
+			timer.Stop()
+			return
+		case <-j.trigger:
+			timer.Stop()
+			j.execute(true)
+		case <-fire:
+			j.mu.Lock()
+			paused := j.paused
+			j.mu.Unlock()
+			if !paused && s.isLeader() {
+				j.execute(false)
+			}
+		}
+	}
+}
+
+func (j *job) execute(manual bool) {
+	j.mu.Lock()
+	j.running = true
+	j.mu.Unlock()
+	run := &Run{
+		StartedAt: time.Now(),
+		Manual:    manual,
+	}
+	result, err := j.run()
+	run.FinishedAt = time.Now()
+	run.Result = result
+	if err != nil {
+		run.Error = err.Error()
+		logger.Error("scheduler: job failed", "job", j.name, "err", err)
+	}
+	j.mu.Lock()
+	j.running = false
+	j.lastRun = run
+	j.mu.Unlock()
+}
+
+func (j *job) status(leader bool) Status {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return Status{
+		Name:     j.name,
+		Schedule: j.spec,
+		Paused:   j.paused,
+		Running:  j.running,
+		Leader:   leader,
+		NextRun:  j.nextRun,
+		LastRun:  j.lastRun,
+	}
+}
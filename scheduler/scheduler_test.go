@@ -0,0 +1,96 @@
+package scheduler
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func waitForRun(s *Scheduler, name string) (Run, error) {
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		run, err := s.LastRun(name)
+		if err != ErrNoRuns {
+			return run, err
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return Run{}, errors.New("timeout waiting for job")
+}
+
+func TestTriggerAndLastRun(t *testing.T) {
+	s := NewScheduler()
+	err := s.Register("failing", "0 0 1 1 *", func() (interface{}, error) {
+		return "partial", errors.New("boom")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Start()
+	defer s.Stop()
+	if _, err := s.LastRun("failing"); err != ErrNoRuns {
+		t.Error("expected no runs before triggering the job")
+	}
+	if err := s.Trigger("failing"); err != nil {
+		t.Fatal(err)
+	}
+	run, err := waitForRun(s, "failing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !run.Manual || run.Error != "boom" || run.Result != "partial" {
+		t.Errorf("unexpected run %+v", run)
+	}
+	status, err := s.Job("failing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.NextRun.IsZero() || status.LastRun == nil {
+		t.Errorf("unexpected status %+v", status)
+	}
+}
+
+func TestPauseResume(t *testing.T) {
+	s := NewScheduler()
+	err := s.Register("sweep", "* * * * *", func() (interface{}, error) {
+		return nil, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Pause("sweep"); err != nil {
+		t.Fatal(err)
+	}
+	if status, _ := s.Job("sweep"); !status.Paused {
+		t.Error("job should be paused")
+	}
+	if err := s.Resume("sweep"); err != nil {
+		t.Fatal(err)
+	}
+	if status, _ := s.Job("sweep"); status.Paused {
+		t.Error("job should be resumed")
+	}
+	if err := s.Pause("unknown"); err != ErrJobNotFound {
+		t.Error("expected job not found error")
+	}
+	if err := s.Register("sweep", "* * * * *", nil); err == nil {
+		t.Error("expected duplicated job error")
+	}
+}
+
+func TestFollowerDoesNotRun(t *testing.T) {
+	s := NewScheduler()
+	err := s.Register("sweep", "* * * * *", func() (interface{}, error) {
+		return nil, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.SetLeader(func() bool { return false })
+	if err := s.Trigger("sweep"); err != ErrNotLeader {
+		t.Error("a follower must not run jobs on demand")
+	}
+	if status, _ := s.Job("sweep"); status.Leader {
+		t.Error("status should report the instance as follower")
+	}
+}
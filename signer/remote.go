@@ -0,0 +1,206 @@
+package signer
+
+import (
+	"errors"
+	"io"
+	"math/big"
+	"net"
+	"net/rpc"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+const serviceName = "Signer"
+
+// callTimeout bounds every call to the remote signer.
+const callTimeout = 10 * time.Second
+
+var ErrTimeout = errors.New("the remote signer did not answer in time")
+
+type SignDigestArgs struct {
+	Coin   string
+	Index  uint32
+	Digest []byte
+}
+
+type SignDigestReply struct {
+	Signature []byte
+	PublicKey []byte
+}
+
+type SignEthTxArgs struct {
+	Tag string
+	// Tx is the RLP encoding of the unsigned transaction.
+	Tx      []byte
+	ChainID []byte
+}
+
+type SignEthTxReply struct {
+	Tx []byte
+}
+
+// Service exposes a Signer through net/rpc.
+type Service struct {
+	signer Signer
+}
+
+func (s *Service) SignDigest(args *SignDigestArgs, reply *SignDigestReply) error {
+	signature, pubKey, err := s.signer.SignDigest(args.Coin, args.Index, args.Digest)
+	if err != nil {
+		return err
+	}
+	reply.Signature = signature
+	reply.PublicKey = pubKey
+	return nil
+}
+
+func (s *Service) SignEthTx(args *SignEthTxArgs, reply *SignEthTxReply) error {
+	var tx types.Transaction
+	if err := rlp.DecodeBytes(args.Tx, &tx); err != nil {
+		return err
+	}
+	signedTx, err := s.signer.SignEthTx(args.Tag, &tx, decodeChainID(args.ChainID))
+	if err != nil {
+		return err
+	}
+	reply.Tx, err = rlp.EncodeToBytes(signedTx)
+	return err
+}
+
+// Serve answers the signing requests received on listener until it is closed. The
+// listener is expected to be a unix socket only reachable by the Plutus user.
+func Serve(listener net.Listener, signer Signer) error {
+	server := rpc.NewServer()
+	if err := server.RegisterName(serviceName, &Service{signer: signer}); err != nil {
+		return err
+	}
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+		go server.ServeConn(conn)
+	}
+}
+
+// Remote is a Signer that forwards the requests to a signer process.
+type Remote struct {
+	network string
+	address string
+
+	mu     sync.Mutex
+	client *rpc.Client
+}
+
+// Dial connects to the signer process listening on address, e.g. ("unix", "/run/plutus/signer.sock").
+func Dial(network string, address string) (*Remote, error) {
+	r := &Remote{network: network, address: address}
+	if _, err := r.conn(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *Remote) SignDigest(coin string, index uint32, digest []byte) ([]byte, []byte, error) {
+	if len(digest) != 32 {
+		return nil, nil, ErrInvalidDigest
+	}
+	var reply SignDigestReply
+	err := r.call("SignDigest", &SignDigestArgs{Coin: coin, Index: index, Digest: digest}, &reply)
+	if err != nil {
+		return nil, nil, err
+	}
+	return reply.Signature, reply.PublicKey, nil
+}
+
+func (r *Remote) SignEthTx(tag string, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	rawTx, err := rlp.EncodeToBytes(tx)
+	if err != nil {
+		return nil, err
+	}
+	args := &SignEthTxArgs{Tag: tag, Tx: rawTx}
+	if chainID != nil {
+		args.ChainID = chainID.Bytes()
+	}
+	var reply SignEthTxReply
+	if err := r.call("SignEthTx", args, &reply); err != nil {
+		return nil, err
+	}
+	var signedTx types.Transaction
+	if err := rlp.DecodeBytes(reply.Tx, &signedTx); err != nil {
+		return nil, err
+	}
+	return &signedTx, nil
+}
+
+// Close closes the connection to the signer process.
+func (r *Remote) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.client == nil {
+		return nil
+	}
+	err := r.client.Close()
+	r.client = nil
+	return err
+}
+
+// call runs a request, reconnecting once if the signer process was restarted.
+func (r *Remote) call(method string, args interface{}, reply interface{}) error {
+	err := r.callOnce(method, args, reply)
+	if err == rpc.ErrShutdown || err == io.EOF || err == io.ErrUnexpectedEOF {
+		r.reset()
+		err = r.callOnce(method, args, reply)
+	}
+	return err
+}
+
+func (r *Remote) callOnce(method string, args interface{}, reply interface{}) error {
+	client, err := r.conn()
+	if err != nil {
+		return err
+	}
+	call := client.Go(serviceName+"."+method, args, reply, make(chan *rpc.Call, 1))
+	timer := time.NewTimer(callTimeout)
+	defer timer.Stop()
+	select {
+	case <-call.Done:
+		return call.Error
+	case <-timer.C:
+		r.reset()
+		return ErrTimeout
+	}
+}
+
+func (r *Remote) conn() (*rpc.Client, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.client != nil {
+		return r.client, nil
+	}
+	conn, err := net.DialTimeout(r.network, r.address, callTimeout)
+	if err != nil {
+		return nil, err
+	}
+	r.client = rpc.NewClient(conn)
+	return r.client, nil
+}
+
+func (r *Remote) reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.client != nil {
+		_ = r.client.Close()
+		r.client = nil
+	}
+}
+
+func decodeChainID(b []byte) *big.Int {
+	if len(b) == 0 {
+		return nil
+	}
+	return new(big.Int).SetBytes(b)
+}
@@ -0,0 +1,62 @@
+package signer
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/martinboehm/btcd/btcec"
+	hdwallet "github.com/miguelmota/go-ethereum-hdwallet"
+)
+
+var ErrInvalidDigest = errors.New("the digest must be 32 bytes long")
+
+// Signer holds the private keys. Plutus builds the transactions and only hands digests and
+// unsigned transactions to the signer, so the keys can live in a separate process.
+type Signer interface {
+	// SignDigest signs a transaction digest with the key at index of the receive chain of
+	// the BIP44 account of coin. It returns the DER signature and the compressed public key.
+	SignDigest(coin string, index uint32, digest []byte) (signature []byte, pubKey []byte, err error)
+	// SignEthTx signs an ethereum transaction with the account of the mnemonic of tag,
+	// ETH or ETHV2.
+	SignEthTx(tag string, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error)
+}
+
+// KeySource derives the keys used by a Local signer.
+type KeySource interface {
+	PrivateKey(coin string, index uint32) (*btcec.PrivateKey, error)
+	EthWallet(tag string) (*hdwallet.Wallet, accounts.Account, error)
+}
+
+// Local signs in process with the keys of a KeySource.
+type Local struct {
+	keys KeySource
+}
+
+func NewLocal(keys KeySource) *Local {
+	return &Local{keys: keys}
+}
+
+func (l *Local) SignDigest(coin string, index uint32, digest []byte) ([]byte, []byte, error) {
+	if len(digest) != 32 {
+		return nil, nil, ErrInvalidDigest
+	}
+	privKey, err := l.keys.PrivateKey(coin, index)
+	if err != nil {
+		return nil, nil, err
+	}
+	signature, err := privKey.Sign(digest)
+	if err != nil {
+		return nil, nil, err
+	}
+	return signature.Serialize(), privKey.PubKey().SerializeCompressed(), nil
+}
+
+func (l *Local) SignEthTx(tag string, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	wallet, account, err := l.keys.EthWallet(tag)
+	if err != nil {
+		return nil, err
+	}
+	return wallet.SignTx(account, tx, chainID)
+}
@@ -0,0 +1,77 @@
+package signer
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+type testSigner struct {
+	key *ecdsa.PrivateKey
+}
+
+func (s *testSigner) SignDigest(coin string, index uint32, digest []byte) ([]byte, []byte, error) {
+	return append([]byte(coin), digest...), []byte{byte(index)}, nil
+}
+
+func (s *testSigner) SignEthTx(tag string, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	return types.SignTx(tx, types.HomesteadSigner{}, s.key)
+}
+
+func TestRemote(t *testing.T) {
+	dir, err := ioutil.TempDir("", "signer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	socket := filepath.Join(dir, "signer.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	go Serve(listener, &testSigner{key: key})
+
+	remote, err := Dial("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer remote.Close()
+	digest := bytes.Repeat([]byte{1}, 32)
+	signature, pubKey, err := remote.SignDigest("BTC", 7, digest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(signature, append([]byte("BTC"), digest...)) || !bytes.Equal(pubKey, []byte{7}) {
+		t.Error("unexpected signature from the remote signer")
+	}
+	if _, _, err := remote.SignDigest("BTC", 7, []byte{1}); err != ErrInvalidDigest {
+		t.Error("expected an error with a short digest")
+	}
+
+	tx := types.NewTransaction(3, common.HexToAddress("0x931D387731bBbC988B312206c74F77D004D6B84b"), big.NewInt(1e18), 21000, big.NewInt(1e9), nil)
+	signedTx, err := remote.SignEthTx("ETH", tx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sender, err := types.Sender(types.HomesteadSigner{}, signedTx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sender != crypto.PubkeyToAddress(key.PublicKey) {
+		t.Error("the remote signer returned a transaction signed by another key")
+	}
+}
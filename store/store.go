@@ -0,0 +1,148 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+var ErrNotFound = errors.New("not found")
+
+// Store is a small key/value store organised in buckets and persisted as a single JSON
+// file. Every write rewrites the file atomically, it is meant for the low volume state
+// Plutus keeps (indexes, labels, journals), not for bulk data.
+type Store struct {
+	path string
+	mu   sync.Mutex
+	data map[string]map[string]json.RawMessage
+}
+
+// Open loads the store from path, creating it if needed. With an empty path the store
+// lives in memory only and its content is lost on restart.
+func Open(path string) (*Store, error) {
+	s := &Store{
+		path: path,
+		data: make(map[string]map[string]json.RawMessage),
+	}
+	if path == "" {
+		return s, nil
+	}
+	file, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(file) == 0 {
+		return s, nil
+	}
+	err = json.Unmarshal(file, &s.data)
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Get decodes the value stored under bucket/key into v.
+func (s *Store) Get(bucket string, key string, v interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	raw, ok := s.data[bucket][key]
+	if !ok {
+		return ErrNotFound
+	}
+	return json.Unmarshal(raw, v)
+}
+
+// Put stores v under bucket/key.
+func (s *Store) Put(bucket string, key string, v interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.put(bucket, key, v)
+}
+
+// Update decodes bucket/key into v (leaving it untouched if missing), calls fn and
+// stores v back. The whole operation is atomic with respect to other store calls.
+func (s *Store) Update(bucket string, key string, v interface{}, fn func(found bool) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	raw, found := s.data[bucket][key]
+	if found {
+		err := json.Unmarshal(raw, v)
+		if err != nil {
+			return err
+		}
+	}
+	err := fn(found)
+	if err != nil {
+		return err
+	}
+	return s.put(bucket, key, v)
+}
+
+// Delete removes bucket/key.
+func (s *Store) Delete(bucket string, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.data[bucket][key]; !ok {
+		return nil
+	}
+	delete(s.data[bucket], key)
+	return s.flush()
+}
+
+// Keys returns the sorted keys of a bucket.
+func (s *Store) Keys(bucket string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	keys := make([]string, 0, len(s.data[bucket]))
+	for key := range s.data[bucket] {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (s *Store) put(bucket string, key string, v interface{}) error {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if s.data[bucket] == nil {
+		s.data[bucket] = make(map[string]json.RawMessage)
+	}
+	s.data[bucket][key] = raw
+	return s.flush()
+}
+
+func (s *Store) flush() error {
+	if s.path == "" {
+		return nil
+	}
+	file, err := json.Marshal(s.data)
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(s.path), filepath.Base(s.path)+".tmp")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(file)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	closeErr := tmp.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
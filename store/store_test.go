@@ -0,0 +1,77 @@
+package store
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type testRecord struct {
+	Index int    `json:"index"`
+	Label string `json:"label"`
+}
+
+func TestPersistence(t *testing.T) {
+	dir, err := ioutil.TempDir("", "plutus-store")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "plutus.json")
+	s, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.Put("cold", "BTC", testRecord{Index: 3, Label: "vault"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	reopened, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var record testRecord
+	err = reopened.Get("cold", "BTC", &record)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record.Index != 3 || record.Label != "vault" {
+		t.Errorf("unexpected record %+v", record)
+	}
+	if err := reopened.Get("cold", "LTC", &record); err != ErrNotFound {
+		t.Error("expected not found error")
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	s, err := Open("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		var record testRecord
+		err = s.Update("cold", "BTC", &record, func(found bool) error {
+			if found != (i > 0) {
+				t.Error("unexpected found flag")
+			}
+			record.Index++
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	var record testRecord
+	_ = s.Get("cold", "BTC", &record)
+	if record.Index != 3 {
+		t.Errorf("expected index 3 got %d", record.Index)
+	}
+	if keys := s.Keys("cold"); len(keys) != 1 || keys[0] != "BTC" {
+		t.Error("unexpected keys")
+	}
+	_ = s.Delete("cold", "BTC")
+	if len(s.Keys("cold")) != 0 {
+		t.Error("key not deleted")
+	}
+}
@@ -0,0 +1,173 @@
+package sweep
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/eabz/btcutil/chaincfg"
+	"github.com/eabz/btcutil/hdkeychain"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/grupokindynos/common/blockbook"
+	coinfactory "github.com/grupokindynos/common/coin-factory"
+	"github.com/grupokindynos/common/coin-factory/coins"
+	"github.com/grupokindynos/plutus/store"
+)
+
+const coldBucket = "cold_index"
+
+// maxColdScan bounds the used ethereum cold addresses skipped by a single sweep.
+const maxColdScan = 100
+
+type coldIndex struct {
+	Next uint32 `json:"next"`
+}
+
+// ColdWallet derives the cold storage addresses from watch-only xpubs and remembers the
+// next unused index of every coin so a cold address never receives funds twice.
+type ColdWallet struct {
+	store *store.Store
+	// usedAddresses returns the amount of addresses of the xpub already used on chain.
+	usedAddresses func(coinConfig *coins.Coin, xpub string) (int, error)
+	// ethAddressUsed reports whether an ethereum address was already used on chain, the
+	// backend has no xpub index for them.
+	ethAddressUsed func(address string) (bool, error)
+}
+
+func NewColdWallet(store *store.Store) *ColdWallet {
+	return &ColdWallet{
+		store:          store,
+		usedAddresses:  usedAddresses,
+		ethAddressUsed: ethAddressUsed,
+	}
+}
+
+// NextAddress returns the first cold address that was neither used by a previous sweep
+// nor seen on chain, together with its index.
+func (w *ColdWallet) NextAddress(coinConfig *coins.Coin, xpub string) (string, uint32, error) {
+	var index coldIndex
+	err := w.store.Get(coldBucket, coldKey(coinConfig), &index)
+	if err != nil && err != store.ErrNotFound {
+		return "", 0, err
+	}
+	if isEthereum(coinConfig) {
+		return w.nextEthAddress(coinConfig, xpub, index.Next)
+	}
+	if w.usedAddresses != nil {
+		used, err := w.usedAddresses(coinConfig, xpub)
+		if err != nil {
+			return "", 0, err
+		}
+		if uint32(used) > index.Next {
+			index.Next = uint32(used)
+		}
+	}
+	address, err := deriveColdAddress(coinConfig, xpub, index.Next)
+	if err != nil {
+		return "", 0, err
+	}
+	return address, index.Next, nil
+}
+
+// nextEthAddress skips the addresses used on chain from index, so the cold addresses are not
+// reused when the store lost the index.
+func (w *ColdWallet) nextEthAddress(coinConfig *coins.Coin, xpub string, index uint32) (string, uint32, error) {
+	for i := 0; i < maxColdScan; i++ {
+		address, err := deriveColdAddress(coinConfig, xpub, index)
+		if err != nil {
+			return "", 0, err
+		}
+		if w.ethAddressUsed == nil {
+			return address, index, nil
+		}
+		used, err := w.ethAddressUsed(address)
+		if err != nil {
+			return "", 0, err
+		}
+		if !used {
+			return address, index, nil
+		}
+		index++
+	}
+	return "", 0, errors.New("more than " + strconv.Itoa(maxColdScan) + " used cold addresses after the stored index")
+}
+
+// MarkUsed records that the address at index received funds.
+func (w *ColdWallet) MarkUsed(coinConfig *coins.Coin, index uint32) error {
+	var stored coldIndex
+	return w.store.Update(coldBucket, coldKey(coinConfig), &stored, func(found bool) error {
+		if index+1 > stored.Next {
+			stored.Next = index + 1
+		}
+		return nil
+	})
+}
+
+// coldKey groups the ERC20 tokens with ETH since they share the same addresses.
+func coldKey(coinConfig *coins.Coin) string {
+	if isEthereum(coinConfig) {
+		return "ETH"
+	}
+	return coinConfig.Info.Tag
+}
+
+func isEthereum(coinConfig *coins.Coin) bool {
+	return coinConfig.Info.Token || coinConfig.Info.Tag == "ETH"
+}
+
+func deriveColdAddress(coinConfig *coins.Coin, xpub string, index uint32) (string, error) {
+	if !isEthereum(coinConfig) {
+		chaincfg.ResetParams()
+		_ = chaincfg.Register(coinConfig.NetParams)
+	}
+	acc, err := hdkeychain.NewKeyFromString(xpub)
+	if err != nil {
+		return "", err
+	}
+	if acc.IsPrivate() {
+		return "", errors.New("the cold wallet must be configured with a public key")
+	}
+	directExtended, err := acc.Child(0)
+	if err != nil {
+		return "", err
+	}
+	addrExtPub, err := directExtended.Child(index)
+	if err != nil {
+		return "", err
+	}
+	if isEthereum(coinConfig) {
+		pub, err := addrExtPub.ECPubKey()
+		if err != nil {
+			return "", err
+		}
+		return crypto.PubkeyToAddress(*pub.ToECDSA()).Hex(), nil
+	}
+	addr, err := addrExtPub.Address(coinConfig.NetParams)
+	if err != nil {
+		return "", err
+	}
+	return addr.String(), nil
+}
+
+func usedAddresses(coinConfig *coins.Coin, xpub string) (int, error) {
+	blockBookWrap := blockbook.NewBlockBookWrapper(coinConfig.Info.Blockbook)
+	info, err := blockBookWrap.GetXpub(xpub)
+	if err != nil {
+		return 0, err
+	}
+	return info.UsedTokens, nil
+}
+
+// ethAddressUsed reports whether the address sent, holds or is receiving ether or tokens. The
+// cold addresses only receive, a used one keeps a balance until the cold wallet spends it.
+func ethAddressUsed(address string) (bool, error) {
+	ethConfig, err := coinfactory.GetCoin("ETH")
+	if err != nil {
+		return false, err
+	}
+	blockBookWrap := blockbook.NewBlockBookWrapper(ethConfig.Info.Blockbook)
+	info, err := blockBookWrap.GetEthAddress(address)
+	if err != nil {
+		return false, err
+	}
+	return info.Nonce != "0" || info.Balance != "0" || len(info.Tokens) > 0 || info.UnconfirmedTxs > 0, nil
+}
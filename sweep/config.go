@@ -0,0 +1,121 @@
+package sweep
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// CoinConfig holds the sweep rules for a single coin.
+type CoinConfig struct {
+	// Threshold is the confirmed balance that must be exceeded before sweeping. When
+	// rebalancing to cold storage it is the minimum excess worth moving instead.
+	Threshold float64 `json:"threshold"`
+	// MinBalance is the amount that is always retained on the hot wallet.
+	MinBalance float64 `json:"min_balance"`
+	// ColdXpub enables the rebalancing mode: the balance above TargetHot is sent to
+	// addresses derived from this watch-only account xpub instead of the exchange.
+	ColdXpub  string  `json:"cold_xpub"`
+	TargetHot float64 `json:"target_hot"`
+}
+
+// Config describes when the sweep job runs and what it moves.
+type Config struct {
+	// Schedule is a five field cron expression.
+	Schedule string `json:"schedule"`
+	// Default is applied to every coin without an entry in Coins.
+	Default CoinConfig            `json:"default"`
+	Coins   map[string]CoinConfig `json:"coins"`
+	// Exclude lists the coin tags that are never swept.
+	Exclude            []string `json:"exclude"`
+	ExcludeStableCoins bool     `json:"exclude_stablecoins"`
+	// DryRun computes and reports the sweep without sending any funds.
+	DryRun bool `json:"dry_run"`
+}
+
+// DefaultConfig mirrors the rules the sweep used before they were configurable.
+func DefaultConfig() Config {
+	return Config{
+		Schedule: "0 * * * *",
+		Default:  CoinConfig{Threshold: 1},
+		Coins: map[string]CoinConfig{
+			"BTC": {Threshold: 0.001},
+			"LTC": {Threshold: 0.1},
+		},
+		Exclude:            []string{"DASH"},
+		ExcludeStableCoins: true,
+	}
+}
+
+// LoadConfig reads the JSON file pointed by SWEEP_CONFIG on top of the defaults.
+// SWEEP_SCHEDULE, SWEEP_EXCLUDE (comma separated tags) and SWEEP_DRY_RUN override the file values.
+func LoadConfig() (Config, error) {
+	config := DefaultConfig()
+	if path := os.Getenv("SWEEP_CONFIG"); path != "" {
+		file, err := ioutil.ReadFile(path)
+		if err != nil {
+			return Config{}, err
+		}
+		err = json.Unmarshal(file, &config)
+		if err != nil {
+			return Config{}, err
+		}
+	}
+	if schedule := os.Getenv("SWEEP_SCHEDULE"); schedule != "" {
+		config.Schedule = schedule
+	}
+	if exclude, ok := os.LookupEnv("SWEEP_EXCLUDE"); ok {
+		config.Exclude = nil
+		for _, tag := range strings.Split(exclude, ",") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				config.Exclude = append(config.Exclude, tag)
+			}
+		}
+	}
+	if dryRun := os.Getenv("SWEEP_DRY_RUN"); dryRun != "" {
+		parsed, err := strconv.ParseBool(dryRun)
+		if err != nil {
+			return Config{}, err
+		}
+		config.DryRun = parsed
+	}
+	return config, nil
+}
+
+// Coin returns the rules for the given tag, falling back to the default ones.
+func (c Config) Coin(tag string) CoinConfig {
+	if coinConfig, ok := c.Coins[tag]; ok {
+		return coinConfig
+	}
+	return c.Default
+}
+
+// ColdXpub returns the cold wallet xpub of a coin, from the configuration or the
+// COLD_XPUB_<TAG> variable. ERC20 tokens fall back to the ETH one as they share addresses.
+func (c Config) ColdXpub(tag string, token bool) string {
+	if xpub := c.Coin(tag).ColdXpub; xpub != "" {
+		return xpub
+	}
+	if xpub := os.Getenv("COLD_XPUB_" + tag); xpub != "" {
+		return xpub
+	}
+	if token && tag != "ETH" {
+		return c.ColdXpub("ETH", false)
+	}
+	return ""
+}
+
+// Excluded reports whether a coin must be skipped by the sweep.
+func (c Config) Excluded(tag string, stableCoin bool) bool {
+	if stableCoin && c.ExcludeStableCoins {
+		return true
+	}
+	for _, excluded := range c.Exclude {
+		if strings.EqualFold(excluded, tag) {
+			return true
+		}
+	}
+	return false
+}
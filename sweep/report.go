@@ -0,0 +1,50 @@
+package sweep
+
+import (
+	"time"
+)
+
+// Coin sweep outcomes.
+const (
+	StatusExcluded       = "excluded"
+	StatusBelowThreshold = "below_threshold"
+	StatusDryRun         = "dry_run"
+	StatusSent           = "sent"
+	StatusFailed         = "failed"
+)
+
+// Sweep destinations.
+const (
+	DestinationExchange = "exchange"
+	DestinationCold     = "cold"
+)
+
+// CoinReport describes what the sweep did, or would have done, for a single coin.
+type CoinReport struct {
+	Coin         string  `json:"coin"`
+	Status       string  `json:"status"`
+	Destination  string  `json:"destination,omitempty"`
+	Balance      float64 `json:"balance"`
+	Threshold    float64 `json:"threshold"`
+	MinBalance   float64 `json:"min_balance"`
+	TargetHot    float64 `json:"target_hot,omitempty"`
+	Amount       float64 `json:"amount"`
+	Address      string  `json:"address,omitempty"`
+	ColdIndex    *uint32 `json:"cold_index,omitempty"`
+	EstimatedFee float64 `json:"estimated_fee"`
+	Txid         string  `json:"txid,omitempty"`
+	Error        string  `json:"error,omitempty"`
+}
+
+// Report is the outcome of a full sweep run.
+type Report struct {
+	DryRun     bool         `json:"dry_run"`
+	StartedAt  time.Time    `json:"started_at"`
+	FinishedAt time.Time    `json:"finished_at"`
+	Coins      []CoinReport `json:"coins"`
+}
+
+func (r *CoinReport) fail(err error) {
+	r.Status = StatusFailed
+	r.Error = err.Error()
+}
@@ -0,0 +1,200 @@
+package sweep
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+	"time"
+
+	coinfactory "github.com/grupokindynos/common/coin-factory"
+	"github.com/grupokindynos/common/plutus"
+	"github.com/grupokindynos/plutus/controllers"
+	"github.com/grupokindynos/plutus/logger"
+	"github.com/grupokindynos/plutus/metrics"
+)
+
+// Wallet is the subset of the controller used by the sweep.
+type Wallet interface {
+	GetBalance(params controllers.Params) (interface{}, error)
+	SendToAddress(params controllers.Params) (interface{}, error)
+	EstimateFee(params controllers.Params) (interface{}, error)
+}
+
+// Job moves the confirmed hot wallet balances to the exchange deposit addresses, or
+// rebalances them to the cold wallet for the coins with a cold xpub configured.
+type Job struct {
+	wallet         Wallet
+	config         Config
+	depositAddress func(coin string) (string, error)
+	cold           *ColdWallet
+}
+
+// NewJob creates the sweep job, cold can be nil if no coin is rebalanced to cold storage.
+func NewJob(wallet Wallet, config Config, depositAddress func(coin string) (string, error), cold *ColdWallet) *Job {
+	return &Job{
+		wallet:         wallet,
+		config:         config,
+		depositAddress: depositAddress,
+		cold:           cold,
+	}
+}
+
+// Run sweeps every coin and returns the resulting *Report.
+func (j *Job) Run() (interface{}, error) {
+	if j.config.DryRun {
+		logger.Info("sweep: running (dry run)")
+	} else {
+		logger.Info("sweep: running")
+	}
+	report := &Report{
+		DryRun:    j.config.DryRun,
+		StartedAt: time.Now(),
+	}
+	tags := make([]string, 0, len(coinfactory.Coins))
+	for tag := range coinfactory.Coins {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	for _, tag := range tags {
+		coinReport := j.sweepCoin(tag)
+		metrics.SweepOutcomes.WithLabelValues(coinReport.Coin, coinReport.Status).Inc()
+		report.Coins = append(report.Coins, coinReport)
+	}
+	report.FinishedAt = time.Now()
+	return report, nil
+}
+
+func (j *Job) sweepCoin(tag string) CoinReport {
+	coinReport := CoinReport{
+		Coin: tag,
+	}
+	coin, err := coinfactory.GetCoin(tag)
+	if err != nil {
+		coinReport.fail(err)
+		return coinReport
+	}
+	coinConfig := j.config.Coin(coin.Info.Tag)
+	coinReport.Threshold = coinConfig.Threshold
+	coinReport.MinBalance = coinConfig.MinBalance
+	if j.config.Excluded(coin.Info.Tag, coin.Info.StableCoin) {
+		coinReport.Status = StatusExcluded
+		return coinReport
+	}
+	coldXpub := j.config.ColdXpub(coin.Info.Tag, coin.Info.Token)
+	if coldXpub != "" {
+		coinReport.Destination = DestinationCold
+		coinReport.TargetHot = coinConfig.TargetHot
+	} else {
+		coinReport.Destination = DestinationExchange
+	}
+	params := controllers.Params{
+		Coin: coin.Info.Tag,
+	}
+	balance, err := j.wallet.GetBalance(params)
+	if err != nil {
+		coinReport.fail(err)
+		return coinReport
+	}
+	plutusBalance, ok := balance.(plutus.Balance)
+	if !ok {
+		coinReport.fail(errors.New("unexpected balance response"))
+		return coinReport
+	}
+	coinReport.Balance = plutusBalance.Confirmed
+	var amount float64
+	if coldXpub != "" {
+		amount = rebalanceAmount(plutusBalance.Confirmed, coinConfig)
+	} else {
+		amount = sweepAmount(plutusBalance.Confirmed, coinConfig)
+	}
+	if amount <= 0 {
+		coinReport.Status = StatusBelowThreshold
+		return coinReport
+	}
+	coinReport.Amount = amount
+	var address string
+	var coldIndex uint32
+	if coldXpub != "" {
+		if j.cold == nil {
+			coinReport.fail(errors.New("cold storage is not available"))
+			return coinReport
+		}
+		address, coldIndex, err = j.cold.NextAddress(coin, coldXpub)
+		coinReport.ColdIndex = &coldIndex
+	} else {
+		address, err = j.depositAddress(coin.Info.Tag)
+	}
+	if err != nil {
+		coinReport.fail(err)
+		return coinReport
+	}
+	if address == "" {
+		coinReport.fail(errors.New("no deposit address available"))
+		return coinReport
+	}
+	coinReport.Address = address
+	sendInfo := plutus.SendAddressBodyReq{
+		Amount:  amount,
+		Address: address,
+		Coin:    coin.Info.Tag,
+	}
+	rawData, err := json.Marshal(sendInfo)
+	if err != nil {
+		coinReport.fail(err)
+		return coinReport
+	}
+	newParams := controllers.Params{
+		Coin: coin.Info.Tag,
+		Body: rawData,
+	}
+	fee, err := j.wallet.EstimateFee(newParams)
+	if err != nil {
+		coinReport.fail(err)
+		return coinReport
+	}
+	coinReport.EstimatedFee, _ = fee.(float64)
+	if j.config.DryRun {
+		coinReport.Status = StatusDryRun
+		logger.Info("sweep: dry run", "coin", sendInfo.Coin, "amount", sendInfo.Amount, "address", sendInfo.Address)
+		return coinReport
+	}
+	txId, err := j.wallet.SendToAddress(newParams)
+	if err != nil {
+		logger.Error("sweep: send failed", "coin", sendInfo.Coin, "amount", sendInfo.Amount, "address", sendInfo.Address, "err", err)
+		coinReport.fail(err)
+		return coinReport
+	}
+	logger.Info("sweep: sent", "coin", sendInfo.Coin, "amount", sendInfo.Amount, "address", sendInfo.Address, "txid", txId)
+	if coldXpub != "" {
+		err = j.cold.MarkUsed(coin, coldIndex)
+		if err != nil {
+			logger.Error("sweep: unable to record the cold index", "coin", coin.Info.Tag, "index", coldIndex, "err", err)
+		}
+	}
+	coinReport.Status = StatusSent
+	coinReport.Txid, _ = txId.(string)
+	return coinReport
+}
+
+// rebalanceAmount returns the excess over the target hot balance, it is only moved when
+// larger than the threshold to avoid sending dust to cold storage.
+func rebalanceAmount(confirmed float64, config CoinConfig) float64 {
+	excess := confirmed - config.TargetHot
+	if excess <= config.Threshold {
+		return 0
+	}
+	return excess
+}
+
+// sweepAmount returns how much of the confirmed balance should leave the hot wallet,
+// zero means nothing has to be sent.
+func sweepAmount(confirmed float64, config CoinConfig) float64 {
+	if confirmed <= config.Threshold {
+		return 0
+	}
+	amount := confirmed - config.MinBalance
+	if amount <= 0 {
+		return 0
+	}
+	return amount
+}
@@ -0,0 +1,221 @@
+package sweep
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	coinfactory "github.com/grupokindynos/common/coin-factory"
+	"github.com/grupokindynos/common/coin-factory/coins"
+	"github.com/grupokindynos/common/plutus"
+	"github.com/grupokindynos/plutus/adrestia"
+	"github.com/grupokindynos/plutus/adrestia/adrestiatest"
+	"github.com/grupokindynos/plutus/controllers"
+	"github.com/grupokindynos/plutus/store"
+)
+
+type testSweep struct {
+	coin     string
+	balance  float64
+	expected float64
+}
+
+var testSweeps = []testSweep{
+	{coin: "BTC", balance: 0.001, expected: 0},
+	{coin: "BTC", balance: 0.002, expected: 0.002},
+	{coin: "LTC", balance: 0.05, expected: 0},
+	{coin: "LTC", balance: 0.5, expected: 0.5},
+	{coin: "POLIS", balance: 1, expected: 0},
+	{coin: "POLIS", balance: 12, expected: 12},
+}
+
+func TestDefaultConfigMatchesLegacyThresholds(t *testing.T) {
+	config := DefaultConfig()
+	for _, test := range testSweeps {
+		amount := sweepAmount(test.balance, config.Coin(test.coin))
+		if amount != test.expected {
+			t.Errorf("unexpected sweep amount for %s with balance %v: expected %v got %v", test.coin, test.balance, test.expected, amount)
+		}
+	}
+	if !config.Excluded("DASH", false) {
+		t.Error("DASH must be excluded by default")
+	}
+	if !config.Excluded("USDT", true) {
+		t.Error("stable coins must be excluded by default")
+	}
+}
+
+func TestMinBalanceIsRetained(t *testing.T) {
+	config := CoinConfig{Threshold: 1, MinBalance: 0.5}
+	if amount := sweepAmount(2, config); amount != 1.5 {
+		t.Errorf("expected 1.5 got %v", amount)
+	}
+	config = CoinConfig{Threshold: 0, MinBalance: 3}
+	if amount := sweepAmount(2, config); amount != 0 {
+		t.Errorf("expected 0 got %v", amount)
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	file, err := ioutil.TempFile("", "sweep-*.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(file.Name())
+	_, err = file.WriteString(`{"schedule": "30 */3 * * *", "coins": {"POLIS": {"threshold": 100, "min_balance": 10}}, "exclude": ["XSG"]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+	os.Setenv("SWEEP_CONFIG", file.Name())
+	defer os.Unsetenv("SWEEP_CONFIG")
+	config, err := LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Schedule != "30 */3 * * *" {
+		t.Error("schedule not loaded, got " + config.Schedule)
+	}
+	if config.Coin("POLIS").MinBalance != 10 || config.Coin("BTC").Threshold != 0.001 {
+		t.Error("per coin configuration not merged with the defaults")
+	}
+	if config.Excluded("DASH", false) || !config.Excluded("XSG", false) {
+		t.Error("exclusions not loaded from file")
+	}
+}
+
+type testWallet struct {
+	balance float64
+	sent    []controllers.Params
+}
+
+func (w *testWallet) GetBalance(params controllers.Params) (interface{}, error) {
+	return plutus.Balance{Confirmed: w.balance}, nil
+}
+
+func (w *testWallet) SendToAddress(params controllers.Params) (interface{}, error) {
+	w.sent = append(w.sent, params)
+	return "txid", nil
+}
+
+func (w *testWallet) EstimateFee(params controllers.Params) (interface{}, error) {
+	return 0.0001, nil
+}
+
+func TestDryRunDoesNotSend(t *testing.T) {
+	wallet := &testWallet{balance: 2}
+	config := DefaultConfig()
+	config.DryRun = true
+	job := NewJob(wallet, config, func(coin string) (string, error) {
+		return "deposit-" + coin, nil
+	}, nil)
+	report := job.sweepCoin("BTC")
+	if report.Status != StatusDryRun {
+		t.Error("expected dry run status, got " + report.Status)
+	}
+	if report.Amount != 2 || report.Address != "deposit-BTC" || report.EstimatedFee != 0.0001 {
+		t.Errorf("unexpected dry run report %+v", report)
+	}
+	if len(wallet.sent) != 0 {
+		t.Error("dry run must not send funds")
+	}
+	job.config.DryRun = false
+	report = job.sweepCoin("BTC")
+	if report.Status != StatusSent || report.Txid != "txid" || len(wallet.sent) != 1 {
+		t.Errorf("unexpected send report %+v", report)
+	}
+}
+
+func TestSweepWithAdrestia(t *testing.T) {
+	server := adrestiatest.NewServer(map[string]string{"BTC": "1JsMio1jkCBsneBsQxs1cbpj5BwTnZKS8i"})
+	defer server.Close()
+	wallet := &testWallet{balance: 0.5}
+	job := NewJob(wallet, DefaultConfig(), server.Client().GetDepositAddress, nil)
+	report := job.sweepCoin("BTC")
+	if report.Status != StatusSent || report.Address != "1JsMio1jkCBsneBsQxs1cbpj5BwTnZKS8i" {
+		t.Errorf("unexpected report %+v", report)
+	}
+	server.Unsigned(true)
+	report = job.sweepCoin("BTC")
+	if report.Status != StatusFailed || report.Error != adrestia.ErrNoSignature.Error() {
+		t.Errorf("unsigned adrestia responses must not be used, got %+v", report)
+	}
+	if len(wallet.sent) != 1 {
+		t.Error("funds sent to an unverified address")
+	}
+}
+
+func TestColdRebalance(t *testing.T) {
+	s, err := store.Open("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cold := NewColdWallet(s)
+	cold.usedAddresses = func(coinConfig *coins.Coin, xpub string) (int, error) {
+		return 10, nil
+	}
+	config := DefaultConfig()
+	config.Coins["BTC"] = CoinConfig{
+		Threshold: 0.01,
+		TargetHot: 0.5,
+		ColdXpub:  "xpub6CJU1RX4dgbFg44YcVY9ighjvsoh184QVXRaxMnxASiCGw6stuAJTTGyWkvyv7d2HKMz2V9hUFBWfYQCjFZUDrxna82vURQTVwkp69poMhx",
+	}
+	wallet := &testWallet{balance: 2}
+	job := NewJob(wallet, config, func(coin string) (string, error) {
+		return "", errors.New("exchange must not be used")
+	}, cold)
+	report := job.sweepCoin("BTC")
+	if report.Status != StatusSent || report.Destination != DestinationCold || report.Amount != 1.5 {
+		t.Fatalf("unexpected report %+v", report)
+	}
+	if report.Address != "1JsMio1jkCBsneBsQxs1cbpj5BwTnZKS8i" || *report.ColdIndex != 10 {
+		t.Errorf("unexpected cold address %s at %d", report.Address, *report.ColdIndex)
+	}
+	report = job.sweepCoin("BTC")
+	if *report.ColdIndex != 11 || report.Address == "1JsMio1jkCBsneBsQxs1cbpj5BwTnZKS8i" {
+		t.Error("cold address reused")
+	}
+	wallet.balance = 0.505
+	report = job.sweepCoin("BTC")
+	if report.Status != StatusBelowThreshold {
+		t.Error("excess below the threshold must not be moved")
+	}
+}
+
+func TestColdEthIndex(t *testing.T) {
+	s, err := store.Open("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ethConfig, err := coinfactory.GetCoin("ETH")
+	if err != nil {
+		t.Fatal(err)
+	}
+	xpub := "xpub6CJU1RX4dgbFg44YcVY9ighjvsoh184QVXRaxMnxASiCGw6stuAJTTGyWkvyv7d2HKMz2V9hUFBWfYQCjFZUDrxna82vURQTVwkp69poMhx"
+	used := make(map[string]bool)
+	for i := uint32(0); i < 3; i++ {
+		address, err := deriveColdAddress(ethConfig, xpub, i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		used[address] = true
+	}
+	cold := NewColdWallet(s)
+	cold.ethAddressUsed = func(address string) (bool, error) {
+		return used[address], nil
+	}
+	address, index, err := cold.NextAddress(ethConfig, xpub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if index != 3 || used[address] {
+		t.Error("the cold addresses used on chain must be skipped when the store lost the index")
+	}
+	if err := cold.MarkUsed(ethConfig, 5); err != nil {
+		t.Fatal(err)
+	}
+	if _, index, _ = cold.NextAddress(ethConfig, xpub); index != 6 {
+		t.Error("the scan must start from the stored index")
+	}
+}